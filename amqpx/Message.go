@@ -0,0 +1,210 @@
+package amqpx
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Message is an annotated message, the sections carried by the transfers of a delivery.
+// Spec section 3.2 Message Format
+//
+//	header, delivery-annotations, message-annotations, properties,
+//	application-properties, body, footer
+//
+// Every section is optional and nil when absent. The body is one or more Data
+// sections, one or more AmqpSequence sections, or a single AmqpValue
+type Message struct {
+	Header                *MessageHeader     `json:"header,omitempty"`
+	DeliveryAnnotations   Annotations        `json:"deliveryAnnotations,omitempty"`
+	MessageAnnotations    Annotations        `json:"messageAnnotations,omitempty"`
+	Properties            *MessageProperties `json:"properties,omitempty"`
+	ApplicationProperties Map                `json:"applicationProperties,omitempty"`
+	Data                  []Binary           `json:"data,omitempty"`
+	AmqpSequence          [][]interface{}    `json:"amqpSequence,omitempty"`
+	AmqpValue             *MessageAmqpValue  `json:"amqpValue,omitempty"`
+	Footer                Annotations        `json:"footer,omitempty"`
+}
+
+// sectionRank orders the sections of a message, the three body sections share a rank
+var sectionRank = map[byte]int{
+	PerfHeader:                0,
+	PerfDeliveryAnnotations:   1,
+	PerfMessageAnnotations:    2,
+	PerfProperties:            3,
+	PerfApplicationProperties: 4,
+	PerfData:                  5,
+	PerfAmqpSequence:          5,
+	PerfAmqpValue:             5,
+	PerfFooter:                6,
+}
+
+// sectionNames maps the symbolic descriptors of the sections To their codes
+var sectionNames = map[Symbol]byte{
+	"amqp:header:list":                PerfHeader,
+	"amqp:delivery-annotations:map":   PerfDeliveryAnnotations,
+	"amqp:message-annotations:map":    PerfMessageAnnotations,
+	"amqp:properties:list":            PerfProperties,
+	"amqp:application-properties:map": PerfApplicationProperties,
+	"amqp:data:binary":                PerfData,
+	"amqp:amqp-sequence:list":         PerfAmqpSequence,
+	"amqp:amqp-value:*":               PerfAmqpValue,
+	"amqp:footer:map":                 PerfFooter,
+}
+
+// bodyKinds counts the body kinds of the message, a message has at most one
+func (message Message) bodyKinds() int {
+	kinds := 0
+	if message.Data != nil {
+		kinds++
+	}
+	if message.AmqpSequence != nil {
+		kinds++
+	}
+	if message.AmqpValue != nil {
+		kinds++
+	}
+	return kinds
+}
+
+// Marshal serializes the sections of the message in their spec order
+func (message Message) Marshal() (buf []byte, err error) {
+	if message.bodyKinds() > 1 {
+		return nil, fmt.Errorf("amqpx: message body can only be one of data, amqp-sequence or amqp-value")
+	}
+
+	var sections [][]byte
+	if message.Header != nil {
+		header, err := Marshal(*message.Header)
+		if err != nil {
+			return nil, errors.New(err.Error() + "\nMessage.Marshal() failed serializing Header")
+		}
+		sections = append(sections, header)
+	}
+	if message.DeliveryAnnotations != nil {
+		deliveryAnnotations, err := Marshal(DeliveryAnnotations(message.DeliveryAnnotations))
+		if err != nil {
+			return nil, errors.New(err.Error() + "\nMessage.Marshal() failed serializing DeliveryAnnotations")
+		}
+		sections = append(sections, deliveryAnnotations)
+	}
+	if message.MessageAnnotations != nil {
+		messageAnnotations, err := Marshal(MessageAnnotations(message.MessageAnnotations))
+		if err != nil {
+			return nil, errors.New(err.Error() + "\nMessage.Marshal() failed serializing MessageAnnotations")
+		}
+		sections = append(sections, messageAnnotations)
+	}
+	if message.Properties != nil {
+		properties, err := Marshal(*message.Properties)
+		if err != nil {
+			return nil, errors.New(err.Error() + "\nMessage.Marshal() failed serializing Properties")
+		}
+		sections = append(sections, properties)
+	}
+	if message.ApplicationProperties != nil {
+		applicationProperties, err := Marshal(ApplicationProperties(message.ApplicationProperties))
+		if err != nil {
+			return nil, errors.New(err.Error() + "\nMessage.Marshal() failed serializing ApplicationProperties")
+		}
+		sections = append(sections, applicationProperties)
+	}
+	for i, data := range message.Data {
+		section, err := Marshal(Data(data))
+		if err != nil {
+			return nil, errors.New(err.Error() + fmt.Sprintf("\nMessage.Marshal() failed serializing Data %d", i))
+		}
+		sections = append(sections, section)
+	}
+	for i, sequence := range message.AmqpSequence {
+		section, err := Marshal(AmqpSequence(sequence))
+		if err != nil {
+			return nil, errors.New(err.Error() + fmt.Sprintf("\nMessage.Marshal() failed serializing AmqpSequence %d", i))
+		}
+		sections = append(sections, section)
+	}
+	if message.AmqpValue != nil {
+		amqpValue, err := Marshal(*message.AmqpValue)
+		if err != nil {
+			return nil, errors.New(err.Error() + "\nMessage.Marshal() failed serializing AmqpValue")
+		}
+		sections = append(sections, amqpValue)
+	}
+	if message.Footer != nil {
+		footer, err := Marshal(Footer(message.Footer))
+		if err != nil {
+			return nil, errors.New(err.Error() + "\nMessage.Marshal() failed serializing Footer")
+		}
+		sections = append(sections, footer)
+	}
+
+	for _, section := range sections {
+		buf = append(buf, section...)
+	}
+	return buf, nil
+}
+
+// Unmarshal reads the sections of a message from buffer, the payload of its transfers.
+// The sections must be in spec order and a body can not mix data, amqp-sequence and amqp-value
+func (message *Message) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	*message = Message{}
+	inx := uint32(0)
+	lastRank, lastSection := -1, byte(0)
+	for inx < uint32(len(buffer)) {
+		descriptor, _, err := ParseDescriptor(buffer[inx:])
+		if err != nil {
+			return inx, decodeErrorAt(err, inx, "message")
+		}
+		code, _ := DescriptorCode(descriptor)
+		if name, ok := descriptor.(Symbol); ok {
+			if named, ok := sectionNames[name]; ok {
+				code = uint64(named)
+			}
+		}
+		section := byte(code)
+		rank, ok := sectionRank[section]
+		if code > 0xff || !ok {
+			return inx, decodeErrorAt(invalidValueError("descriptor %v is not a message section", descriptor), inx, "message")
+		}
+		// only data and amqp-sequence sections repeat, and only after their own kind
+		repeats := section == lastSection && (section == PerfData || section == PerfAmqpSequence)
+		if rank < lastRank || (rank == lastRank && !repeats) {
+			return inx, decodeErrorAt(invalidValueError("section 0x%02x after section 0x%02x", section, lastSection), inx, "message")
+		}
+		lastRank, lastSection = rank, section
+
+		// each section reads its own descriptor
+		body, advanceInx := buffer[inx:], uint32(0)
+		switch section {
+		case PerfHeader:
+			message.Header = &MessageHeader{}
+			advanceInx, err = Unmarshal(body, message.Header)
+		case PerfDeliveryAnnotations:
+			advanceInx, err = (*DeliveryAnnotations)(&message.DeliveryAnnotations).UnmarshalAMQP(body)
+		case PerfMessageAnnotations:
+			advanceInx, err = (*MessageAnnotations)(&message.MessageAnnotations).UnmarshalAMQP(body)
+		case PerfProperties:
+			message.Properties = &MessageProperties{}
+			advanceInx, err = Unmarshal(body, message.Properties)
+		case PerfApplicationProperties:
+			advanceInx, err = (*ApplicationProperties)(&message.ApplicationProperties).UnmarshalAMQP(body)
+		case PerfData:
+			var data Data
+			advanceInx, err = data.UnmarshalAMQP(body)
+			message.Data = append(message.Data, Binary(data))
+		case PerfAmqpSequence:
+			var sequence AmqpSequence
+			advanceInx, err = sequence.UnmarshalAMQP(body)
+			message.AmqpSequence = append(message.AmqpSequence, sequence)
+		case PerfAmqpValue:
+			message.AmqpValue = &MessageAmqpValue{}
+			advanceInx, err = message.AmqpValue.UnmarshalAMQP(body)
+		case PerfFooter:
+			advanceInx, err = (*Footer)(&message.Footer).UnmarshalAMQP(body)
+		}
+		if err != nil {
+			return inx, decodeErrorAt(err, inx, "message")
+		}
+		inx += advanceInx
+	}
+	return inx, nil
+}
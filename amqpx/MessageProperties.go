@@ -1,245 +1,132 @@
 package amqpx
 
 import (
-	"errors"
+	"time"
 
 	log "github.com/mgutz/logxi/v1"
 )
 
-// MessageProperties is a message format
-// <type Name="properties" class="composite" Source="list" provides="section">
-//
-//	<descriptor Name="amqp:properties:list" code="0x00000000:0x00000073"/>
-//	<field Name="message-id" type="*" requires="message-id"/>
-//	<field Name="user-id" type="binary"/>
-//	<field Name="To" type="*" requires="Address"/>
-//	<field Name="Subject" type="string"/>
-//	<field Name="reply-To" type="*" requires="Address"/>
-//	<field Name="correlation-id" type="*" requires="message-id"/>
-//	<field Name="content-type" type="symbol"/>
-//	<field Name="content-encoding" type="symbol"/>
-//	<field Name="absolute-expiry-time" type="timestamp"/>
-//	<field Name="creation-time" type="timestamp"/>
-//	<field Name="group-id" type="string"/>
-//	<field Name="group-sequence" type="sequence-no"/>
-//	<field Name="reply-To-group-id" type="string"/>
-//
-// </type>
-type MessageProperties struct {
-	MessageId       Binary     `json:"messageId"`
-	UserId          uint32     `json:"userId"`
-	To              string     `json:"to"`
-	Subject         string     `json:"subject"`
-	ReplyTo         string     `json:"replyTo"`
-	CorrelationId   Binary     `json:"correlationId"`
-	ContentType     Symbol     `json:"contentType"`
-	ContentEncoding Symbol     `json:"contentEncoding"`
-	AbsExpiryTime   Timestamp  `json:"absExpiryTime"`
-	CreationTime    Timestamp  `json:"creationTime"`
-	GroupId         string     `json:"groupId"`
-	GroupSequence   SequenceNo `json:"groupSequence"`
-	ReplyToGroupID  string     `json:"replyToGroupId"`
+// MessageID is a message-id or correlation-id, one of MessageIDUlong, MessageIDUUID,
+// MessageIDBinary or MessageIDString
+type MessageID interface {
+	Marshaler
+	messageID()
 }
 
-// ParseMessageProperties message properties after transport.
-func ParseMessageProperties(buffer []byte) (properties MessageProperties, bytesUsed uint32, err error) {
-	err = nil
-	inx := uint32(0)
-	bytesUsed = uint32(0)
-	advanceInx := uint32(0)
+// MessageIDUlong is a message-id-ulong
+type MessageIDUlong uint64
 
-	// header
-	_, countItems, _, advanceInx, err := ParseListPrimitive(buffer[inx:])
-	if err != nil {
-		return properties, bytesUsed, errors.New(err.Error() + "\nReadMessageProperties() failed compound list")
-	}
-	inx += advanceInx
-	advanceInx = 0
-	countItems--
-
-	if buffer[inx] != nullCode {
-		properties.MessageId, advanceInx, err = ParseBinaryPrimitive(buffer[inx:])
-		if err != nil {
-			return properties, bytesUsed, errors.New(err.Error() + "\nReadBinaryPrimitive() failed reading MessageId")
-		}
-		log.Debug("properties.MessageId:", properties.MessageId)
-		inx += advanceInx
-		advanceInx = 0
-	} else {
-		inx++
-		//log.Debug("skipping MessageId .. is nullCode inx:", inx)
-	}
-	countItems--
-
-	if buffer[inx] != nullCode {
-		properties.UserId, advanceInx, err = ParseUintPrimitive(buffer[inx:])
-		if err != nil {
-			return properties, bytesUsed, errors.New(err.Error() + "\nReadUintPrimitive() failed reading UserId")
-		}
-		log.Debug("properties.UserId:", properties.UserId)
-		inx += advanceInx
-		advanceInx = 0
-	} else {
-		inx++
-		//log.Debug("skipping UserId .. is nullCode inx:", inx)
-	}
-	countItems--
-
-	if buffer[inx] != nullCode {
-		properties.To, advanceInx, err = ParseStringPrimitive(buffer[inx:])
-		if err != nil {
-			return properties, bytesUsed, errors.New(err.Error() + "\nReadStringPrimitive() failed reading 'To'")
-		}
-		log.Debug("properties.To:", properties.To)
-		inx += advanceInx
-		advanceInx = 0
-	} else {
-		inx++
-		//log.Debug("skipping 'To' .. is nullCode inx:", inx)
-	}
-	countItems--
-
-	if buffer[inx] != nullCode {
-		properties.Subject, advanceInx, err = ParseStringPrimitive(buffer[inx:])
-		if err != nil {
-			return properties, bytesUsed, errors.New(err.Error() + "\nReadStringPrimitive() failed reading 'Subject'")
-		}
-		log.Debug("properties.Subject:", properties.Subject)
-		inx += advanceInx
-		advanceInx = 0
-	} else {
-		inx++
-		//log.Debug("skipping 'Subject' .. is nullCode inx:", inx)
-	}
-	countItems--
-
-	if buffer[inx] != nullCode {
-		properties.ReplyTo, advanceInx, err = ParseStringPrimitive(buffer[inx:])
-		if err != nil {
-			return properties, bytesUsed, errors.New(err.Error() + "\nReadStringPrimitive() failed reading 'ReplyTo'")
-		}
-		log.Debug("properties.ReplyTo:", properties.ReplyTo)
-		inx += advanceInx
-		advanceInx = 0
-	} else {
-		inx++
-		//log.Debug("skipping 'ReplyTo' .. is nullCode inx:", inx)
-	}
-	countItems--
-
-	if buffer[inx] != nullCode {
-		properties.CorrelationId, advanceInx, err = ParseBinaryPrimitive(buffer[inx:])
-		if err != nil {
-			return properties, bytesUsed, errors.New(err.Error() + "\nReadBinaryPrimitive() failed reading 'CorrelationId'")
-		}
-		log.Debug("properties.CorrelationId:", properties.CorrelationId)
-		inx += advanceInx
-		advanceInx = 0
-	} else {
-		inx++
-		//log.Debug("skipping 'ReplyTo' .. is nullCode inx:", inx)
-	}
-	countItems--
-
-	if buffer[inx] != nullCode {
-		properties.ContentType, advanceInx, err = ParseSymbolPrimitive(buffer[inx:])
-		if err != nil {
-			return properties, bytesUsed, errors.New(err.Error() + "\nReadBinaryPrimitive() failed reading 'ContentType'")
-		}
-		log.Debug("properties.ContentType:", properties.ContentType)
-		inx += advanceInx
-		advanceInx = 0
-	} else {
-		inx++
-		//log.Debug("skipping 'ContentType' .. is nullCode inx:", inx)
-	}
-	countItems--
-
-	if buffer[inx] != nullCode {
-		properties.ContentEncoding, advanceInx, err = ParseSymbolPrimitive(buffer[inx:])
-		if err != nil {
-			return properties, bytesUsed, errors.New(err.Error() + "\nReadBinaryPrimitive() failed reading 'ContentEncoding'")
-		}
-		log.Debug("properties.ContentEncoding:", properties.ContentEncoding)
-		inx += advanceInx
-		advanceInx = 0
-	} else {
-		inx++
-		//log.Debug("skipping 'ContentEncoding' .. is nullCode inx:", inx)
+// MessageIDUUID is a message-id-uuid
+type MessageIDUUID UUID
+
+// MessageIDBinary is a message-id-binary
+type MessageIDBinary Binary
+
+// MessageIDString is a message-id-string
+type MessageIDString string
+
+func (MessageIDUlong) messageID()  {}
+func (MessageIDUUID) messageID()   {}
+func (MessageIDBinary) messageID() {}
+func (MessageIDString) messageID() {}
+
+// MarshalAMQP serializes the message-id as an ulong
+func (id MessageIDUlong) MarshalAMQP() ([]byte, error) {
+	return SerializeUlongPrimitive(uint64(id)), nil
+}
+
+// MarshalAMQP serializes the message-id as an uuid
+func (id MessageIDUUID) MarshalAMQP() ([]byte, error) {
+	return SerializeUuidPrimitive(UUID(id)), nil
+}
+
+// MarshalAMQP serializes the message-id as a binary
+func (id MessageIDBinary) MarshalAMQP() ([]byte, error) {
+	return SerializeBinaryPrimitive(id), nil
+}
+
+// MarshalAMQP serializes the message-id as a string
+func (id MessageIDString) MarshalAMQP() ([]byte, error) {
+	return SerializeStringPrimitive(string(id)), nil
+}
+
+func (id MessageIDUUID) String() string {
+	return UUID(id).String()
+}
+
+// SerializeMessageIDPrimitive serializes a message-id, nil is null
+func SerializeMessageIDPrimitive(value MessageID) (buf []byte, err error) {
+	if value == nil {
+		return SerializeNullPrimitive(), nil
 	}
-	countItems--
-
-	if buffer[inx] != nullCode {
-		properties.AbsExpiryTime, advanceInx, err = ParseTimestampPrimitive(buffer[inx:])
-		if err != nil {
-			return properties, bytesUsed, errors.New(err.Error() + "\nReadTimestampPrimitive() failed reading 'AbsExpiryTime'")
-		}
-		log.Debug("properties.AbsExpiryTime:", properties.AbsExpiryTime)
-		inx += advanceInx
-		advanceInx = 0
-	} else {
-		inx++
-		//log.Debug("skipping 'AbsExpiryTime' .. is nullCode inx:", inx)
+	return value.MarshalAMQP()
+}
+
+// ParseMessageIDPrimitive reads an ulong, uuid, binary or string message-id, null is nil
+func ParseMessageIDPrimitive(buffer []byte) (retVal MessageID, bytesUsed uint32, err error) {
+	if len(buffer) < 1 {
+		return nil, 0, truncatedError(1, 0)
 	}
-	countItems--
-
-	if buffer[inx] != nullCode {
-		properties.CreationTime, advanceInx, err = ParseTimestampPrimitive(buffer[inx:])
-		if err != nil {
-			return properties, bytesUsed, errors.New(err.Error() + "\nReadTimestampPrimitive() failed reading 'CreationTime'")
-		}
-		log.Debug("properties.CreationTime:", properties.CreationTime)
-		inx += advanceInx
-		advanceInx = 0
-	} else {
-		inx++
-		//log.Debug("skipping 'CreationTime' .. is nullCode inx:", inx)
+	switch buffer[0] {
+	case nullCode:
+		return nil, 1, nil
+	case ulongCode, ulongSmallCode, ulong0Code:
+		value, bytesUsed, err := ParseUlongPrimitive(buffer)
+		return MessageIDUlong(value), bytesUsed, err
+	case uuidCode:
+		value, bytesUsed, err := ParseUuidPrimitive(buffer)
+		return MessageIDUUID(value), bytesUsed, err
+	case binary8Code, binary32Code:
+		value, bytesUsed, err := ParseBinaryPrimitive(buffer)
+		return MessageIDBinary(value), bytesUsed, err
+	case string8Code, string32Code:
+		value, bytesUsed, err := ParseStringPrimitive(buffer)
+		return MessageIDString(value), bytesUsed, err
 	}
-	countItems--
-
-	if buffer[inx] != nullCode {
-		properties.GroupId, advanceInx, err = ParseStringPrimitive(buffer[inx:])
-		if err != nil {
-			return properties, bytesUsed, errors.New(err.Error() + "\nReadStringPrimitive() failed reading 'GroupId'")
-		}
-		log.Debug("properties.GroupId:", properties.GroupId)
-		inx += advanceInx
-		advanceInx = 0
-	} else {
-		inx++
-		//log.Debug("skipping 'GroupId' .. is nullCode inx:", inx)
+	return nil, 0, constructorError(buffer, ulongCode, ulongSmallCode, ulong0Code, uuidCode, binary8Code, binary32Code, string8Code, string32Code)
+}
+
+// AbsoluteExpiryTime returns the absolute-expiry-time, the zero time when not set
+func (properties MessageProperties) AbsoluteExpiryTime() time.Time {
+	if properties.AbsExpiryTime == nil {
+		return time.Time{}
 	}
-	countItems--
-
-	if buffer[inx] != nullCode {
-		properties.GroupSequence, advanceInx, err = ParseSequenceNoPrimitive(buffer[inx:])
-		if err != nil {
-			return properties, bytesUsed, errors.New(err.Error() + "\nReadSequenceNoPrimitive() failed reading GroupSequence")
-		}
-		log.Debug("properties.GroupSequence:", properties.GroupSequence)
-		inx += advanceInx
-		advanceInx = 0
-	} else {
-		inx++
-		//log.Debug("skipping GroupSequence .. is nullCode inx:", inx)
+	return properties.AbsExpiryTime.Time()
+}
+
+// SetAbsoluteExpiryTime sets the absolute-expiry-time, the zero time unsets it
+func (properties *MessageProperties) SetAbsoluteExpiryTime(t time.Time) {
+	properties.AbsExpiryTime = timestampOf(t)
+}
+
+// Created returns the creation-time, the zero time when not set
+func (properties MessageProperties) Created() time.Time {
+	if properties.CreationTime == nil {
+		return time.Time{}
 	}
-	countItems--
-
-	if buffer[inx] != nullCode {
-		properties.ReplyToGroupID, advanceInx, err = ParseStringPrimitive(buffer[inx:])
-		if err != nil {
-			return properties, bytesUsed, errors.New(err.Error() + "\nReadStringPrimitive() failed reading 'ReplyToGroupID'")
-		}
-		log.Debug("properties.ReplyToGroupID:", properties.ReplyToGroupID)
-		inx += advanceInx
-		advanceInx = 0
-	} else {
-		inx++
-		//log.Debug("skipping 'ReplyToGroupID' .. is nullCode inx:", inx)
+	return properties.CreationTime.Time()
+}
+
+// SetCreated sets the creation-time, the zero time unsets it
+func (properties *MessageProperties) SetCreated(t time.Time) {
+	properties.CreationTime = timestampOf(t)
+}
+
+// timestampOf returns a pointer To the timestamp of t, nil for the zero time
+func timestampOf(t time.Time) *Timestamp {
+	if t.IsZero() {
+		return nil
 	}
-	countItems--
+	timestamp := NewTimestamp(t)
+	return &timestamp
+}
 
-	bytesUsed = inx
+// ParseMessageProperties message properties after transport, with or without its descriptor.
+func ParseMessageProperties(buffer []byte) (properties MessageProperties, bytesUsed uint32, err error) {
+	bytesUsed, err = Unmarshal(buffer, &properties)
+	if err != nil {
+		return properties, bytesUsed, err
+	}
+	log.Debug("properties:", properties)
 	return properties, bytesUsed, nil
-
 }
@@ -0,0 +1,415 @@
+package amqpx
+
+import (
+	"errors"
+	"fmt"
+
+	log "github.com/mgutz/logxi/v1"
+)
+
+// ConnectionState is the state of a connection, spec section 2.4.6 Connection States
+type ConnectionState int
+
+// Spec connection states
+const (
+	// ConnStart no header sent or received
+	ConnStart ConnectionState = iota
+	// ConnHdrRcvd the header of the peer was received, ours not yet sent
+	ConnHdrRcvd
+	// ConnHdrSent our header was sent, the one of the peer not yet received
+	ConnHdrSent
+	// ConnHdrExch both headers were exchanged
+	ConnHdrExch
+	// ConnOpenPipe our header and open were sent, the header of the peer not yet received
+	ConnOpenPipe
+	// ConnOcPipe our header, open and close were sent, the header of the peer not yet received
+	ConnOcPipe
+	// ConnOpenRcvd the open of the peer was received, ours not yet sent
+	ConnOpenRcvd
+	// ConnOpenSent our open was sent, the one of the peer not yet received
+	ConnOpenSent
+	// ConnClosePipe our open and close were sent, the open of the peer not yet received
+	ConnClosePipe
+	// ConnOpened both opens were exchanged
+	ConnOpened
+	// ConnCloseRcvd the close of the peer was received, ours not yet sent
+	ConnCloseRcvd
+	// ConnCloseSent our close was sent, the one of the peer not yet received
+	ConnCloseSent
+	// ConnDiscarding our close was sent with an error, frames of the peer are discarded until its close
+	ConnDiscarding
+	// ConnEnd the connection is done
+	ConnEnd
+)
+
+var connectionStateNames = [...]string{"START", "HDR_RCVD", "HDR_SENT", "HDR_EXCH", "OPEN_PIPE", "OC_PIPE", "OPEN_RCVD",
+	"OPEN_SENT", "CLOSE_PIPE", "OPENED", "CLOSE_RCVD", "CLOSE_SENT", "DISCARDING", "END"}
+
+func (state ConnectionState) String() string {
+	if state < 0 || int(state) >= len(connectionStateNames) {
+		return fmt.Sprintf("ConnectionState(%d)", int(state))
+	}
+	return connectionStateNames[state]
+}
+
+// connectionEvent is what moves a connection from one state To the next
+type connectionEvent int
+
+const (
+	sendHeader connectionEvent = iota
+	recvHeader
+	sendOpen
+	recvOpen
+	sendClose
+	recvClose
+)
+
+var connectionEventNames = [...]string{"send header", "receive header", "send open", "receive open", "send close", "receive close"}
+
+func (event connectionEvent) String() string {
+	return connectionEventNames[event]
+}
+
+// connectionTransitions are the legal transitions of figure 2.23, an event missing
+// for a state is illegal
+var connectionTransitions = map[ConnectionState]map[connectionEvent]ConnectionState{
+	ConnStart:      {sendHeader: ConnHdrSent, recvHeader: ConnHdrRcvd},
+	ConnHdrRcvd:    {sendHeader: ConnHdrExch},
+	ConnHdrSent:    {recvHeader: ConnHdrExch, sendOpen: ConnOpenPipe},
+	ConnHdrExch:    {sendOpen: ConnOpenSent, recvOpen: ConnOpenRcvd},
+	ConnOpenPipe:   {recvHeader: ConnOpenSent, sendClose: ConnOcPipe},
+	ConnOcPipe:     {recvHeader: ConnClosePipe},
+	ConnOpenRcvd:   {sendOpen: ConnOpened},
+	ConnOpenSent:   {recvOpen: ConnOpened, sendClose: ConnClosePipe},
+	ConnClosePipe:  {recvOpen: ConnCloseSent},
+	ConnOpened:     {sendClose: ConnCloseSent, recvClose: ConnCloseRcvd},
+	ConnCloseRcvd:  {sendClose: ConnEnd},
+	ConnCloseSent:  {recvClose: ConnEnd},
+	ConnDiscarding: {recvClose: ConnEnd},
+}
+
+// ErrConnectionState is returned when a frame is sent in a state that does not allow it
+var ErrConnectionState = errors.New("amqpx: illegal connection state")
+
+// Connection drives the protocol header and the frames of an AMQP connection through the
+// connection states, for the client and the server alike. Spec section 2.4 Connections.
+// The frames of sessions are routed To the Session of their channel, see Begin
+type Connection struct {
+	// Local is the open we send, Remote the open received from the peer
+	Local  ConnectionParameters
+	Remote ConnectionParameters
+
+	state ConnectionState
+	// discarding is set by a close with an error, CLOSE_SENT is then DISCARDING
+	discarding bool
+	reader     *FrameReader
+	writer     *FrameWriter
+	// sessions maps our channels To their sessions, remoteChannels the channels of the peer
+	sessions       map[uint16]*Session
+	remoteChannels map[uint16]*Session
+}
+
+// NewConnection returns a connection in the START state over reader and writer. Until the
+// opens are exchanged frames are limited To MinMaxFrameSize
+func NewConnection(reader *FrameReader, writer *FrameWriter) *Connection {
+	reader.MaxFrameSize = MinMaxFrameSize
+	writer.MaxFrameSize = MinMaxFrameSize
+	return &Connection{state: ConnStart, reader: reader, writer: writer,
+		sessions: map[uint16]*Session{}, remoteChannels: map[uint16]*Session{}}
+}
+
+// State returns the current connection state
+func (c *Connection) State() ConnectionState {
+	return c.state
+}
+
+// transition moves the connection on event, an illegal event leaves the state unchanged
+func (c *Connection) transition(event connectionEvent) error {
+	next, ok := connectionTransitions[c.state][event]
+	if !ok {
+		return fmt.Errorf("%w, can not %s in %s", ErrConnectionState, event, c.state)
+	}
+	if next == ConnCloseSent && c.discarding {
+		next = ConnDiscarding
+	}
+	log.Debug("connection state:", c.state.String()+" -> "+next.String())
+	c.state = next
+	return nil
+}
+
+// SendHeader writes the AMQP protocol header
+func (c *Connection) SendHeader() error {
+	if err := c.transition(sendHeader); err != nil {
+		return err
+	}
+	return c.writer.WriteProtocolHeader(ProtocolIdAMQP)
+}
+
+// ReceiveHeader reads the protocol header of the peer, see HeaderReceived
+func (c *Connection) ReceiveHeader() error {
+	protocolVersion, err := c.reader.ReadProtocolHeader()
+	if err != nil {
+		c.state = ConnEnd
+		return err
+	}
+	return c.HeaderReceived(protocolVersion)
+}
+
+// HeaderReceived records the protocol header of the peer when the caller has read it, e.g.
+// after the TLS and SASL layers. A header other than AMQP 1.0.0 is answered with ours, if not
+// sent yet, and ends the connection. Spec section 2.2 Version Negotiation
+func (c *Connection) HeaderReceived(protocolVersion ProtoocolVersion) error {
+	if protocolVersion.ProtocolId != ProtocolIdAMQP || protocolVersion.Major != 1 || protocolVersion.Minor != 0 || protocolVersion.Revision != 0 {
+		if c.state == ConnStart {
+			c.writer.WriteProtocolHeader(ProtocolIdAMQP)
+		}
+		c.state = ConnEnd
+		return fmt.Errorf("amqpx: unsupported protocol header %d %d.%d.%d", protocolVersion.ProtocolId,
+			protocolVersion.Major, protocolVersion.Minor, protocolVersion.Revision)
+	}
+	if err := c.transition(recvHeader); err != nil {
+		c.state = ConnEnd
+		return err
+	}
+	return nil
+}
+
+// Open sends Local, frames up To its max-frame-size are accepted from then on
+func (c *Connection) Open() error {
+	if err := c.transition(sendOpen); err != nil {
+		return err
+	}
+	c.reader.MaxFrameSize = c.Local.FrameSizeLimit()
+	return c.writer.WritePerformative(0, &c.Local, nil)
+}
+
+// Close sends a close, with amqpError when the connection fails. After an error the frames
+// of the peer are discarded until its close arrives. When our open was not sent yet it is
+// sent first, as a close must follow an open
+func (c *Connection) Close(amqpError *Error) error {
+	if c.state == ConnHdrExch || c.state == ConnOpenRcvd {
+		if err := c.Open(); err != nil {
+			return err
+		}
+	}
+	if _, ok := connectionTransitions[c.state][sendClose]; !ok {
+		return fmt.Errorf("%w, can not %s in %s", ErrConnectionState, sendClose, c.state)
+	}
+	c.discarding = amqpError != nil
+	c.transition(sendClose)
+	return c.writer.WritePerformative(0, &CloseParameters{Error: amqpError}, nil)
+}
+
+// canSend tells whether frames other than open and close may be sent, our open was sent
+// and our close was not
+func (c *Connection) canSend() bool {
+	switch c.state {
+	case ConnOpenPipe, ConnOpenSent, ConnOpened:
+		return true
+	}
+	return false
+}
+
+// canReceive tells whether the peer may send frames other than open and close, its open
+// was received and its close was not
+func (c *Connection) canReceive() bool {
+	switch c.state {
+	case ConnOpenRcvd, ConnOpened, ConnCloseSent:
+		return true
+	}
+	return false
+}
+
+// ChannelMax returns the highest channel both ends allow, a session more than that is refused
+func (c *Connection) ChannelMax() uint16 {
+	if c.Remote.ChannelLimit() < c.Local.ChannelLimit() {
+		return c.Remote.ChannelLimit()
+	}
+	return c.Local.ChannelLimit()
+}
+
+// Begin starts a session on the lowest free channel with local as our begin
+func (c *Connection) Begin(local SessionParameters) (*Session, error) {
+	channel, err := c.freeChannel()
+	if err != nil {
+		return nil, err
+	}
+	session := &Session{Local: local, Channel: channel, state: SessionUnmapped, connection: c}
+	c.sessions[channel] = session
+	if err = session.Begin(); err != nil {
+		delete(c.sessions, channel)
+		return nil, err
+	}
+	return session, nil
+}
+
+// Session returns the session the peer sends on channel, nil when there is none. A session
+// the peer began is in SessionBeginRcvd until its Begin is called
+func (c *Connection) Session(channel uint16) *Session {
+	return c.remoteChannels[channel]
+}
+
+func (c *Connection) freeChannel() (uint16, error) {
+	for channel := uint16(0); ; channel++ {
+		if _, ok := c.sessions[channel]; !ok {
+			return channel, nil
+		}
+		if channel == c.ChannelMax() {
+			return 0, fmt.Errorf("%w %d", ErrChannelMax, c.ChannelMax())
+		}
+	}
+}
+
+// releaseSession frees the channels of an unmapped session
+func (c *Connection) releaseSession(session *Session) {
+	delete(c.sessions, session.Channel)
+	if c.remoteChannels[session.RemoteChannel] == session {
+		delete(c.remoteChannels, session.RemoteChannel)
+	}
+}
+
+// Send writes performative on channel, open and close are sent with Open and Close. The
+// frames of sessions are better sent with Session.Send, which keeps their state
+func (c *Connection) Send(channel uint16, performative Performative, payload []byte) error {
+	switch p := performative.(type) {
+	case *ConnectionParameters:
+		c.Local = *p
+		return c.Open()
+	case *CloseParameters:
+		return c.Close(p.Error)
+	}
+	if !c.canSend() {
+		return fmt.Errorf("%w, can not send %v in %s", ErrConnectionState, performative, c.state)
+	}
+	return c.writer.WritePerformative(channel, performative, payload)
+}
+
+// Receive reads the next frame of the peer, the protocol header of the peer first when it
+// is due. A nil performative is an empty frame. A frame the state does not allow closes the
+// connection with amqp:connection:framing-error, the returned error is then an *Error.
+// Frames received while discarding are dropped, the state is ConnEnd after the close of the peer
+func (c *Connection) Receive() (channel uint16, performative Performative, payload []byte, err error) {
+	switch c.state {
+	case ConnHdrSent, ConnOpenPipe, ConnOcPipe:
+		// the header of the peer was not received yet
+		if err = c.ReceiveHeader(); err != nil {
+			return 0, nil, nil, err
+		}
+	case ConnStart, ConnHdrRcvd, ConnCloseRcvd, ConnEnd:
+		return 0, nil, nil, fmt.Errorf("%w, can not receive in %s", ErrConnectionState, c.state)
+	}
+
+	for {
+		frame, err := c.reader.ReadFrame()
+		if err != nil {
+			if errors.Is(err, ErrFrameTooLarge) {
+				return 0, nil, nil, c.fail(NewError(ErrorConnectionFramingError, err.Error()))
+			}
+			return 0, nil, nil, err
+		}
+		if frame.TypeCode != FrameTypeAMQP {
+			return 0, nil, nil, c.fail(NewError(ErrorConnectionFramingError, "frame type 0x%02x after the AMQP header", frame.TypeCode))
+		}
+		if frame.IsEmpty() {
+			if c.state == ConnDiscarding {
+				continue
+			}
+			return frame.Channel, nil, nil, nil
+		}
+
+		performative, payload, err = ParseFrameBody(frame.Body)
+		if c.state == ConnDiscarding {
+			if _, ok := performative.(*CloseParameters); !ok {
+				continue
+			}
+		}
+		if session := c.remoteChannels[frame.Channel]; session != nil && session.state == SessionDiscarding {
+			if _, ok := performative.(*EndParameters); !ok {
+				continue
+			}
+		}
+		if err != nil {
+			return 0, nil, nil, c.fail(NewError(ErrorDecodeError, err.Error()))
+		}
+		return frame.Channel, performative, payload, c.received(frame.Channel, performative)
+	}
+}
+
+// received moves the connection on a performative of the peer, the other frames go To
+// the session of channel
+func (c *Connection) received(channel uint16, performative Performative) error {
+	switch p := performative.(type) {
+	case *ConnectionParameters:
+		if err := c.transition(recvOpen); err != nil {
+			return c.fail(NewError(ErrorConnectionFramingError, err.Error()))
+		}
+		c.Remote = *p
+		// the peer told us the largest frame it accepts in its open
+		c.writer.MaxFrameSize = p.FrameSizeLimit()
+	case *CloseParameters:
+		if err := c.transition(recvClose); err != nil {
+			return c.fail(NewError(ErrorConnectionFramingError, err.Error()))
+		}
+	default:
+		if !c.canReceive() {
+			return c.fail(NewError(ErrorConnectionFramingError, "%v received in %s", performative, c.state))
+		}
+		if begin, ok := performative.(*SessionParameters); ok {
+			return c.beginReceived(channel, begin)
+		}
+		session := c.remoteChannels[channel]
+		if session == nil {
+			return c.fail(NewError(ErrorConnectionFramingError, "%v received on channel %d without a session", performative, channel))
+		}
+		return session.received(performative)
+	}
+	return nil
+}
+
+// beginReceived maps a session To channel, the one we began when the begin answers ours or
+// a new one in SessionBeginRcvd
+func (c *Connection) beginReceived(channel uint16, begin *SessionParameters) error {
+	if channel > c.Local.ChannelLimit() {
+		return c.fail(NewError(ErrorConnectionFramingError, "begin received on channel %d above channel-max %d", channel, c.Local.ChannelLimit()))
+	}
+	if _, ok := c.remoteChannels[channel]; ok {
+		return c.fail(NewError(ErrorConnectionFramingError, "begin received on channel %d which has a session", channel))
+	}
+	if begin.RemoteChannel != nil {
+		session := c.sessions[*begin.RemoteChannel]
+		if session == nil || session.state != SessionBeginSent {
+			return c.fail(NewError(ErrorConnectionFramingError, "begin answers channel %d which did not begin", *begin.RemoteChannel))
+		}
+		session.beginReceived(channel, begin)
+		c.remoteChannels[channel] = session
+		session.transition(SessionMapped)
+		return nil
+	}
+	local, err := c.freeChannel()
+	if err != nil {
+		return c.fail(NewError(ErrorResourceLimitExceeded, err.Error()))
+	}
+	session := &Session{Channel: local, state: SessionBeginRcvd, connection: c}
+	session.beginReceived(channel, begin)
+	c.sessions[local] = session
+	c.remoteChannels[channel] = session
+	return nil
+}
+
+// fail closes the connection with amqpError, unless our close was sent already, and returns amqpError
+func (c *Connection) fail(amqpError *Error) error {
+	log.Debug("connection failed:", amqpError.Error())
+	switch c.state {
+	case ConnCloseSent, ConnDiscarding, ConnOcPipe, ConnClosePipe, ConnEnd:
+		return amqpError
+	case ConnStart, ConnHdrRcvd, ConnHdrSent:
+		// no frames before the headers are exchanged
+		c.state = ConnEnd
+		return amqpError
+	}
+	if err := c.Close(amqpError); err != nil {
+		c.state = ConnEnd
+		log.Debug("connection close failed:", err.Error())
+	}
+	return amqpError
+}
@@ -0,0 +1,120 @@
+package amqpx
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestConnectionStates(t *testing.T) {
+	var toServer, toClient bytes.Buffer
+	client := NewConnection(NewFrameReader(&toClient, 0), NewFrameWriter(&toServer, 0))
+	server := NewConnection(NewFrameReader(&toServer, 0), NewFrameWriter(&toClient, 0))
+	client.Local = ConnectionParameters{ContainerId: "client"}
+	server.Local = ConnectionParameters{ContainerId: "server"}
+	expectState := func(name string, c *Connection, expected ConnectionState) {
+		if c.State() != expected {
+			t.Errorf("%s state was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", name, expected, c.State())
+		}
+	}
+
+	// the client pipelines its header, open and a begin
+	if err := client.SendHeader(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Send(0, &SessionParameters{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	expectState("client", client, ConnOpenPipe)
+
+	if err := server.ReceiveHeader(); err != nil {
+		t.Fatal(err)
+	}
+	expectState("server", server, ConnHdrRcvd)
+	if err := server.Send(0, &SessionParameters{}, nil); !errors.Is(err, ErrConnectionState) {
+		t.Errorf("Send before open was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", ErrConnectionState, err)
+	}
+	server.SendHeader()
+	if _, performative, _, err := server.Receive(); err != nil || server.Remote.ContainerId != "client" {
+		t.Errorf("Receive (open) was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", "open of client", performative, err)
+	}
+	expectState("server", server, ConnOpenRcvd)
+	if _, performative, _, err := server.Receive(); err != nil {
+		t.Errorf("Receive (begin) was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", "begin", performative, err)
+	}
+	server.Open()
+	expectState("server", server, ConnOpened)
+
+	client.Receive()
+	expectState("client", client, ConnOpened)
+
+	// a second open is a framing error, the server closes and discards until the close of the client
+	client.writer.WritePerformative(0, &client.Local, nil)
+	client.Send(0, &SessionParameters{}, nil)
+	_, _, _, err := server.Receive()
+	var amqpError *Error
+	if !errors.As(err, &amqpError) || amqpError.Condition != ErrorConnectionFramingError {
+		t.Errorf("Receive (second open) was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", ErrorConnectionFramingError, err)
+	}
+	expectState("server", server, ConnDiscarding)
+
+	_, performative, _, err := client.Receive()
+	if closeParameters, ok := performative.(*CloseParameters); err != nil || !ok || closeParameters.Error == nil {
+		t.Errorf("Receive (close) was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", "close with error", performative, err)
+	}
+	expectState("client", client, ConnCloseRcvd)
+	client.Close(nil)
+	expectState("client", client, ConnEnd)
+
+	if _, performative, _, err = server.Receive(); err != nil {
+		t.Errorf("%s\nReceive (discarding) was incorrect, expected no errors", err.Error())
+	}
+	if _, ok := performative.(*CloseParameters); !ok {
+		t.Errorf("Receive (discarding) was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", "close", performative)
+	}
+	expectState("server", server, ConnEnd)
+}
+
+func TestConnectionFrameBeforeOpen(t *testing.T) {
+	var toServer, toClient bytes.Buffer
+	server := NewConnection(NewFrameReader(&toServer, 0), NewFrameWriter(&toClient, 0))
+	server.Local = ConnectionParameters{ContainerId: "server"}
+	NewFrameWriter(&toServer, 0).WriteProtocolHeader(ProtocolIdAMQP)
+	NewFrameWriter(&toServer, 0).WritePerformative(0, &SessionParameters{}, nil)
+
+	server.ReceiveHeader()
+	server.SendHeader()
+	_, _, _, err := server.Receive()
+	var amqpError *Error
+	if !errors.As(err, &amqpError) || amqpError.Condition != ErrorConnectionFramingError || server.State() != ConnClosePipe {
+		t.Errorf("Receive (begin before open) was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", ErrorConnectionFramingError, err, server.State())
+	}
+
+	// the close follows the open of the server, which discards frames once it got ours
+	client := NewConnection(NewFrameReader(&toClient, 0), NewFrameWriter(&toServer, 0))
+	client.Local = ConnectionParameters{ContainerId: "client"}
+	client.SendHeader()
+	// the server read the header of the client already
+	toServer.Reset()
+	for _, expected := range []uint64{uint64(PerfOpen), uint64(PerfClose)} {
+		_, performative, _, err := client.Receive()
+		if err != nil || performative == nil || performative.Descriptor() != expected {
+			t.Errorf("Receive was incorrect, \n\texpected: \"0x%x\" \n\tgot:\"%v\" %v", expected, performative, err)
+		}
+		if expected == uint64(PerfOpen) {
+			client.Open()
+		}
+	}
+	client.Close(nil)
+	server.Receive()
+	if server.State() != ConnDiscarding {
+		t.Errorf("server state was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", ConnDiscarding, server.State())
+	}
+	server.Receive()
+	if server.State() != ConnEnd {
+		t.Errorf("server state was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", ConnEnd, server.State())
+	}
+}
@@ -0,0 +1,432 @@
+package amqpx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Decoder reads AMQP encoded values from a bufio.Reader and keeps track of the
+// offset itself. A Value that fits into the buffer of the reader is parsed in place,
+// larger values are copied out of the reader
+type Decoder struct {
+	r      *bufio.Reader
+	offset int64
+	peeked int // size of the current Value, discarded once it is parsed
+
+	// MaxValueSize is the largest Value read, usually the negotiated max-frame-size, 0 for no limit
+	MaxValueSize uint32
+}
+
+// NewDecoder returns a Decoder reading from r, r is wrapped in a bufio.Reader unless it is one
+func NewDecoder(r io.Reader) *Decoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Decoder{r: br, MaxValueSize: DefaultMaxFrameSize}
+}
+
+// Offset returns the number of bytes read so far
+func (d *Decoder) Offset() int64 {
+	return d.offset
+}
+
+// peek returns the next n bytes without reading them, io.ErrUnexpectedEOF when a Value is cut off
+func (d *Decoder) peek(n int) ([]byte, error) {
+	buf, err := d.r.Peek(n)
+	if err == io.EOF && len(buf) > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return buf, err
+}
+
+// discard reads n bytes that were peeked
+func (d *Decoder) discard(n int) {
+	d.r.Discard(n)
+	d.offset += int64(n)
+}
+
+// valueSize returns the encoded size of the Value that starts off bytes ahead
+func (d *Decoder) valueSize(off int) (int, error) {
+	buf, err := d.peek(off + 1)
+	if err != nil {
+		if off > 0 && err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	constructor := buf[off]
+
+	if constructor == 0x00 {
+		descriptorSize, err := d.valueSize(off + 1)
+		if err != nil {
+			return 0, err
+		}
+		describedSize, err := d.valueSize(off + 1 + descriptorSize)
+		if err != nil {
+			return 0, err
+		}
+		return 1 + descriptorSize + describedSize, nil
+	}
+	if width, ok := fixedWidth(constructor); ok {
+		return 1 + int(width), nil
+	}
+
+	switch constructor & 0xf0 {
+	case 0xa0, 0xc0, 0xe0:
+		if buf, err = d.peek(off + 2); err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return 2 + int(buf[off+1]), nil
+	case 0xb0, 0xd0, 0xf0:
+		if buf, err = d.peek(off + 5); err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return 5 + int(binary.BigEndian.Uint32(buf[off+1:])), nil
+	}
+	return 0, invalidValueError("unknown constructor 0x%02x", constructor)
+}
+
+// next returns the encoded bytes of the next Value, done must be called once they are parsed
+func (d *Decoder) next() ([]byte, error) {
+	size, err := d.valueSize(0)
+	if err != nil {
+		return nil, err
+	}
+	// the size comes from the wire, refuse it before reading that far
+	if d.MaxValueSize != 0 && uint64(size) > uint64(d.MaxValueSize) {
+		return nil, invalidValueError("Value of %d bytes exceeds the max size of %d", size, d.MaxValueSize)
+	}
+
+	if size <= d.r.Size() {
+		buf, err := d.peek(size)
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		d.peeked = size
+		return buf, nil
+	}
+
+	// the buffer grows as data arrives so a bogus size does not allocate up front
+	var large bytes.Buffer
+	if _, err = io.CopyN(&large, d.r, int64(size)); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	d.offset += int64(size)
+	return large.Bytes(), nil
+}
+
+// done discards the current Value when it was parsed without error
+func (d *Decoder) done(err error) error {
+	if err == nil && d.peeked > 0 {
+		d.discard(d.peeked)
+	}
+	d.peeked = 0
+	return err
+}
+
+// nextCopy returns the encoded bytes of the next Value in a buffer owned by the caller
+func (d *Decoder) nextCopy() ([]byte, error) {
+	buf, err := d.next()
+	if err != nil || d.peeked == 0 {
+		return buf, err
+	}
+	buf = append([]byte(nil), buf...)
+	return buf, d.done(nil)
+}
+
+// IsNull reports whether the next Value is null without reading it
+func (d *Decoder) IsNull() (bool, error) {
+	buf, err := d.peek(1)
+	if err != nil {
+		return false, err
+	}
+	return buf[0] == nullCode, nil
+}
+
+// Skip reads the next Value and drops it
+func (d *Decoder) Skip() error {
+	_, err := d.next()
+	return d.done(err)
+}
+
+// ReadBoolean reads a boolean
+func (d *Decoder) ReadBoolean() (bool, error) {
+	buf, err := d.next()
+	if err != nil {
+		return false, err
+	}
+	value, _, err := ParseBooleanPrimitive(buf)
+	return value, d.done(err)
+}
+
+// ReadUbyte reads an ubyte
+func (d *Decoder) ReadUbyte() (uint8, error) {
+	buf, err := d.next()
+	if err != nil {
+		return 0, err
+	}
+	value, _, err := ParseUbytePrimitive(buf)
+	return value, d.done(err)
+}
+
+// ReadUshort reads an ushort
+func (d *Decoder) ReadUshort() (uint16, error) {
+	buf, err := d.next()
+	if err != nil {
+		return 0, err
+	}
+	value, _, err := PraseUshortPrimitive(buf)
+	return value, d.done(err)
+}
+
+// ReadUint reads an uint
+func (d *Decoder) ReadUint() (uint32, error) {
+	buf, err := d.next()
+	if err != nil {
+		return 0, err
+	}
+	value, _, err := ParseUintPrimitive(buf)
+	return value, d.done(err)
+}
+
+// ReadUlong reads an ulong
+func (d *Decoder) ReadUlong() (uint64, error) {
+	buf, err := d.next()
+	if err != nil {
+		return 0, err
+	}
+	value, _, err := ParseUlongPrimitive(buf)
+	return value, d.done(err)
+}
+
+// ReadSignedByte reads an AMQP byte
+func (d *Decoder) ReadSignedByte() (int8, error) {
+	buf, err := d.next()
+	if err != nil {
+		return 0, err
+	}
+	value, _, err := ParseBytePrimitive(buf)
+	return int8(value), d.done(err)
+}
+
+// ReadShort reads a short
+func (d *Decoder) ReadShort() (int16, error) {
+	buf, err := d.next()
+	if err != nil {
+		return 0, err
+	}
+	value, _, err := ParseShortPrimitive(buf)
+	return value, d.done(err)
+}
+
+// ReadInt reads an int
+func (d *Decoder) ReadInt() (int32, error) {
+	buf, err := d.next()
+	if err != nil {
+		return 0, err
+	}
+	value, _, err := ParseIntPrimitive(buf)
+	return value, d.done(err)
+}
+
+// ReadLong reads a long
+func (d *Decoder) ReadLong() (int64, error) {
+	buf, err := d.next()
+	if err != nil {
+		return 0, err
+	}
+	value, _, err := ParseLongPrimitive(buf)
+	return value, d.done(err)
+}
+
+// ReadFloat reads a float
+func (d *Decoder) ReadFloat() (float32, error) {
+	buf, err := d.next()
+	if err != nil {
+		return 0, err
+	}
+	value, _, err := ParseFloatPrimitive(buf)
+	return value, d.done(err)
+}
+
+// ReadDouble reads a double
+func (d *Decoder) ReadDouble() (float64, error) {
+	buf, err := d.next()
+	if err != nil {
+		return 0, err
+	}
+	value, _, err := ParseDoublePrimitive(buf)
+	return value, d.done(err)
+}
+
+// ReadChar reads a char
+func (d *Decoder) ReadChar() (rune, error) {
+	buf, err := d.next()
+	if err != nil {
+		return 0, err
+	}
+	value, _, err := ParseCharPrimitive(buf)
+	return value, d.done(err)
+}
+
+// ReadTimestamp reads a timestamp
+func (d *Decoder) ReadTimestamp() (Timestamp, error) {
+	buf, err := d.next()
+	if err != nil {
+		return 0, err
+	}
+	value, _, err := ParseTimestampPrimitive(buf)
+	return value, d.done(err)
+}
+
+// ReadUUID reads an uuid
+func (d *Decoder) ReadUUID() (UUID, error) {
+	buf, err := d.next()
+	if err != nil {
+		return UUID{}, err
+	}
+	value, _, err := ParseUuidPrimitive(buf)
+	return value, d.done(err)
+}
+
+// ReadBinary reads binary data into a new slice
+func (d *Decoder) ReadBinary() ([]byte, error) {
+	buf, err := d.next()
+	if err != nil {
+		return nil, err
+	}
+	value, _, err := ParseBinaryPrimitive(buf)
+	if value != nil {
+		value = append([]byte{}, value...)
+	}
+	return value, d.done(err)
+}
+
+// ReadString reads a string
+func (d *Decoder) ReadString() (string, error) {
+	buf, err := d.next()
+	if err != nil {
+		return "", err
+	}
+	value, _, err := ParseStringPrimitive(buf)
+	return value, d.done(err)
+}
+
+// ReadSymbol reads a symbol
+func (d *Decoder) ReadSymbol() (Symbol, error) {
+	buf, err := d.next()
+	if err != nil {
+		return "", err
+	}
+	value, _, err := ParseSymbolPrimitive(buf)
+	return value, d.done(err)
+}
+
+// ReadSymbolArray reads an array of symbols or a single symbol
+func (d *Decoder) ReadSymbolArray() ([]Symbol, error) {
+	buf, err := d.next()
+	if err != nil {
+		return nil, err
+	}
+	value, _, err := ParseSymbolArrayPrimitive(buf)
+	return value, d.done(err)
+}
+
+// ReadDeliveryState reads a delivery State, null is read as nil
+func (d *Decoder) ReadDeliveryState() (DeliveryState, error) {
+	buf, err := d.next()
+	if err != nil {
+		return nil, err
+	}
+	value, _, err := ParseDeliveryStatePrimitive(buf)
+	return value, d.done(err)
+}
+
+// readCompoundHeader reads the constructor, size and count of a list or map,
+// the items are read by the following calls
+func (d *Decoder) readCompoundHeader(code0 byte, code8 byte, code32 byte) (count uint32, err error) {
+	buf, err := d.peek(1)
+	if err != nil {
+		return 0, err
+	}
+
+	switch buf[0] {
+	case code0:
+		d.discard(1)
+		return 0, nil
+	case code8:
+		if buf, err = d.peek(3); err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+		count = uint32(buf[2])
+		d.discard(3)
+	case code32:
+		if buf, err = d.peek(9); err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+		count = binary.BigEndian.Uint32(buf[5:])
+		d.discard(9)
+	default:
+		return 0, constructorError(buf, code0, code8, code32)
+	}
+	return count, nil
+}
+
+// ReadListHeader reads the header of a list and returns the number of items that follow
+func (d *Decoder) ReadListHeader() (count uint32, err error) {
+	return d.readCompoundHeader(list0Code, list8Code, list32Code)
+}
+
+// ReadMapHeader reads the header of a map and returns the number of keys and values that follow.
+// A map has no zero length form, null is read as an empty map
+func (d *Decoder) ReadMapHeader() (count uint32, err error) {
+	return d.readCompoundHeader(nullCode, map8Code, map32Code)
+}
+
+// ReadDescriptor reads the described type constructor and the descriptor, an ulong or a symbol.
+// The described Value is read by the following call
+func (d *Decoder) ReadDescriptor() (descriptor interface{}, err error) {
+	buf, err := d.peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if buf[0] != 0x00 {
+		return nil, constructorError(buf, 0x00)
+	}
+	d.discard(1)
+
+	descriptor, err = d.ReadAny()
+	if err != nil {
+		return nil, err
+	}
+	switch descriptor.(type) {
+	case uint64, Symbol:
+		return descriptor, nil
+	}
+	return nil, invalidValueError("descriptor must be an ulong or a symbol, got %T", descriptor)
+}
+
+// ReadAny reads the next Value as ParseAny does
+func (d *Decoder) ReadAny() (interface{}, error) {
+	buf, err := d.nextCopy()
+	if err != nil {
+		return nil, err
+	}
+	value, _, err := ParseAny(buf)
+	return value, err
+}
+
+// Decode reads the next Value into the Value pointed To by v as Unmarshal does
+func (d *Decoder) Decode(v interface{}) error {
+	buf, err := d.nextCopy()
+	if err != nil {
+		return err
+	}
+	if _, err = Unmarshal(buf, v); err != nil {
+		return decodeErrorAt(err, uint32(d.offset-int64(len(buf))), "")
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package amqpx
+
+import (
+	"fmt"
+)
+
+// Delivery is a message delivery joined from one or more transfers.
+// Transfer is the first transfer of the delivery, Settled and State are updated
+// from the later transfers
+type Delivery struct {
+	Transfer TransferParameters `json:"transfer"`
+	Payload  []byte             `json:"payload"`
+}
+
+// Message reads the sections of the delivered message
+func (delivery Delivery) Message() (message Message, err error) {
+	_, err = message.Unmarshal(delivery.Payload)
+	return message, err
+}
+
+// DeliveryAssembler joins the payloads of the transfers of a link, a transfer with
+// more set is followed by the next transfer of the same delivery.
+// Spec section 2.6.14 Transferring a Message
+type DeliveryAssembler struct {
+	// MaxMessageSize is the max-message-size of the link, 0 for no limit
+	MaxMessageSize uint64
+
+	first   *TransferParameters
+	payload []byte
+}
+
+// InProgress tells whether a delivery waits for more transfers
+func (assembler *DeliveryAssembler) InProgress() bool {
+	return assembler.first != nil
+}
+
+// Add adds a transfer and its payload. It returns the delivery when the transfer was the
+// last one, nil while more transfers are expected or when the delivery was aborted
+func (assembler *DeliveryAssembler) Add(transfer TransferParameters, payload []byte) (delivery *Delivery, err error) {
+	if transfer.Aborted {
+		// the sender gave up on the delivery, what was received so far is dropped
+		assembler.reset()
+		return nil, nil
+	}
+
+	if assembler.first == nil {
+		if transfer.DeliveryId == nil || transfer.DeliveryTag == nil {
+			return nil, NewError(ErrorInvalidField, "first transfer of a delivery needs a delivery-id and a delivery-tag")
+		}
+		first := transfer
+		assembler.first = &first
+		assembler.payload = nil
+	} else {
+		if transfer.Handle != assembler.first.Handle {
+			return nil, NewError(ErrorIllegalState, "transfer on handle %d while a delivery on handle %d is not complete", transfer.Handle, assembler.first.Handle)
+		}
+		if transfer.DeliveryId != nil && *transfer.DeliveryId != *assembler.first.DeliveryId {
+			return nil, NewError(ErrorIllegalState, "transfer of delivery %d while delivery %d is not complete", *transfer.DeliveryId, *assembler.first.DeliveryId)
+		}
+		if transfer.Settled != nil {
+			assembler.first.Settled = transfer.Settled
+		}
+		if transfer.State != nil {
+			assembler.first.State = transfer.State
+		}
+	}
+
+	if assembler.MaxMessageSize != 0 && uint64(len(assembler.payload))+uint64(len(payload)) > assembler.MaxMessageSize {
+		size := uint64(len(assembler.payload)) + uint64(len(payload))
+		assembler.reset()
+		return nil, NewError(ErrorLinkMessageSizeExceeded, "message of %d bytes exceeds max-message-size %d", size, assembler.MaxMessageSize)
+	}
+	assembler.payload = append(assembler.payload, payload...)
+
+	if transfer.More {
+		return nil, nil
+	}
+	delivery = &Delivery{Transfer: *assembler.first, Payload: assembler.payload}
+	delivery.Transfer.More = false
+	assembler.first = nil
+	assembler.payload = nil
+	return delivery, nil
+}
+
+func (assembler *DeliveryAssembler) reset() {
+	assembler.first = nil
+	assembler.payload = nil
+}
+
+// TransferFrame is a transfer performative with its share of the delivery payload
+type TransferFrame struct {
+	Transfer TransferParameters `json:"transfer"`
+	Payload  []byte             `json:"payload"`
+}
+
+// Marshal serializes the frame body, the transfer followed by its payload
+func (frame TransferFrame) Marshal() ([]byte, error) {
+	buf, err := frame.Transfer.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, frame.Payload...), nil
+}
+
+// FragmentDelivery splits the payload of a delivery, an encoded Message, into transfers
+// whose frames fit in maxFrameSize. The first transfer carries the fields of transfer,
+// the following ones only the handle. All but the last have more set
+func FragmentDelivery(transfer TransferParameters, payload []byte, maxFrameSize uint32) (frames []TransferFrame, err error) {
+	first := transfer
+	first.More = true
+	next := TransferParameters{Handle: transfer.Handle, More: true}
+
+	for len(frames) == 0 || len(payload) > 0 {
+		frame := next
+		if len(frames) == 0 {
+			frame = first
+		}
+		body, err := frame.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		overhead := uint64(szFrameHeader) + uint64(len(body))
+		if overhead >= uint64(maxFrameSize) {
+			return nil, fmt.Errorf("amqpx: max-frame-size %d leaves no room for the payload of a transfer", maxFrameSize)
+		}
+
+		size := uint64(maxFrameSize) - overhead
+		if size >= uint64(len(payload)) {
+			size = uint64(len(payload))
+			frame.More = transfer.More
+		}
+		frames = append(frames, TransferFrame{Transfer: frame, Payload: payload[:size]})
+		payload = payload[size:]
+	}
+	return frames, nil
+}
@@ -0,0 +1,582 @@
+package amqpx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+)
+
+// maxPooledEncoderSize is the largest buffer kept in the pool, larger buffers are left To the gc
+const maxPooledEncoderSize = 64 * 1024
+
+// encoderPool holds released Encoders and their buffers
+var encoderPool = sync.Pool{
+	New: func() interface{} {
+		return &Encoder{buf: make([]byte, 0, 512)}
+	},
+}
+
+// compound is a list or map that is being written
+type compound struct {
+	start     int    // offset of the constructor
+	count     uint32 // number of items written
+	code8     byte
+	code32    byte
+	nullStart int    // offset of the trailing run of null items, -1 if the last item is not null
+	nullCount uint32 // count before the trailing run of null items
+}
+
+// mapPair locates a key and its Value written into the buffer
+type mapPair struct {
+	start  int // offset of the key
+	keyEnd int // offset of the Value
+	end    int
+}
+
+// Encoder appends AMQP encoded values To a growable buffer. Lists and maps are written in place
+// between Begin and End calls, sizes and counts are filled in by the End call.
+// When the Encoder has a writer, Flush writes the buffer To it
+type Encoder struct {
+	w          io.Writer
+	buf        []byte
+	stack      []compound
+	pairs      []mapPair // pairs of the open maps, ordered when the map ends
+	describing bool
+}
+
+// NewEncoder returns a pooled Encoder writing To w, w may be nil when only Bytes is used.
+// Release returns the Encoder To the pool
+func NewEncoder(w io.Writer) *Encoder {
+	e := encoderPool.Get().(*Encoder)
+	e.w = w
+	e.Reset()
+	return e
+}
+
+// Release returns the Encoder To the pool, the Encoder and its Bytes must not be used afterwards
+func (e *Encoder) Release() {
+	e.w = nil
+	if cap(e.buf) > maxPooledEncoderSize {
+		return
+	}
+	encoderPool.Put(e)
+}
+
+// Reset drops everything written since the last Flush
+func (e *Encoder) Reset() {
+	e.buf = e.buf[:0]
+	e.stack = e.stack[:0]
+	e.pairs = e.pairs[:0]
+	e.describing = false
+}
+
+// Bytes returns the encoded bytes, valid until the next write, Reset or Release
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+// Len returns the number of encoded bytes
+func (e *Encoder) Len() int {
+	return len(e.buf)
+}
+
+// Flush writes the encoded bytes To the writer and resets the buffer
+func (e *Encoder) Flush() error {
+	if len(e.stack) > 0 {
+		return errors.New("amqpx: Flush() called with an open list or map")
+	}
+	if e.w == nil {
+		return errors.New("amqpx: Flush() called on an Encoder without a writer")
+	}
+	_, err := e.w.Write(e.buf)
+	e.Reset()
+	return err
+}
+
+// item counts a Value written into the open list or map
+func (e *Encoder) item(null bool) {
+	if e.describing {
+		// the described Value belongs To the descriptor counted before
+		e.describing = false
+		return
+	}
+	if n := len(e.stack); n > 0 {
+		top := &e.stack[n-1]
+		if !null {
+			top.nullStart = -1
+		} else if top.nullStart < 0 {
+			top.nullStart = len(e.buf)
+			top.nullCount = top.count
+		}
+		top.count++
+	}
+}
+
+// WriteEncoded appends a Value that is already encoded, e.g. by a Serialize function
+func (e *Encoder) WriteEncoded(buf []byte) {
+	e.item(len(buf) == 1 && buf[0] == nullCode)
+	e.buf = append(e.buf, buf...)
+}
+
+// WriteNull appends null
+func (e *Encoder) WriteNull() {
+	e.item(true)
+	e.buf = append(e.buf, nullCode)
+}
+
+// WriteBoolean appends true or false
+func (e *Encoder) WriteBoolean(value bool) {
+	e.item(false)
+	if value {
+		e.buf = append(e.buf, booleanTrue)
+	} else {
+		e.buf = append(e.buf, booleanFalse)
+	}
+}
+
+// WriteUbyte appends an ubyte
+func (e *Encoder) WriteUbyte(value uint8) {
+	e.item(false)
+	e.buf = append(e.buf, ubyteCode, value)
+}
+
+// WriteUshort appends an ushort
+func (e *Encoder) WriteUshort(value uint16) {
+	e.item(false)
+	e.buf = append(e.buf, ushortCode)
+	e.buf = binary.BigEndian.AppendUint16(e.buf, value)
+}
+
+// WriteUint appends an uint in its smallest encoding
+func (e *Encoder) WriteUint(value uint32) {
+	e.item(false)
+	switch {
+	case value == 0:
+		e.buf = append(e.buf, uint0Code)
+	case value <= 0xff:
+		e.buf = append(e.buf, uintSmallCode, byte(value))
+	default:
+		e.buf = append(e.buf, uintCode)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, value)
+	}
+}
+
+// WriteUlong appends an ulong in its smallest encoding
+func (e *Encoder) WriteUlong(value uint64) {
+	e.item(false)
+	switch {
+	case value == 0:
+		e.buf = append(e.buf, ulong0Code)
+	case value <= 0xff:
+		e.buf = append(e.buf, ulongSmallCode, byte(value))
+	default:
+		e.buf = append(e.buf, ulongCode)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, value)
+	}
+}
+
+// WriteSignedByte appends an AMQP byte
+func (e *Encoder) WriteSignedByte(value int8) {
+	e.item(false)
+	e.buf = append(e.buf, byteCode, byte(value))
+}
+
+// WriteShort appends a short
+func (e *Encoder) WriteShort(value int16) {
+	e.item(false)
+	e.buf = append(e.buf, shortCode)
+	e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(value))
+}
+
+// WriteInt appends an int in its smallest encoding
+func (e *Encoder) WriteInt(value int32) {
+	e.item(false)
+	if value >= math.MinInt8 && value <= math.MaxInt8 {
+		e.buf = append(e.buf, intSmallCode, byte(value))
+		return
+	}
+	e.buf = append(e.buf, intCode)
+	e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(value))
+}
+
+// WriteLong appends a long in its smallest encoding
+func (e *Encoder) WriteLong(value int64) {
+	e.item(false)
+	if value >= math.MinInt8 && value <= math.MaxInt8 {
+		e.buf = append(e.buf, longSmallCode, byte(value))
+		return
+	}
+	e.buf = append(e.buf, longCode)
+	e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(value))
+}
+
+// WriteFloat appends a float
+func (e *Encoder) WriteFloat(value float32) {
+	e.item(false)
+	e.buf = append(e.buf, floatCode)
+	e.buf = binary.BigEndian.AppendUint32(e.buf, math.Float32bits(value))
+}
+
+// WriteDouble appends a double
+func (e *Encoder) WriteDouble(value float64) {
+	e.item(false)
+	e.buf = append(e.buf, doubleCode)
+	e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(value))
+}
+
+// WriteChar appends a char
+func (e *Encoder) WriteChar(value rune) {
+	e.item(false)
+	e.buf = append(e.buf, charCode)
+	e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(value))
+}
+
+// WriteTimestamp appends a timestamp
+func (e *Encoder) WriteTimestamp(value Timestamp) {
+	e.item(false)
+	e.buf = append(e.buf, timestampCode)
+	e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(value))
+}
+
+// WriteUUID appends an uuid
+func (e *Encoder) WriteUUID(value UUID) {
+	e.item(false)
+	e.buf = append(e.buf, uuidCode)
+	e.buf = append(e.buf, value[:]...)
+}
+
+// writeLength appends the constructor and length of a binary, string or symbol
+func (e *Encoder) writeLength(code8 byte, code32 byte, length int) {
+	e.item(false)
+	if length <= 0xff {
+		e.buf = append(e.buf, code8, byte(length))
+		return
+	}
+	e.buf = append(e.buf, code32)
+	e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(length))
+}
+
+// WriteBinary appends binary data
+func (e *Encoder) WriteBinary(value []byte) {
+	e.writeLength(binary8Code, binary32Code, len(value))
+	e.buf = append(e.buf, value...)
+}
+
+// WriteString appends a string, the empty string is written as a string of length zero
+func (e *Encoder) WriteString(value string) {
+	e.writeLength(string8Code, string32Code, len(value))
+	e.buf = append(e.buf, value...)
+}
+
+// WriteSymbol appends a symbol
+func (e *Encoder) WriteSymbol(value Symbol) {
+	e.writeLength(symbol8Code, symbol32Code, len(value))
+	e.buf = append(e.buf, value...)
+}
+
+// WriteSymbolArray appends symbols as an array, a nil slice is written as null.
+// The symbols share the 8bit constructor unless one of them is longer than 255 bytes
+func (e *Encoder) WriteSymbolArray(value []Symbol) {
+	if value == nil {
+		e.WriteNull()
+		return
+	}
+	e.item(false)
+
+	constructor, width := symbol8Code, szByte
+	for _, sym := range value {
+		if len(sym) > 0xff {
+			constructor, width = symbol32Code, szInt32
+			break
+		}
+	}
+	itemsSize := 0
+	for _, sym := range value {
+		itemsSize += width + len(sym)
+	}
+	// size counts the count field, the element constructor and the elements
+	if itemsSize+2*szByte <= 0xff && len(value) <= 0xff {
+		e.buf = append(e.buf, array8Code, byte(itemsSize+2*szByte), byte(len(value)))
+	} else {
+		e.buf = append(e.buf, array32Code)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(itemsSize+szInt32+szByte))
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(len(value)))
+	}
+	e.buf = append(e.buf, constructor)
+	for _, sym := range value {
+		if constructor == symbol8Code {
+			e.buf = append(e.buf, byte(len(sym)))
+		} else {
+			e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(len(sym)))
+		}
+		e.buf = append(e.buf, sym...)
+	}
+}
+
+// WriteDeliveryState appends a delivery State as its described list, nil as null
+func (e *Encoder) WriteDeliveryState(value DeliveryState) error {
+	if value == nil {
+		e.WriteNull()
+		return nil
+	}
+	return e.Encode(value)
+}
+
+// WriteDescriptor appends the described type constructor and a numeric descriptor,
+// the next Value written is the described Value
+func (e *Encoder) WriteDescriptor(code uint64) {
+	e.item(false)
+	e.buf = append(e.buf, 0x00)
+	e.describing = true
+	e.WriteUlong(code)
+	e.describing = true
+}
+
+// WriteSymbolicDescriptor appends the described type constructor and a symbolic descriptor,
+// the next Value written is the described Value
+func (e *Encoder) WriteSymbolicDescriptor(name Symbol) {
+	e.item(false)
+	e.buf = append(e.buf, 0x00)
+	e.describing = true
+	e.WriteSymbol(name)
+	e.describing = true
+}
+
+// beginCompound reserves the 32bit header of a list or map
+func (e *Encoder) beginCompound(code8 byte, code32 byte) {
+	e.item(false)
+	e.stack = append(e.stack, compound{start: len(e.buf), code8: code8, code32: code32, nullStart: -1})
+	e.buf = append(e.buf, code32, 0, 0, 0, 0, 0, 0, 0, 0)
+}
+
+// endCompound fills in the header of the innermost open list or map. The 8bit form is
+// used when both size and count fit into a byte, as in serializeCompound
+func (e *Encoder) endCompound(code32 byte, trimNulls bool) error {
+	n := len(e.stack)
+	if n == 0 || e.stack[n-1].code32 != code32 {
+		return fmt.Errorf("amqpx: no open compound for constructor 0x%x", code32)
+	}
+	top := e.stack[n-1]
+	e.stack = e.stack[:n-1]
+
+	if trimNulls && top.nullStart >= 0 {
+		e.buf = e.buf[:top.nullStart]
+		top.count = top.nullCount
+	}
+
+	header := 1 + 2*szInt32
+	itemsSize := len(e.buf) - top.start - header
+	switch {
+	case top.count == 0 && top.code8 == list8Code:
+		e.buf = append(e.buf[:top.start], list0Code)
+	case itemsSize+szByte <= 0xff && top.count <= 0xff:
+		copy(e.buf[top.start+3:], e.buf[top.start+header:])
+		e.buf = e.buf[:len(e.buf)-header+3]
+		e.buf[top.start] = top.code8
+		e.buf[top.start+1] = byte(itemsSize + szByte)
+		e.buf[top.start+2] = byte(top.count)
+	default:
+		binary.BigEndian.PutUint32(e.buf[top.start+1:], uint32(itemsSize+szInt32))
+		binary.BigEndian.PutUint32(e.buf[top.start+1+szInt32:], top.count)
+	}
+	return nil
+}
+
+// BeginList starts a list, the values written until EndList are its items
+func (e *Encoder) BeginList() {
+	e.beginCompound(list8Code, list32Code)
+}
+
+// EndList ends the innermost open list
+func (e *Encoder) EndList() error {
+	return e.endCompound(list32Code, false)
+}
+
+// EndComposite ends the innermost open list and drops its trailing null items,
+// a receiver treats missing trailing fields of a composite as null
+func (e *Encoder) EndComposite() error {
+	return e.endCompound(list32Code, true)
+}
+
+// BeginMap starts a map, the values written until EndMap are its keys and values
+func (e *Encoder) BeginMap() {
+	e.beginCompound(map8Code, map32Code)
+}
+
+// EndMap ends the innermost open map
+func (e *Encoder) EndMap() error {
+	if n := len(e.stack); n > 0 && e.stack[n-1].count%2 != 0 {
+		return errors.New("amqpx: map must contain an even number of items")
+	}
+	return e.endCompound(map32Code, false)
+}
+
+// WriteAny appends a native go Value as SerializeAny does
+func (e *Encoder) WriteAny(value interface{}) (err error) {
+	switch v := value.(type) {
+	case nil:
+		e.WriteNull()
+	case bool:
+		e.WriteBoolean(v)
+	case uint8:
+		e.WriteUbyte(v)
+	case uint16:
+		e.WriteUshort(v)
+	case uint32:
+		e.WriteUint(v)
+	case uint64:
+		e.WriteUlong(v)
+	case int8:
+		e.WriteSignedByte(v)
+	case int16:
+		e.WriteShort(v)
+	case int32:
+		e.WriteInt(v)
+	case int64:
+		e.WriteLong(v)
+	case float32:
+		e.WriteFloat(v)
+	case float64:
+		e.WriteDouble(v)
+	case Timestamp:
+		e.WriteTimestamp(v)
+	case UUID:
+		e.WriteUUID(v)
+	case Decimal32:
+		e.WriteEncoded(SerializeDecimal32Primitive(v))
+	case Decimal64:
+		e.WriteEncoded(SerializeDecimal64Primitive(v))
+	case Decimal128:
+		e.WriteEncoded(SerializeDecimal128Primitive(v))
+	case Binary:
+		e.WriteBinary(v)
+	case []byte:
+		e.WriteBinary(v)
+	case BinaryKey:
+		e.writeLength(binary8Code, binary32Code, len(v))
+		e.buf = append(e.buf, v...)
+	case string:
+		e.WriteString(v)
+	case Symbol:
+		e.WriteSymbol(v)
+	case []Symbol:
+		e.WriteSymbolArray(v)
+	case []interface{}:
+		e.BeginList()
+		for i, item := range v {
+			if err = e.WriteAny(item); err != nil {
+				return errors.New(err.Error() + fmt.Sprintf("\nWriteAny() failed writing list item %d", i))
+			}
+		}
+		return e.EndList()
+	case map[interface{}]interface{}:
+		return e.writeMap(Map(v))
+	case Map:
+		return e.writeMap(v)
+	case Fields:
+		if v == nil {
+			e.WriteNull()
+			return nil
+		}
+		base := e.beginSortedMap()
+		for key, item := range v {
+			if err = e.writePair(key, item); err != nil {
+				return err
+			}
+		}
+		return e.endSortedMap(base)
+	case Annotations:
+		if v == nil {
+			e.WriteNull()
+			return nil
+		}
+		base := e.beginSortedMap()
+		for key, item := range v {
+			switch key.(type) {
+			case Symbol, uint64:
+			default:
+				return fmt.Errorf("amqpx: annotations key must be a symbol or ulong, got %T", key)
+			}
+			if err = e.writePair(key, item); err != nil {
+				return err
+			}
+		}
+		return e.endSortedMap(base)
+	case DescribedType:
+		switch descriptor := v.Descriptor.(type) {
+		case uint64:
+			e.WriteDescriptor(descriptor)
+		case Symbol:
+			e.WriteSymbolicDescriptor(descriptor)
+		default:
+			return fmt.Errorf("amqpx: descriptor must be an ulong or a symbol, got %T", v.Descriptor)
+		}
+		if err = e.WriteAny(v.Value); err != nil {
+			return errors.New(err.Error() + "\nWriteAny() failed writing described Value")
+		}
+	default:
+		return e.Encode(value)
+	}
+	return nil
+}
+
+// writeMap appends a Map, a nil Map is written as null
+func (e *Encoder) writeMap(value Map) error {
+	if value == nil {
+		e.WriteNull()
+		return nil
+	}
+	base := e.beginSortedMap()
+	for key, item := range value {
+		if err := e.writePair(key, item); err != nil {
+			return err
+		}
+	}
+	return e.endSortedMap(base)
+}
+
+// beginSortedMap starts a map whose pairs are ordered by endSortedMap, it returns
+// the index of its first pair
+func (e *Encoder) beginSortedMap() int {
+	e.BeginMap()
+	return len(e.pairs)
+}
+
+// writePair appends a key and its Value To the open map and records where they are
+func (e *Encoder) writePair(key interface{}, value interface{}) error {
+	start := len(e.buf)
+	if err := e.WriteAny(key); err != nil {
+		return errors.New(err.Error() + "\nwritePair() failed writing key")
+	}
+	keyEnd := len(e.buf)
+	if err := e.WriteAny(value); err != nil {
+		return errors.New(err.Error() + "\nwritePair() failed writing Value")
+	}
+	e.pairs = append(e.pairs, mapPair{start: start, keyEnd: keyEnd, end: len(e.buf)})
+	return nil
+}
+
+// endSortedMap orders the pairs written since base by their encoded key, as
+// serializeMapPairs does, and ends the map. The pairs are copied behind the map in
+// their order and moved back, so the buffer is the only scratch space
+func (e *Encoder) endSortedMap(base int) error {
+	pairs := e.pairs[base:]
+	e.pairs = e.pairs[:base]
+	if len(pairs) > 1 {
+		bodyStart, bodyEnd := pairs[0].start, len(e.buf)
+		sort.Slice(pairs, func(i, j int) bool {
+			return bytes.Compare(e.buf[pairs[i].start:pairs[i].keyEnd], e.buf[pairs[j].start:pairs[j].keyEnd]) < 0
+		})
+		for _, p := range pairs {
+			e.buf = append(e.buf, e.buf[p.start:p.end]...)
+		}
+		copy(e.buf[bodyStart:], e.buf[bodyEnd:])
+		e.buf = e.buf[:bodyEnd]
+	}
+	return e.EndMap()
+}
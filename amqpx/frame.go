@@ -0,0 +1,143 @@
+package amqpx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrFrameTooLarge is returned when a frame exceeds the max-frame-size of the receiving side
+var ErrFrameTooLarge = errors.New("amqpx: frame exceeds max-frame-size")
+
+// FrameReader reads whole frames from a stream, e.g. a net.Conn.
+// Spec section 2.3 Framing
+type FrameReader struct {
+	reader io.Reader
+	// MaxFrameSize is the largest frame accepted, 0 for no limit
+	MaxFrameSize uint32
+}
+
+// NewFrameReader returns a FrameReader of reader accepting frames up To maxFrameSize
+func NewFrameReader(reader io.Reader, maxFrameSize uint32) *FrameReader {
+	return &FrameReader{reader: reader, MaxFrameSize: maxFrameSize}
+}
+
+// ReadProtocolHeader reads the 8 byte protocol header that starts the stream and
+// every SASL or TLS layer on it
+func (r *FrameReader) ReadProtocolHeader() (protocolVersion ProtoocolVersion, err error) {
+	buffer := make([]byte, szFrameHeader)
+	if _, err = io.ReadFull(r.reader, buffer); err != nil {
+		return protocolVersion, err
+	}
+	protocolVersion, _, err = ParseProtocolHeader(buffer)
+	return protocolVersion, err
+}
+
+// ReadFrame reads the next AMQP or SASL frame. The size is checked against MaxFrameSize
+// before the frame is read, an empty frame, i.e. a heartbeat, is returned with no Body
+func (r *FrameReader) ReadFrame() (frame Frame, err error) {
+	header := make([]byte, szFrameHeader)
+	if _, err = io.ReadFull(r.reader, header); err != nil {
+		return frame, err
+	}
+	if frame, _, _, err = ParseFraming(header); err != nil {
+		return frame, err
+	}
+	if r.MaxFrameSize != 0 && frame.Size > r.MaxFrameSize {
+		return frame, fmt.Errorf("%w, frame of %d bytes, max-frame-size %d", ErrFrameTooLarge, frame.Size, r.MaxFrameSize)
+	}
+
+	buffer := make([]byte, frame.Size)
+	copy(buffer, header)
+	if _, err = io.ReadFull(r.reader, buffer[szFrameHeader:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return frame, err
+	}
+	doff := 4 * uint32(frame.Doff)
+	frame.ExtendedHeader = buffer[szFrameHeader:doff]
+	frame.Body = buffer[doff:]
+	return frame, nil
+}
+
+// Marshal serializes the frame, Size and Doff are computed from the extended header and
+// the body. The extended header is padded To a multiple of 4 bytes
+func (frame Frame) Marshal() ([]byte, error) {
+	doff := (szFrameHeader + len(frame.ExtendedHeader) + 3) / 4
+	if doff > 0xff {
+		return nil, fmt.Errorf("amqpx: extended header of %d bytes does not fit the data offset", len(frame.ExtendedHeader))
+	}
+	size := uint64(4*doff) + uint64(len(frame.Body))
+	if size > uint64(DefaultMaxFrameSize) {
+		return nil, fmt.Errorf("%w, frame of %d bytes", ErrFrameTooLarge, size)
+	}
+
+	buffer := make([]byte, size)
+	binary.BigEndian.PutUint32(buffer, uint32(size))
+	buffer[4] = byte(doff)
+	buffer[5] = frame.TypeCode
+	binary.BigEndian.PutUint16(buffer[6:], frame.Channel)
+	copy(buffer[szFrameHeader:], frame.ExtendedHeader)
+	copy(buffer[4*doff:], frame.Body)
+	return buffer, nil
+}
+
+// FrameWriter writes whole frames To a stream
+type FrameWriter struct {
+	writer io.Writer
+	// MaxFrameSize is the largest frame the peer accepts, 0 for no limit
+	MaxFrameSize uint32
+}
+
+// NewFrameWriter returns a FrameWriter of writer sending frames up To maxFrameSize
+func NewFrameWriter(writer io.Writer, maxFrameSize uint32) *FrameWriter {
+	return &FrameWriter{writer: writer, MaxFrameSize: maxFrameSize}
+}
+
+// WriteProtocolHeader writes the protocol header for protocolId, 0 for AMQP,
+// 2 for TLS and 3 for SASL
+func (w *FrameWriter) WriteProtocolHeader(protocolId byte) error {
+	header := append([]byte(nil), amqp100...)
+	header[4] = protocolId
+	_, err := w.writer.Write(header)
+	return err
+}
+
+// WriteFrame writes frame, the frame is not written when it exceeds MaxFrameSize
+func (w *FrameWriter) WriteFrame(frame Frame) error {
+	buffer, err := frame.Marshal()
+	if err != nil {
+		return err
+	}
+	if w.MaxFrameSize != 0 && uint64(len(buffer)) > uint64(w.MaxFrameSize) {
+		return fmt.Errorf("%w, frame of %d bytes, max-frame-size %d", ErrFrameTooLarge, len(buffer), w.MaxFrameSize)
+	}
+	_, err = w.writer.Write(buffer)
+	return err
+}
+
+// WritePerformative writes an AMQP frame on channel with performative followed by payload,
+// the message sections of a transfer
+func (w *FrameWriter) WritePerformative(channel uint16, performative Performative, payload []byte) error {
+	body, err := performative.Marshal()
+	if err != nil {
+		return err
+	}
+	return w.WriteFrame(Frame{TypeCode: FrameTypeAMQP, Channel: channel, Body: append(body, payload...)})
+}
+
+// WriteHeartbeat writes an empty AMQP frame, sent To keep the connection from idling out
+func (w *FrameWriter) WriteHeartbeat() error {
+	return w.WriteFrame(Frame{TypeCode: FrameTypeAMQP})
+}
+
+// WriteSasl writes a SASL frame with a sasl-mechanisms, init, challenge, response or outcome body
+func (w *FrameWriter) WriteSasl(performative Performative) error {
+	body, err := performative.Marshal()
+	if err != nil {
+		return err
+	}
+	return w.WriteFrame(Frame{TypeCode: FrameTypeSASL, Body: body})
+}
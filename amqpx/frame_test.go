@@ -0,0 +1,58 @@
+package amqpx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestFrameReaderWriter(t *testing.T) {
+	var stream bytes.Buffer
+	writer := NewFrameWriter(&stream, 0)
+	if err := writer.WriteHeartbeat(); err != nil {
+		t.Fatalf("%s\nWriteHeartbeat was incorrect, expected no errors", err.Error())
+	}
+	if err := writer.WriteFrame(Frame{TypeCode: FrameTypeSASL, ExtendedHeader: []byte{1, 2, 3, 4}, Body: []byte{0x00, 0x53, 0x44, 0x45}}); err != nil {
+		t.Fatalf("%s\nWriteFrame was incorrect, expected no errors", err.Error())
+	}
+	if err := writer.WritePerformative(3, &EndParameters{}, nil); err != nil {
+		t.Fatalf("%s\nWritePerformative was incorrect, expected no errors", err.Error())
+	}
+
+	reader := NewFrameReader(&stream, 0)
+	heartbeat, err := reader.ReadFrame()
+	if err != nil || !heartbeat.IsEmpty() || heartbeat.Size != 8 {
+		t.Errorf("ReadFrame (heartbeat) was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", "empty frame of 8 bytes", heartbeat, err)
+	}
+	sasl, err := reader.ReadFrame()
+	if err != nil || sasl.TypeCode != FrameTypeSASL || sasl.Doff != 3 || !bytes.Equal(sasl.ExtendedHeader, []byte{1, 2, 3, 4}) || !bytes.Equal(sasl.Body, []byte{0x00, 0x53, 0x44, 0x45}) {
+		t.Errorf("ReadFrame (sasl) was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", "sasl frame with extended header", sasl, err)
+	}
+	end, err := reader.ReadFrame()
+	if err == nil {
+		var performative Performative
+		performative, _, err = ParseFrameBody(end.Body)
+		if _, ok := performative.(*EndParameters); !ok || end.Channel != 3 {
+			t.Errorf("ReadFrame (end) was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", "end on channel 3", end, err)
+		}
+	}
+	if err != nil {
+		t.Errorf("%s\nReadFrame (end) was incorrect, expected no errors", err.Error())
+	}
+
+	writer.MaxFrameSize = 512
+	if err = writer.WriteFrame(Frame{Body: make([]byte, 512)}); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("WriteFrame was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", ErrFrameTooLarge, err)
+	}
+	reader.MaxFrameSize = 512
+	stream.Write([]byte{0x00, 0x00, 0x02, 0x01, 0x02, 0x00, 0x00, 0x00})
+	if _, err = reader.ReadFrame(); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("ReadFrame was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", ErrFrameTooLarge, err)
+	}
+	stream.Reset()
+	stream.Write([]byte{0x00, 0x00, 0x00, 0x0c, 0x02, 0x00, 0x00, 0x00, 0x00, 0x53})
+	if _, err = reader.ReadFrame(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadFrame was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", io.ErrUnexpectedEOF, err)
+	}
+}
@@ -0,0 +1,460 @@
+package amqpx
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Run a target with e.g. go test -run XXX -fuzz FuzzParseFraming ./amqpx, the parsers
+// read untrusted bytes from the network so none of them may panic
+
+// addCaptureSeeds seeds the corpus with the frames captured in testdata/captures and
+// with every frame body and described section found in them
+func addCaptureSeeds(f *testing.F) {
+	files, err := filepath.Glob(filepath.Join("testdata", "captures", "*.bin"))
+	if err != nil || len(files) == 0 {
+		f.Fatalf("no captures found in testdata/captures: %v", err)
+	}
+	for _, file := range files {
+		capture, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(capture)
+		addSectionSeeds(f, capture)
+
+		buffer := bytes.TrimPrefix(capture, amqp100)
+		for len(buffer) >= szFrameHeader {
+			frame, _, _, err := ParseFraming(buffer)
+			if err != nil {
+				break
+			}
+			end := int(frame.Size)
+			if end > len(buffer) {
+				end = len(buffer)
+			}
+			if doff := 4 * int(frame.Doff); doff < end {
+				f.Add(buffer[doff:end])
+				addSectionSeeds(f, buffer[doff:end])
+			}
+			buffer = buffer[end:]
+		}
+	}
+}
+
+// addSectionSeeds adds the Value after each descriptor in buffer, these are the
+// buffers given To the performative and message section parsers
+func addSectionSeeds(f *testing.F, buffer []byte) {
+	for inx := uint32(0); inx < uint32(len(buffer)); {
+		if buffer[inx] == 0x00 {
+			_, advanceInx, err := ParseDescriptor(buffer[inx:])
+			if err != nil {
+				return
+			}
+			inx += advanceInx
+			f.Add(buffer[inx:])
+		}
+		_, advanceInx, err := ParseAny(buffer[inx:])
+		if err != nil {
+			return
+		}
+		inx += advanceInx
+	}
+}
+
+// checkRoundTrip serializes the Value parsed from data, parses it again and
+// serializes it again, both serializations must be the same bytes
+func checkRoundTrip(t *testing.T, name string, data []byte, reserialize func([]byte) ([]byte, error)) {
+	first, err := reserialize(data)
+	if err != nil {
+		return
+	}
+	second, err := reserialize(first)
+	if err != nil {
+		t.Fatalf("%s\n%s was incorrect reading its own serialization % x", err.Error(), name, first)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatalf("%s round trip was incorrect, \n\texpected: \"% x\" \n\tgot:\"% x\"", name, first, second)
+	}
+}
+
+// checkBytesUsed fails when a parser claims To have read more than it was given
+func checkBytesUsed(t *testing.T, name string, data []byte, bytesUsed uint32, err error) {
+	if err == nil && bytesUsed > uint32(len(data)) {
+		t.Fatalf("%s was incorrect bytesUsed, \n\texpected: \"<= %d\" \n\tgot:\"%d\"", name, len(data), bytesUsed)
+	}
+}
+
+func FuzzParseProtocolHeader(f *testing.F) {
+	addCaptureSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, bytesUsed, err := ParseProtocolHeader(data)
+		checkBytesUsed(t, "ParseProtocolHeader", data, bytesUsed, err)
+	})
+}
+
+func FuzzParseFraming(f *testing.F) {
+	addCaptureSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, bytesUsed, _, err := ParseFraming(data)
+		checkBytesUsed(t, "ParseFraming", data, bytesUsed, err)
+	})
+}
+
+func FuzzParseFrameBody(f *testing.F) {
+	addCaptureSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		performative, payload, err := ParseFrameBody(data)
+		if err != nil {
+			return
+		}
+		if len(payload) > len(data) {
+			t.Fatalf("ParseFrameBody was incorrect payload, \n\texpected: \"<= %d\" \n\tgot:\"%d\"", len(data), len(payload))
+		}
+		checkRoundTrip(t, "ParseFrameBody", data, func(buffer []byte) ([]byte, error) {
+			performative, _, err := ParseFrameBody(buffer)
+			if err != nil {
+				return nil, err
+			}
+			return performative.Marshal()
+		})
+		_ = performative.String()
+	})
+}
+
+func FuzzParsePerformativeOpen(f *testing.F) {
+	addCaptureSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, bytesUsed, err := ParsePerformativeOpen(data)
+		checkBytesUsed(t, "ParsePerformativeOpen", data, bytesUsed, err)
+		checkRoundTrip(t, "ParsePerformativeOpen", data, func(buffer []byte) ([]byte, error) {
+			connParameters, _, err := ParsePerformativeOpen(buffer)
+			if err != nil {
+				return nil, err
+			}
+			return Marshal(connParameters)
+		})
+	})
+}
+
+func FuzzParsePerformativeBegin(f *testing.F) {
+	addCaptureSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, bytesUsed, err := ParsePerformativeBegin(data)
+		checkBytesUsed(t, "ParsePerformativeBegin", data, bytesUsed, err)
+		checkRoundTrip(t, "ParsePerformativeBegin", data, func(buffer []byte) ([]byte, error) {
+			sessionParameters, _, err := ParsePerformativeBegin(buffer)
+			if err != nil {
+				return nil, err
+			}
+			return Marshal(sessionParameters)
+		})
+	})
+}
+
+func FuzzParsePerformativeAttach(f *testing.F) {
+	addCaptureSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, bytesUsed, err := ParsePerformativeAttach(data)
+		checkBytesUsed(t, "ParsePerformativeAttach", data, bytesUsed, err)
+		checkRoundTrip(t, "ParsePerformativeAttach", data, func(buffer []byte) ([]byte, error) {
+			attachParameters, _, err := ParsePerformativeAttach(buffer)
+			if err != nil {
+				return nil, err
+			}
+			return Marshal(attachParameters)
+		})
+	})
+}
+
+func FuzzParsePerformativeFlow(f *testing.F) {
+	addCaptureSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, bytesUsed, err := ParsePerformativeFlow(data)
+		checkBytesUsed(t, "ParsePerformativeFlow", data, bytesUsed, err)
+		checkRoundTrip(t, "ParsePerformativeFlow", data, func(buffer []byte) ([]byte, error) {
+			flowParameters, _, err := ParsePerformativeFlow(buffer)
+			if err != nil {
+				return nil, err
+			}
+			return Marshal(flowParameters)
+		})
+	})
+}
+
+func FuzzParsePerformativeTransfer(f *testing.F) {
+	addCaptureSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, bytesUsed, err := ParsePerformativeTransfer(data)
+		checkBytesUsed(t, "ParsePerformativeTransfer", data, bytesUsed, err)
+		checkRoundTrip(t, "ParsePerformativeTransfer", data, func(buffer []byte) ([]byte, error) {
+			transfer, _, err := ParsePerformativeTransfer(buffer)
+			if err != nil {
+				return nil, err
+			}
+			return Marshal(transfer)
+		})
+	})
+}
+
+func FuzzParsePerformativeDisposition(f *testing.F) {
+	addCaptureSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, bytesUsed, err := ParsePerformativeDisposition(data)
+		checkBytesUsed(t, "ParsePerformativeDisposition", data, bytesUsed, err)
+		checkRoundTrip(t, "ParsePerformativeDisposition", data, func(buffer []byte) ([]byte, error) {
+			disposition, _, err := ParsePerformativeDisposition(buffer)
+			if err != nil {
+				return nil, err
+			}
+			return Marshal(disposition)
+		})
+	})
+}
+
+func FuzzParseMessageHeader(f *testing.F) {
+	addCaptureSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, bytesUsed, err := ParseMessageHeader(data)
+		checkBytesUsed(t, "ParseMessageHeader", data, bytesUsed, err)
+		checkRoundTrip(t, "ParseMessageHeader", data, func(buffer []byte) ([]byte, error) {
+			header, _, err := ParseMessageHeader(buffer)
+			if err != nil {
+				return nil, err
+			}
+			return Marshal(header)
+		})
+	})
+}
+
+func FuzzParseMessageProperties(f *testing.F) {
+	addCaptureSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, bytesUsed, err := ParseMessageProperties(data)
+		checkBytesUsed(t, "ParseMessageProperties", data, bytesUsed, err)
+		checkRoundTrip(t, "ParseMessageProperties", data, func(buffer []byte) ([]byte, error) {
+			properties, _, err := ParseMessageProperties(buffer)
+			if err != nil {
+				return nil, err
+			}
+			return Marshal(properties)
+		})
+	})
+}
+
+func FuzzParseMessageAmqpValue(f *testing.F) {
+	addCaptureSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, bytesUsed, err := ParseMessageAmqpValue(data)
+		checkBytesUsed(t, "ParseMessageAmqpValue", data, bytesUsed, err)
+	})
+}
+
+func FuzzMessageUnmarshal(f *testing.F) {
+	addCaptureSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var message Message
+		bytesUsed, err := message.Unmarshal(data)
+		checkBytesUsed(t, "Message.Unmarshal", data, bytesUsed, err)
+		checkRoundTrip(t, "Message.Unmarshal", data, func(buffer []byte) ([]byte, error) {
+			var message Message
+			if _, err := message.Unmarshal(buffer); err != nil {
+				return nil, err
+			}
+			return message.Marshal()
+		})
+	})
+}
+
+func FuzzParseAny(f *testing.F) {
+	addCaptureSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, bytesUsed, err := ParseAny(data)
+		checkBytesUsed(t, "ParseAny", data, bytesUsed, err)
+		checkRoundTrip(t, "ParseAny", data, func(buffer []byte) ([]byte, error) {
+			value, _, err := ParseAny(buffer)
+			if err != nil {
+				return nil, err
+			}
+			return SerializeAny(value)
+		})
+	})
+}
+
+// primitiveParsers reads a primitive from a buffer and serializes it again
+var primitiveParsers = map[string]func([]byte) ([]byte, uint32, error){
+	"ParseBooleanPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseBooleanPrimitive(b)
+		return SerializeBooleanPrimitive(v), n, err
+	},
+	"ParseUbytePrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseUbytePrimitive(b)
+		return SerializeUbytePrimitive(v), n, err
+	},
+	"PraseUshortPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := PraseUshortPrimitive(b)
+		return SerializeUshortPrimitive(v), n, err
+	},
+	"ParseUintPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseUintPrimitive(b)
+		return SerializeUintPrimitive(v), n, err
+	},
+	"ParseUlongPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseUlongPrimitive(b)
+		return SerializeUlongPrimitive(v), n, err
+	},
+	"ParseBytePrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseBytePrimitive(b)
+		return SerializeBytePrimitive(v), n, err
+	},
+	"ParseShortPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseShortPrimitive(b)
+		return SerializeShortPrimitive(v), n, err
+	},
+	"ParseIntPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseIntPrimitive(b)
+		return SerializeIntPrimitive(v), n, err
+	},
+	"ParseLongPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseLongPrimitive(b)
+		return SerializeLongPrimitive(v), n, err
+	},
+	"ParseFloatPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseFloatPrimitive(b)
+		return SerializeFloatPrimitive(v), n, err
+	},
+	"ParseDoublePrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseDoublePrimitive(b)
+		return SerializeDoublePrimitive(v), n, err
+	},
+	"ParseDecimal32Primitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseDecimal32Primitive(b)
+		return SerializeDecimal32Primitive(v), n, err
+	},
+	"ParseDecimal64Primitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseDecimal64Primitive(b)
+		return SerializeDecimal64Primitive(v), n, err
+	},
+	"ParseDecimal128Primitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseDecimal128Primitive(b)
+		return SerializeDecimal128Primitive(v), n, err
+	},
+	"ParseCharPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseCharPrimitive(b)
+		return SerializeCharPrimitive(v), n, err
+	},
+	"ParseTimestampPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseTimestampPrimitive(b)
+		return SerializeTimestampPrimitive(v), n, err
+	},
+	"ParseUuidPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseUuidPrimitive(b)
+		return SerializeUuidPrimitive(v), n, err
+	},
+	"ParseBinaryPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseBinaryPrimitive(b)
+		return SerializeBinaryPrimitive(v), n, err
+	},
+	"ParseStringPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseStringPrimitive(b)
+		return SerializeStringPrimitive(v), n, err
+	},
+	"ParseSymbolPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseSymbolPrimitive(b)
+		return SerializeSymbolPrimitive(v), n, err
+	},
+	"ParseSymbolArrayPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseSymbolArrayPrimitive(b)
+		return SerializeSymbolArrayPrimitive(v), n, err
+	},
+	"ParseDeliveryStatePrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseDeliveryStatePrimitive(b)
+		if err != nil {
+			return nil, n, err
+		}
+		buf, err := SerializeDeliveryStatePrimitive(v)
+		return buf, n, err
+	},
+	"ParseMapPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseMapPrimitive(b)
+		if err != nil {
+			return nil, n, err
+		}
+		buf, err := SerializeMapPrimitive(v)
+		return buf, n, err
+	},
+	"ParseFieldsPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseFieldsPrimitive(b)
+		if err != nil {
+			return nil, n, err
+		}
+		buf, err := SerializeFieldsPrimitive(v)
+		return buf, n, err
+	},
+	"ParseAnnotationsPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseAnnotationsPrimitive(b)
+		if err != nil {
+			return nil, n, err
+		}
+		buf, err := SerializeAnnotationsPrimitive(v)
+		return buf, n, err
+	},
+	"ParseArrayPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseArrayPrimitive(b)
+		if err != nil {
+			return nil, n, err
+		}
+		buf, err := SerializeAny(v)
+		return buf, n, err
+	},
+	"ParseDescribedPrimitive": func(b []byte) ([]byte, uint32, error) {
+		v, n, err := ParseDescribedPrimitive(b)
+		if err != nil {
+			return nil, n, err
+		}
+		buf, err := SerializeAny(v)
+		return buf, n, err
+	},
+	"ParseListPrimitive": func(b []byte) ([]byte, uint32, error) {
+		_, _, _, n, err := ParseListPrimitive(b)
+		return nil, n, err
+	},
+	"ParseDescriptor": func(b []byte) ([]byte, uint32, error) {
+		_, n, err := ParseDescriptor(b)
+		return nil, n, err
+	},
+	"ParseBlockType": func(b []byte) ([]byte, uint32, error) {
+		_, n, err := ParseBlockType(b)
+		return nil, n, err
+	},
+	"ParseRawBytePrimitive": func(b []byte) ([]byte, uint32, error) {
+		_, n, err := ParseRawBytePrimitive(b)
+		return nil, n, err
+	},
+}
+
+func FuzzParsePrimitives(f *testing.F) {
+	addCaptureSeeds(f)
+	for _, seed := range [][]byte{
+		{0x56, 0x01}, {0x60, 0x7f, 0xff}, {0x72, 0x7f, 0xc0, 0x00, 0x00}, {0x73, 0x00, 0x01, 0xf6, 0x00},
+		{0x83, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00}, {0x98, 0xf8, 0x1d, 0x4f, 0xae, 0x7d, 0xec, 0x11, 0xd0, 0xa7, 0x65, 0x00, 0xa0, 0xc9, 0x1e, 0x6b, 0xf6},
+		{0xe0, 0x05, 0x02, 0xa3, 0x01, 0x61, 0x01, 0x62}, {0xc1, 0x05, 0x02, 0xa3, 0x01, 0x6b, 0x41},
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for name, parse := range primitiveParsers {
+			first, bytesUsed, err := parse(data)
+			checkBytesUsed(t, name, data, bytesUsed, err)
+			if err != nil || first == nil {
+				continue
+			}
+			checkRoundTrip(t, name, first, func(buffer []byte) ([]byte, error) {
+				buf, _, err := parse(buffer)
+				return buf, err
+			})
+		}
+	})
+}
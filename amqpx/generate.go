@@ -0,0 +1,8 @@
+package amqpx
+
+// The composites in spec*.go are generated from the type definitions of the AMQP 1.0
+// specification in xml/, regenerate them after changing the xml or internal/specgen with
+//
+//	go generate ./amqpx
+
+//go:generate go run ./internal/specgen -dir . xml/transport.xml xml/messaging.xml xml/security.xml xml/transactions.xml
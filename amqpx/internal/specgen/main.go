@@ -0,0 +1,527 @@
+// Command specgen generates the composite types of package amqpx from the XML type
+// definitions of the AMQP 1.0 specification.
+//
+//	go run ./internal/specgen -dir . xml/transport.xml xml/messaging.xml ...
+//
+// All files are read first so that a type may refer To a type of another file, then
+// a go file named spec<Name>.go is written To dir for each <amqp name="..."> element.
+// The composites in composites become structs tagged for Marshal and Unmarshal and the
+// message sections in sections become go types of their source, each with the methods
+// Descriptor, Marshal and Unmarshal. The other types of the spec are the go types of
+// package amqpx listed in goTypes
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/types"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type amqpXML struct {
+	Name     string       `xml:"name,attr"`
+	Sections []sectionXML `xml:"section"`
+}
+
+type sectionXML struct {
+	Name  string    `xml:"name,attr"`
+	Label string    `xml:"label,attr"`
+	Types []typeXML `xml:"type"`
+}
+
+type typeXML struct {
+	Name       string         `xml:"name,attr"`
+	Class      string         `xml:"class,attr"`
+	Source     string         `xml:"source,attr"`
+	Provides   string         `xml:"provides,attr"`
+	Label      string         `xml:"label,attr"`
+	Descriptor *descriptorXML `xml:"descriptor"`
+	Fields     []fieldXML     `xml:"field"`
+	Choices    []choiceXML    `xml:"choice"`
+}
+
+type descriptorXML struct {
+	Name string `xml:"name,attr"`
+	Code string `xml:"code,attr"`
+}
+
+type fieldXML struct {
+	Name      string `xml:"name,attr"`
+	Type      string `xml:"type,attr"`
+	Requires  string `xml:"requires,attr"`
+	Default   string `xml:"default,attr"`
+	Mandatory bool   `xml:"mandatory,attr"`
+	Multiple  bool   `xml:"multiple,attr"`
+}
+
+type choiceXML struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// composites are the generated composites and their go names
+var composites = map[string]string{
+	"open":                           "ConnectionParameters",
+	"begin":                          "SessionParameters",
+	"attach":                         "AttachParameters",
+	"flow":                           "FlowParameters",
+	"transfer":                       "TransferParameters",
+	"disposition":                    "DispositionParameters",
+	"detach":                         "DetachParameters",
+	"end":                            "EndParameters",
+	"close":                          "CloseParameters",
+	"error":                          "Error",
+	"header":                         "MessageHeader",
+	"properties":                     "MessageProperties",
+	"received":                       "Received",
+	"accepted":                       "Accepted",
+	"rejected":                       "Rejected",
+	"released":                       "Released",
+	"modified":                       "Modified",
+	"source":                         "Source",
+	"target":                         "Target",
+	"delete-on-close":                "DeleteOnClose",
+	"delete-on-no-links":             "DeleteOnNoLinks",
+	"delete-on-no-messages":          "DeleteOnNoMessages",
+	"delete-on-no-links-or-messages": "DeleteOnNoLinksOrMessages",
+	"sasl-mechanisms":                "SaslMechanisms",
+	"sasl-init":                      "SaslInit",
+	"sasl-challenge":                 "SaslChallenge",
+	"sasl-response":                  "SaslResponse",
+	"sasl-outcome":                   "SaslOutcome",
+	"coordinator":                    "Coordinator",
+	"declare":                        "Declare",
+	"discharge":                      "Discharge",
+	"declared":                       "Declared",
+	"transactional-state":            "TransactionalState",
+}
+
+// sections are the generated restricted types of the message sections and their go names
+var sections = map[string]string{
+	"delivery-annotations":   "DeliveryAnnotations",
+	"message-annotations":    "MessageAnnotations",
+	"application-properties": "ApplicationProperties",
+	"data":                   "Data",
+	"amqp-sequence":          "AmqpSequence",
+	"amqp-value":             "MessageAmqpValue",
+	"footer":                 "Footer",
+}
+
+// fieldNames are the go names of the fields not named after their spec name
+var fieldNames = map[string]string{
+	"open.idle-time-out":                     "IdleTimeoutMs",
+	"begin.next-outgoing-id":                 "NextOutgoing",
+	"flow.next-incoming-id":                  "NextIncoming",
+	"flow.next-outgoing-id":                  "NextOutgoing",
+	"sasl-mechanisms.sasl-server-mechanisms": "Mechanisms",
+	"properties.absolute-expiry-time":        "AbsExpiryTime",
+	"properties.reply-to-group-id":           "ReplyToGroupID",
+}
+
+// jsonNames are the json names of the fields not named after their spec name
+var jsonNames = map[string]string{
+	"open.idle-time-out":     "idleTimeoutMs",
+	"begin.next-outgoing-id": "nextOutgoing",
+	"flow.next-incoming-id":  "nextIncoming",
+	"flow.next-outgoing-id":  "nextOutgoing",
+}
+
+// goType is the go type of a spec type, encoding is the type option of the field tag
+// for a go type that Marshal would write as another AMQP type
+type goType struct {
+	name     string
+	encoding string
+}
+
+// goTypes maps the primitive and restricted types of the spec, and the archetypes
+// a field of type * requires, To go
+var goTypes = map[string]goType{
+	"boolean":   {"BooleanChoice", ""},
+	"ubyte":     {"byte", ""},
+	"ushort":    {"uint16", ""},
+	"uint":      {"uint32", ""},
+	"ulong":     {"uint64", ""},
+	"byte":      {"int8", ""},
+	"short":     {"int16", ""},
+	"int":       {"int32", ""},
+	"long":      {"int64", ""},
+	"float":     {"float32", ""},
+	"double":    {"float64", ""},
+	"timestamp": {"Timestamp", ""},
+	"uuid":      {"UUID", ""},
+	"binary":    {"Binary", ""},
+	"string":    {"string", ""},
+	"symbol":    {"Symbol", ""},
+	"list":      {"[]interface{}", ""},
+	"map":       {"Map", ""},
+	"*":         {"interface{}", ""},
+
+	"fields":                 {"Fields", ""},
+	"annotations":            {"Annotations", ""},
+	"handle":                 {"Handle", ""},
+	"seconds":                {"uint32", ""},
+	"milliseconds":           {"Milliseconds", ""},
+	"delivery-tag":           {"DeliveryTag", ""},
+	"delivery-number":        {"DeliveryNumber", ""},
+	"transfer-number":        {"TransferNumber", ""},
+	"sequence-no":            {"SequenceNo", ""},
+	"message-format":         {"MessageFormat", ""},
+	"ietf-language-tag":      {"Symbol", ""},
+	"role":                   {"RoleChoice", ""},
+	"sender-settle-mode":     {"SenderSettleModeChoice", ""},
+	"receiver-settle-mode":   {"ReceiverSettleModeChoice", ""},
+	"sasl-code":              {"SaslCode", ""},
+	"terminus-durability":    {"TerminusDurabilityChoice", "uint"},
+	"terminus-expiry-policy": {"TerminusExpiryPolicyChoice", "symbol"},
+	"filter-set":             {"FilterSet", ""},
+	"node-properties":        {"Fields", ""},
+
+	"address":        {"string", ""},
+	"target":         {"LinkTarget", ""},
+	"message-id":     {"MessageID", ""},
+	"delivery-state": {"DeliveryState", ""},
+	"outcome":        {"Outcome", ""},
+	"txn-id":         {"Binary", ""},
+	"global-tx-id":   {"interface{}", ""},
+}
+
+// nilTypes are the go types of goTypes that are nil when not set. An optional field of
+// another type is a pointer unless its default is the zero Value, so that a zero is sent
+// and a field that is not set is null
+var nilTypes = map[string]bool{
+	"[]interface{}": true,
+	"Map":           true,
+	"interface{}":   true,
+	"Binary":        true,
+	"Fields":        true,
+	"Annotations":   true,
+	"DeliveryTag":   true,
+	"FilterSet":     true,
+	"MessageID":     true,
+	"DeliveryState": true,
+	"Outcome":       true,
+	"LinkTarget":    true,
+}
+
+// generator resolves the types of all files
+type generator struct {
+	types map[string]*typeXML
+}
+
+func main() {
+	dir := flag.String("dir", ".", "directory the go files are written To")
+	flag.Parse()
+
+	g := &generator{types: map[string]*typeXML{}}
+	var files []amqpXML
+	for _, path := range flag.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var file amqpXML
+		if err = xml.Unmarshal(data, &file); err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+		for s := range file.Sections {
+			for t := range file.Sections[s].Types {
+				typ := &file.Sections[s].Types[t]
+				g.types[typ.Name] = typ
+			}
+		}
+		files = append(files, file)
+	}
+
+	for _, file := range files {
+		src, err := g.generate(file)
+		if err != nil {
+			log.Fatalf("%s: %v", file.Name, err)
+		}
+		if src == nil {
+			continue
+		}
+		if err = os.WriteFile(filepath.Join(*dir, "spec"+goName(file.Name)+".go"), src, 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// goName converts a spec name such as container-id To ContainerId
+func goName(name string) string {
+	var sb strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '-' || r == ':' || r == '*' }) {
+		sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return sb.String()
+}
+
+// jsonName converts a spec name such as container-id To containerId
+func jsonName(name string) string {
+	name = goName(name)
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// fieldType returns the go type of a field and the type option of its tag. An archetype
+// in goTypes, as target, takes precedence over the composite of the same name
+func (g *generator) fieldType(f fieldXML) (goType string, encoding string, err error) {
+	name := f.Type
+	if name == "*" && f.Requires != "" {
+		name = f.Requires
+	}
+	if t, ok := goTypes[name]; ok {
+		goType, encoding = t.name, t.encoding
+		if !f.Mandatory && !f.Multiple && !nilTypes[goType] && !g.zeroDefault(f) {
+			goType = "*" + goType
+		}
+	} else if composite, ok := composites[name]; ok {
+		goType = "*" + composite
+	} else {
+		return "", "", fmt.Errorf("no go type for %q", name)
+	}
+	if f.Multiple {
+		goType = "[]" + goType
+	}
+	return goType, encoding, nil
+}
+
+// defaultValue resolves the default of a field, a choice name becomes its Value
+func (g *generator) defaultValue(f fieldXML) string {
+	if typ, ok := g.types[f.Type]; ok {
+		for _, choice := range typ.Choices {
+			if choice.Name == f.Default {
+				return choice.Value
+			}
+		}
+	}
+	return f.Default
+}
+
+// zeroDefault tells whether the default of a field is the zero Value of its go type
+func (g *generator) zeroDefault(f fieldXML) bool {
+	switch g.defaultValue(f) {
+	case "0", "false":
+		return true
+	}
+	return false
+}
+
+// receiver returns the receiver name of the methods of a go type, Error is e
+func receiver(goType string) string {
+	name := strings.ToLower(goType[:1]) + goType[1:]
+	if types.Universe.Lookup(name) != nil {
+		return name[:1]
+	}
+	return name
+}
+
+// generate returns the go file of the composites of file, nil when it has none
+func (g *generator) generate(file amqpXML) ([]byte, error) {
+	var types bytes.Buffer
+	for _, section := range file.Sections {
+		for _, typ := range section.Types {
+			var err error
+			if _, ok := composites[typ.Name]; ok && typ.Class == "composite" {
+				err = g.composite(&types, section, typ)
+			} else if _, ok := sections[typ.Name]; ok && typ.Class == "restricted" {
+				err = g.section(&types, section, typ)
+			} else {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", typ.Name, err)
+			}
+		}
+	}
+	if types.Len() == 0 {
+		return nil, nil
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by specgen from xml/%s.xml. DO NOT EDIT.\n\n", file.Name)
+	b.WriteString("package amqpx\n\n")
+	b.Write(types.Bytes())
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%v\n%s", err, b.String())
+	}
+	return src, nil
+}
+
+// typeComment writes the doc comment of a composite, its description and its definition in the spec
+func typeComment(b *bytes.Buffer, section sectionXML, typ typeXML) {
+	description := typ.Label
+	if description == "" {
+		label := section.Label
+		if label == "" {
+			label = section.Name
+		}
+		description = fmt.Sprintf("the %s composite of the %s", typ.Name, label)
+	}
+	fmt.Fprintf(b, "// %s .. %s\n", goTypeName(typ), description)
+	fmt.Fprintf(b, "// <type name=%q class=%q source=%q", typ.Name, typ.Class, typ.Source)
+	if typ.Provides != "" {
+		fmt.Fprintf(b, " provides=%q", typ.Provides)
+	}
+	b.WriteString(">\n//\n")
+	if typ.Descriptor != nil {
+		fmt.Fprintf(b, "//\t<descriptor name=%q code=%q/>\n", typ.Descriptor.Name, typ.Descriptor.Code)
+	}
+	for _, f := range typ.Fields {
+		fmt.Fprintf(b, "//\t<field name=%q type=%q", f.Name, f.Type)
+		if f.Requires != "" {
+			fmt.Fprintf(b, " requires=%q", f.Requires)
+		}
+		if f.Default != "" {
+			fmt.Fprintf(b, " default=%q", f.Default)
+		}
+		if f.Mandatory {
+			b.WriteString(` mandatory="true"`)
+		}
+		if f.Multiple {
+			b.WriteString(` multiple="true"`)
+		}
+		b.WriteString("/>\n")
+	}
+	b.WriteString("//\n// </type>\n")
+}
+
+// goTypeName returns the go name of a generated type
+func goTypeName(typ typeXML) string {
+	if name, ok := composites[typ.Name]; ok {
+		return name
+	}
+	return sections[typ.Name]
+}
+
+// descriptorCode returns the descriptor code of typ as a go literal, 0x10 for 0x00000000:0x00000010
+func descriptorCode(typ typeXML) (string, error) {
+	parts := strings.Split(typ.Descriptor.Code, ":")
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid descriptor code %q", typ.Descriptor.Code)
+	}
+	domain, err := strconv.ParseUint(parts[0], 0, 32)
+	if err != nil {
+		return "", err
+	}
+	id, err := strconv.ParseUint(parts[1], 0, 32)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("0x%02x", domain<<32|id), nil
+}
+
+// methods writes the Descriptor method of a generated type and, unless they are the
+// hooks of Marshal and Unmarshal, its Marshal and Unmarshal methods
+func methods(b *bytes.Buffer, typ typeXML, code string, marshal string, unmarshal string) {
+	name, recv := goTypeName(typ), receiver(goTypeName(typ))
+	fmt.Fprintf(b, "// Descriptor returns the descriptor code of %s\n", typ.Name)
+	fmt.Fprintf(b, "func (%s) Descriptor() uint64 {\n\treturn %s\n}\n\n", name, code)
+	fmt.Fprintf(b, "// %s serializes %s including its descriptor\n", marshal, typ.Name)
+	fmt.Fprintf(b, "func (%s %s) %s() ([]byte, error) {\n", recv, name, marshal)
+	if typ.Class == "composite" {
+		fmt.Fprintf(b, "\treturn Marshal(%s)\n}\n\n", recv)
+	} else {
+		fmt.Fprintf(b, "\treturn marshalDescribed(%s, %s)\n}\n\n", code, sectionValue(typ, recv))
+	}
+	if typ.Class == "composite" {
+		fmt.Fprintf(b, "// %s reads %s from buffer, with or without its descriptor\n", unmarshal, typ.Name)
+		fmt.Fprintf(b, "func (%s *%s) %s(buffer []byte) (bytesUsed uint32, err error) {\n", recv, name, unmarshal)
+		fmt.Fprintf(b, "\treturn Unmarshal(buffer, %s)\n}\n\n", recv)
+	} else {
+		fmt.Fprintf(b, "// %s reads %s from buffer including its descriptor\n", unmarshal, typ.Name)
+		fmt.Fprintf(b, "func (%s *%s) %s(buffer []byte) (bytesUsed uint32, err error) {\n", recv, name, unmarshal)
+		fmt.Fprintf(b, "\treturn unmarshalDescribed(buffer, %q, %s, %s)\n}\n\n", typ.Descriptor.Name, code, sectionPointer(typ, recv))
+	}
+}
+
+// sectionValue returns the expression of the Value of section recv, its source type
+// or its Value field when the source is *
+func sectionValue(typ typeXML, recv string) string {
+	if typ.Source == "*" {
+		return recv + ".Value"
+	}
+	return "(" + goTypes[typ.Source].name + ")(" + recv + ")"
+}
+
+// sectionPointer returns the expression of a pointer To the Value of the section recv points To
+func sectionPointer(typ typeXML, recv string) string {
+	if typ.Source == "*" {
+		return "&" + recv + ".Value"
+	}
+	return "(*" + goTypes[typ.Source].name + ")(" + recv + ")"
+}
+
+// section writes a message section, a restricted type described by its descriptor. A
+// section of any type is a struct holding the Value
+func (g *generator) section(b *bytes.Buffer, section sectionXML, typ typeXML) error {
+	if typ.Descriptor == nil {
+		return fmt.Errorf("section without descriptor")
+	}
+	code, err := descriptorCode(typ)
+	if err != nil {
+		return err
+	}
+	source, ok := goTypes[typ.Source]
+	if !ok {
+		return fmt.Errorf("no go type for %q", typ.Source)
+	}
+	typeComment(b, section, typ)
+	if typ.Source == "*" {
+		fmt.Fprintf(b, "type %s struct {\n\tValue interface{} `json:\"value\"`\n}\n\n", goTypeName(typ))
+	} else {
+		fmt.Fprintf(b, "type %s %s\n\n", goTypeName(typ), source.name)
+	}
+	methods(b, typ, code, "MarshalAMQP", "UnmarshalAMQP")
+	return nil
+}
+
+func (g *generator) composite(b *bytes.Buffer, section sectionXML, typ typeXML) error {
+	if typ.Descriptor == nil {
+		return fmt.Errorf("composite without descriptor")
+	}
+	code, err := descriptorCode(typ)
+	if err != nil {
+		return err
+	}
+	typeComment(b, section, typ)
+	fmt.Fprintf(b, "type %s struct {\n", composites[typ.Name])
+	fmt.Fprintf(b, "\t_ struct{} `amqp:\"%s,%s\"`\n", typ.Descriptor.Name, typ.Descriptor.Code)
+	for _, f := range typ.Fields {
+		goType, encoding, err := g.fieldType(f)
+		if err != nil {
+			return err
+		}
+		name, ok := fieldNames[typ.Name+"."+f.Name]
+		if !ok {
+			name = goName(f.Name)
+		}
+		json, ok := jsonNames[typ.Name+"."+f.Name]
+		if !ok {
+			json = jsonName(f.Name)
+		}
+
+		tag := f.Name
+		if f.Mandatory {
+			tag += ",mandatory"
+		} else {
+			json += ",omitempty"
+		}
+		if f.Default != "" {
+			tag += ",default=" + g.defaultValue(f)
+		}
+		if encoding != "" {
+			tag += ",type=" + encoding
+		}
+		fmt.Fprintf(b, "\t%s %s `json:\"%s\" amqp:\"%s\"`\n", name, goType, json, tag)
+	}
+	b.WriteString("}\n\n")
+	methods(b, typ, code, "Marshal", "Unmarshal")
+	return nil
+}
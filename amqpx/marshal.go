@@ -0,0 +1,799 @@
+package amqpx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Marshaler is implemented by types that serialize themselves
+type Marshaler interface {
+	MarshalAMQP() ([]byte, error)
+}
+
+// Unmarshaler is implemented by types that parse themselves from buffer
+type Unmarshaler interface {
+	UnmarshalAMQP(buffer []byte) (bytesUsed uint32, err error)
+}
+
+// validator is implemented by composites with constraints their tags do not tell, it
+// is called before a composite is marshaled and after it is unmarshaled
+type validator interface {
+	validate() error
+}
+
+var (
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+)
+
+// compositeField is a struct field read from its amqp tag
+type compositeField struct {
+	index        int
+	name         string
+	mandatory    bool
+	hasDefault   bool
+	defaultValue reflect.Value
+	typeName     string
+}
+
+// composite is the list layout of a struct
+type composite struct {
+	name           string // used in the field path of a DecodeError
+	described      bool
+	descriptorCode uint64
+	descriptorName Symbol
+	fields         []compositeField
+}
+
+// composites caches the layout of each struct type
+var composites sync.Map
+
+// compositeOf returns the list layout of a struct type. Fields are read from tags of the form
+//
+//	_       struct{} `amqp:"amqp:header:list,0x00000000:0x00000070"`
+//	Durable bool     `amqp:"durable,default=false"`
+//	Role    bool     `amqp:"role,mandatory"`
+//	Durable uint32   `amqp:"durable,type=uint"`
+//	Ignored string   `amqp:"-"`
+//
+// The tag on the blank field holds the descriptor name and code of a described composite.
+// Exported fields without a tag are list items named after the go field
+func compositeOf(t reflect.Type) (*composite, error) {
+	if c, ok := composites.Load(t); ok {
+		return c.(*composite), nil
+	}
+
+	c := &composite{name: strings.ToLower(t.Name())}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, hasTag := sf.Tag.Lookup("amqp")
+		if tag == "-" {
+			continue
+		}
+		options := strings.Split(tag, ",")
+
+		if sf.Name == "_" {
+			if !hasTag {
+				continue
+			}
+			c.described = true
+			c.descriptorName = Symbol(options[0])
+			if parts := strings.Split(options[0], ":"); len(parts) == 3 {
+				c.name = parts[1]
+			}
+			if len(options) > 1 {
+				code, err := parseDescriptorCode(options[1])
+				if err != nil {
+					return nil, fmt.Errorf("amqpx: %s has an invalid descriptor %q", t, options[1])
+				}
+				c.descriptorCode = code
+			}
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		field := compositeField{index: i, name: options[0]}
+		if field.name == "" {
+			field.name = sf.Name
+		}
+		for _, option := range options[1:] {
+			switch {
+			case option == "mandatory":
+				field.mandatory = true
+			case strings.HasPrefix(option, "default="):
+				value, err := parseDefault(sf.Type, strings.TrimPrefix(option, "default="))
+				if err != nil {
+					return nil, errors.New(err.Error() + fmt.Sprintf("\ncompositeOf() failed reading default of %s.%s", t, sf.Name))
+				}
+				field.hasDefault = true
+				field.defaultValue = value
+			case strings.HasPrefix(option, "type="):
+				field.typeName = strings.TrimPrefix(option, "type=")
+			default:
+				return nil, fmt.Errorf("amqpx: unknown amqp tag option %q on %s.%s", option, t, sf.Name)
+			}
+		}
+		c.fields = append(c.fields, field)
+	}
+
+	actual, _ := composites.LoadOrStore(t, c)
+	return actual.(*composite), nil
+}
+
+// sentAsNull tells whether an optional field is left out as null: when it is nil or
+// holds its default. Any other Value is sent, a zero as well, so an optional field
+// that may be missing without a default is a pointer
+func (field compositeField) sentAsNull(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		if fv.IsNil() {
+			return true
+		}
+	}
+	return field.hasDefault && reflect.DeepEqual(fv.Interface(), field.defaultValue.Interface())
+}
+
+// hasMandatory tells whether a field of the composite is mandatory
+func (c *composite) hasMandatory() bool {
+	for _, field := range c.fields {
+		if field.mandatory {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDescriptorCode reads a descriptor code written as 0x70 or, as in the spec, 0x00000000:0x00000070
+func parseDescriptorCode(s string) (uint64, error) {
+	if parts := strings.Split(s, ":"); len(parts) == 2 {
+		domain, err := strconv.ParseUint(parts[0], 0, 32)
+		if err != nil {
+			return 0, err
+		}
+		id, err := strconv.ParseUint(parts[1], 0, 32)
+		if err != nil {
+			return 0, err
+		}
+		return domain<<32 | id, nil
+	}
+	return strconv.ParseUint(s, 0, 64)
+}
+
+// newDefault returns the default of the field, a pointer is To a new copy of the default
+func (field compositeField) newDefault() reflect.Value {
+	if field.defaultValue.Kind() != reflect.Ptr {
+		return field.defaultValue
+	}
+	ptr := reflect.New(field.defaultValue.Type().Elem())
+	ptr.Elem().Set(field.defaultValue.Elem())
+	return ptr
+}
+
+// parseDefault converts the default of a tag To the field type, a pointer field
+// defaults To a pointer To the converted Value
+func parseDefault(t reflect.Type, s string) (reflect.Value, error) {
+	value := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Ptr:
+		elem, err := parseDefault(t.Elem(), s)
+		if err != nil {
+			return value, err
+		}
+		value.Set(reflect.New(t.Elem()))
+		value.Elem().Set(elem)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return value, err
+		}
+		value.SetBool(b)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 0, t.Bits())
+		if err != nil {
+			return value, err
+		}
+		value.SetUint(u)
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, t.Bits())
+		if err != nil {
+			return value, err
+		}
+		value.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return value, err
+		}
+		value.SetFloat(f)
+	case reflect.String:
+		value.SetString(s)
+	default:
+		return value, fmt.Errorf("amqpx: default is not supported for %s", t)
+	}
+	return value, nil
+}
+
+// Marshal returns the AMQP encoding of v. Structs are encoded as lists, described
+// when they carry a descriptor (see compositeOf). Optional fields that are nil or hold
+// their default are sent as null and trailing nulls are elided, a zero Value is sent.
+// Other values are encoded as by SerializeAny
+func Marshal(v interface{}) ([]byte, error) {
+	e := NewEncoder(nil)
+	defer e.Release()
+	if err := e.WriteAny(v); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), e.Bytes()...), nil
+}
+
+// Encode appends v as Marshal encodes it
+func (e *Encoder) Encode(v interface{}) error {
+	return e.marshalValue(reflect.ValueOf(v), "")
+}
+
+var (
+	symbolType        = reflect.TypeOf(Symbol(""))
+	describedTypeType = reflect.TypeOf(DescribedType{})
+	deliveryStateType = reflect.TypeOf((*DeliveryState)(nil)).Elem()
+	messageIDType     = reflect.TypeOf((*MessageID)(nil)).Elem()
+	timestampType     = reflect.TypeOf(Timestamp(0))
+)
+
+// marshalValue appends rv, typeName overrides the AMQP type of a primitive
+func (e *Encoder) marshalValue(rv reflect.Value, typeName string) error {
+	if !rv.IsValid() {
+		e.WriteNull()
+		return nil
+	}
+	if rv.Type().Implements(marshalerType) && !(rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		buf, err := rv.Interface().(Marshaler).MarshalAMQP()
+		if err != nil {
+			return err
+		}
+		e.WriteEncoded(buf)
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			e.WriteNull()
+			return nil
+		}
+		return e.marshalValue(rv.Elem(), typeName)
+	}
+	if typeName != "" {
+		return e.marshalAs(rv, typeName)
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		return e.marshalComposite(rv)
+	case reflect.Slice:
+		switch {
+		case rv.IsNil():
+			e.WriteNull()
+		case rv.Type().Elem().Kind() == reflect.Uint8:
+			e.WriteBinary(rv.Bytes())
+		case rv.Type().Elem() == symbolType:
+			e.WriteSymbolArray(rv.Convert(reflect.TypeOf([]Symbol(nil))).Interface().([]Symbol))
+		default:
+			e.BeginList()
+			for i := 0; i < rv.Len(); i++ {
+				if err := e.marshalValue(rv.Index(i), ""); err != nil {
+					return errors.New(err.Error() + fmt.Sprintf("\nMarshal() failed marshaling list item %d", i))
+				}
+			}
+			return e.EndList()
+		}
+	case reflect.Map:
+		if rv.IsNil() {
+			e.WriteNull()
+			return nil
+		}
+		keys := make([]interface{}, 0, rv.Len())
+		values := make([]interface{}, 0, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			keys = append(keys, iter.Key().Interface())
+			values = append(values, iter.Value().Interface())
+		}
+		buf, err := serializeMapPairs(keys, values)
+		if err != nil {
+			return err
+		}
+		e.WriteEncoded(buf)
+	case reflect.Bool:
+		e.WriteBoolean(rv.Bool())
+	case reflect.Uint8:
+		e.WriteUbyte(uint8(rv.Uint()))
+	case reflect.Uint16:
+		e.WriteUshort(uint16(rv.Uint()))
+	case reflect.Uint32:
+		e.WriteUint(uint32(rv.Uint()))
+	case reflect.Uint64:
+		e.WriteUlong(rv.Uint())
+	case reflect.Int8:
+		e.WriteSignedByte(int8(rv.Int()))
+	case reflect.Int16:
+		e.WriteShort(int16(rv.Int()))
+	case reflect.Int32:
+		e.WriteInt(int32(rv.Int()))
+	case reflect.Int64:
+		if rv.Type() == timestampType {
+			e.WriteTimestamp(Timestamp(rv.Int()))
+		} else {
+			e.WriteLong(rv.Int())
+		}
+	case reflect.Float32:
+		e.WriteFloat(float32(rv.Float()))
+	case reflect.Float64:
+		e.WriteDouble(rv.Float())
+	case reflect.String:
+		if rv.Type() == symbolType {
+			e.WriteSymbol(Symbol(rv.String()))
+		} else {
+			e.WriteString(rv.String())
+		}
+	case reflect.Array:
+		switch v := rv.Interface().(type) {
+		case UUID, Decimal32, Decimal64, Decimal128:
+			return e.WriteAny(v)
+		}
+		return fmt.Errorf("amqpx: can not marshal %s", rv.Type())
+	default:
+		return fmt.Errorf("amqpx: can not marshal %s", rv.Type())
+	}
+	return nil
+}
+
+// marshalAs appends a primitive as the AMQP type given by a type= tag option
+func (e *Encoder) marshalAs(rv reflect.Value, typeName string) error {
+	kind := rv.Kind()
+	unsigned := kind >= reflect.Uint && kind <= reflect.Uint64
+	signed := kind >= reflect.Int && kind <= reflect.Int64
+
+	switch {
+	case typeName == "boolean" && kind == reflect.Bool:
+		e.WriteBoolean(rv.Bool())
+	case typeName == "ubyte" && unsigned && rv.Uint() <= math.MaxUint8:
+		e.WriteUbyte(uint8(rv.Uint()))
+	case typeName == "ushort" && unsigned && rv.Uint() <= math.MaxUint16:
+		e.WriteUshort(uint16(rv.Uint()))
+	case typeName == "uint" && unsigned && rv.Uint() <= math.MaxUint32:
+		e.WriteUint(uint32(rv.Uint()))
+	case typeName == "ulong" && unsigned:
+		e.WriteUlong(rv.Uint())
+	case typeName == "long" && signed:
+		e.WriteLong(rv.Int())
+	case typeName == "char" && signed:
+		e.WriteChar(rune(rv.Int()))
+	case typeName == "timestamp" && signed:
+		e.WriteTimestamp(Timestamp(rv.Int()))
+	case typeName == "string" && kind == reflect.String:
+		e.WriteString(rv.String())
+	case typeName == "symbol" && kind == reflect.String:
+		e.WriteSymbol(Symbol(rv.String()))
+	case typeName == "binary" && kind == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		e.WriteBinary(rv.Bytes())
+	default:
+		return fmt.Errorf("amqpx: can not marshal %s with Value %v as %s", rv.Type(), rv.Interface(), typeName)
+	}
+	return nil
+}
+
+// marshalComposite appends a struct as a list, see compositeOf
+func (e *Encoder) marshalComposite(rv reflect.Value) error {
+	c, err := compositeOf(rv.Type())
+	if err != nil {
+		return err
+	}
+	if v, ok := rv.Interface().(validator); ok {
+		if err = v.validate(); err != nil {
+			return fmt.Errorf("amqpx: %s is invalid, %v", c.name, err)
+		}
+	}
+
+	if c.described {
+		if c.descriptorCode != 0 {
+			e.WriteDescriptor(c.descriptorCode)
+		} else {
+			e.WriteSymbolicDescriptor(c.descriptorName)
+		}
+	}
+	e.BeginList()
+	for _, field := range c.fields {
+		fv := rv.Field(field.index)
+		if field.mandatory {
+			switch fv.Kind() {
+			case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+				if fv.IsNil() {
+					return fmt.Errorf("amqpx: mandatory field %s of %s is nil", field.name, rv.Type())
+				}
+			case reflect.String:
+				// an empty symbol names nothing, e.g. no error condition
+				if fv.Type() == symbolType && fv.Len() == 0 {
+					return fmt.Errorf("amqpx: mandatory field %s of %s is empty", field.name, rv.Type())
+				}
+			}
+		} else if field.sentAsNull(fv) {
+			e.WriteNull()
+			continue
+		}
+
+		if err = e.marshalValue(fv, field.typeName); err != nil {
+			return errors.New(err.Error() + fmt.Sprintf("\nMarshal() failed marshaling %s field %s", rv.Type(), field.name))
+		}
+	}
+	return e.EndComposite()
+}
+
+// Unmarshal parses the AMQP encoded Value at the start of buffer into the Value pointed To by v.
+// Structs are read from a list or a described list, null and missing trailing
+// fields take the tag default or the zero Value. A mandatory field that is null is an error
+func Unmarshal(buffer []byte, v interface{}) (bytesUsed uint32, err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return 0, fmt.Errorf("amqpx: Unmarshal requires a non nil pointer, got %T", v)
+	}
+
+	bytesUsed, err = unmarshalValue(buffer, rv.Elem())
+	if err != nil {
+		if c, ok := compositeFor(rv.Type().Elem()); ok {
+			err = decodeErrorAt(err, 0, c.name)
+		}
+		return 0, err
+	}
+	return bytesUsed, nil
+}
+
+// compositeFor returns the layout of the struct t reads into through any pointers,
+// false when t does not read a composite
+func compositeFor(t reflect.Type) (*composite, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == describedTypeType || reflect.PtrTo(t).Implements(unmarshalerType) {
+		return nil, false
+	}
+	c, err := compositeOf(t)
+	return c, err == nil
+}
+
+// unmarshalValue parses buffer into rv
+func unmarshalValue(buffer []byte, rv reflect.Value) (bytesUsed uint32, err error) {
+	if len(buffer) < 1 {
+		return 0, truncatedError(1, 0)
+	}
+	if rv.CanAddr() && rv.Addr().Type().Implements(unmarshalerType) {
+		return rv.Addr().Interface().(Unmarshaler).UnmarshalAMQP(buffer)
+	}
+
+	// structs are read item by item so that an error names the failing field, and
+	// so that the registered parser of the descriptor does not take over the struct
+	if c, ok := compositeFor(rv.Type()); ok {
+		switch buffer[0] {
+		case nullCode:
+			// a nil pointer is no composite, a struct read from null would miss its mandatory fields
+			if rv.Kind() != reflect.Ptr && c.hasMandatory() {
+				return 0, invalidValueError("%s is null but has mandatory fields", c.name)
+			}
+			rv.Set(reflect.Zero(rv.Type()))
+			return 1, nil
+		case 0x00, list0Code, list8Code, list32Code:
+			if rv.Kind() != reflect.Ptr {
+				return unmarshalComposite(buffer, rv)
+			}
+			ptr := reflect.New(rv.Type().Elem())
+			if bytesUsed, err = unmarshalValue(buffer, ptr.Elem()); err != nil {
+				return 0, err
+			}
+			rv.Set(ptr)
+			return bytesUsed, nil
+		}
+	}
+
+	if rv.Type() == messageIDType {
+		id, bytesUsed, err := ParseMessageIDPrimitive(buffer)
+		if err != nil {
+			return 0, err
+		}
+		setMessageID(rv, id)
+		return bytesUsed, nil
+	}
+
+	// a string field only reads strings and symbols, anything else is the wrong constructor
+	if rv.Kind() == reflect.String || rv.Kind() == reflect.Ptr && rv.Type().Elem().Kind() == reflect.String {
+		switch buffer[0] {
+		case string8Code, string32Code, symbol8Code, symbol32Code:
+		default:
+			return 0, constructorError(buffer, string8Code, string32Code, symbol8Code, symbol32Code)
+		}
+	}
+
+	value, bytesUsed, err := ParseAny(buffer)
+	if err != nil {
+		return 0, err
+	}
+	if err = assignValue(rv, value); err != nil {
+		return 0, err
+	}
+	return bytesUsed, nil
+}
+
+// marshalDescribed returns the AMQP encoding of v described by code, see Marshal
+func marshalDescribed(code uint64, v interface{}) ([]byte, error) {
+	buf, err := Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return SerializeDescribedPrimitive(code, buf), nil
+}
+
+// unmarshalDescribed reads the Value described by name or code at the start of buffer
+// into the Value v points To, see Unmarshal
+func unmarshalDescribed(buffer []byte, name Symbol, code uint64, v interface{}) (bytesUsed uint32, err error) {
+	descriptor, inx, err := ParseDescriptor(buffer)
+	if err != nil {
+		return 0, err
+	}
+	if actual, ok := DescriptorCode(descriptor); !(ok && actual == code) && descriptor != name {
+		return 0, invalidValueError("descriptor %v does not match %s", descriptor, name)
+	}
+	advanceInx, err := Unmarshal(buffer[inx:], v)
+	if err != nil {
+		return 0, decodeErrorAt(err, inx, "")
+	}
+	return inx + advanceInx, nil
+}
+
+// unmarshalComposite reads a list or a described list into the fields of the struct rv, see compositeOf
+func unmarshalComposite(buffer []byte, rv reflect.Value) (bytesUsed uint32, err error) {
+	c, err := compositeOf(rv.Type())
+	if err != nil {
+		return 0, err
+	}
+
+	inx := uint32(0)
+	if buffer[0] == 0x00 {
+		descriptor, advanceInx, err := ParseDescriptor(buffer)
+		if err != nil {
+			return 0, err
+		}
+		if err = checkDescriptor(rv.Type(), descriptor); err != nil {
+			return 0, decodeErrorAt(err, 1, "")
+		}
+		inx += advanceInx
+	}
+
+	listLen, err := listEnd(buffer[inx:])
+	if err != nil {
+		return 0, decodeErrorAt(err, inx, "")
+	}
+	_, countItems, inxFirstItem, _, err := ParseListPrimitive(buffer[inx:])
+	if err != nil {
+		return 0, decodeErrorAt(err, inx, "")
+	}
+	end := inx + listLen
+	items := buffer[:end]
+
+	inx += inxFirstItem
+	for i, field := range c.fields {
+		fv := rv.Field(field.index)
+		if uint32(i) < countItems && !isNullPrimitive(items[inx:]) {
+			advanceInx, err := unmarshalValue(items[inx:], fv)
+			if err != nil {
+				return 0, decodeErrorAt(err, inx, field.name)
+			}
+			inx += advanceInx
+			continue
+		}
+
+		if field.mandatory {
+			return 0, decodeErrorAt(invalidValueError("mandatory field is null"), inx, field.name)
+		}
+		if uint32(i) < countItems {
+			inx++
+		}
+		if field.hasDefault {
+			fv.Set(field.newDefault())
+		} else {
+			fv.Set(reflect.Zero(fv.Type()))
+		}
+	}
+	if v, ok := rv.Interface().(validator); ok {
+		if err = v.validate(); err != nil {
+			return 0, invalidValueError("%v", err)
+		}
+	}
+	return uint32(end), nil
+}
+
+// assignValue stores a Value read by ParseAny in rv, converting it To the type of rv
+func assignValue(rv reflect.Value, value interface{}) error {
+	if value == nil {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+	if rv.CanAddr() && rv.Addr().Type().Implements(unmarshalerType) {
+		buf, err := SerializeAny(value)
+		if err != nil {
+			return err
+		}
+		_, err = rv.Addr().Interface().(Unmarshaler).UnmarshalAMQP(buf)
+		return err
+	}
+
+	if rv.Type() == messageIDType {
+		// a message-id is read as a plain ulong, uuid, binary or string
+		buf, err := SerializeAny(value)
+		if err != nil {
+			return err
+		}
+		id, _, err := ParseMessageIDPrimitive(buf)
+		if err != nil {
+			return err
+		}
+		setMessageID(rv, id)
+		return nil
+	}
+
+	val := reflect.ValueOf(value)
+	if val.Type().AssignableTo(rv.Type()) {
+		rv.Set(val)
+		return nil
+	}
+	if rv.Type() == deliveryStateType {
+		// a delivery state is a described outcome, not a number
+		return invalidValueError("expected a delivery State, got %T", value)
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		ptr := reflect.New(rv.Type().Elem())
+		if err := assignValue(ptr.Elem(), value); err != nil {
+			return err
+		}
+		rv.Set(ptr)
+		return nil
+	case reflect.Struct:
+		switch v := value.(type) {
+		case DescribedType:
+			if err := checkDescriptor(rv.Type(), v.Descriptor); err != nil {
+				return err
+			}
+			if list, ok := v.Value.([]interface{}); ok {
+				return assignComposite(rv, list)
+			}
+		case []interface{}:
+			return assignComposite(rv, v)
+		}
+	case reflect.Slice:
+		if list, ok := value.([]interface{}); ok {
+			slice := reflect.MakeSlice(rv.Type(), len(list), len(list))
+			for i, item := range list {
+				if err := assignValue(slice.Index(i), item); err != nil {
+					return decodeErrorAt(err, 0, strconv.Itoa(i))
+				}
+			}
+			rv.Set(slice)
+			return nil
+		}
+		if val.Kind() != reflect.Slice {
+			// a multiple field may hold a single Value instead of an array
+			slice := reflect.MakeSlice(rv.Type(), 1, 1)
+			if err := assignValue(slice.Index(0), value); err != nil {
+				return err
+			}
+			rv.Set(slice)
+			return nil
+		}
+	case reflect.Map:
+		if m, ok := value.(map[interface{}]interface{}); ok {
+			retMap := reflect.MakeMapWithSize(rv.Type(), len(m))
+			for k, v := range m {
+				key := reflect.New(rv.Type().Key()).Elem()
+				if err := assignValue(key, k); err != nil {
+					return decodeErrorAt(err, 0, fmt.Sprint(k))
+				}
+				item := reflect.New(rv.Type().Elem()).Elem()
+				if err := assignValue(item, v); err != nil {
+					return decodeErrorAt(err, 0, fmt.Sprint(k))
+				}
+				retMap.SetMapIndex(key, item)
+			}
+			rv.Set(retMap)
+			return nil
+		}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if val.Kind() >= reflect.Uint8 && val.Kind() <= reflect.Uint64 && !rv.OverflowUint(val.Uint()) {
+			rv.SetUint(val.Uint())
+			return nil
+		}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if val.Kind() >= reflect.Int8 && val.Kind() <= reflect.Int64 && !rv.OverflowInt(val.Int()) {
+			rv.SetInt(val.Int())
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if val.Kind() == reflect.Float32 || val.Kind() == reflect.Float64 {
+			rv.SetFloat(val.Float())
+			return nil
+		}
+	case reflect.Bool, reflect.String, reflect.Array:
+		if val.Kind() == rv.Kind() && val.Type().ConvertibleTo(rv.Type()) {
+			rv.Set(val.Convert(rv.Type()))
+			return nil
+		}
+	}
+
+	if val.Kind() == reflect.Slice && rv.Kind() == reflect.Slice && val.Type().ConvertibleTo(rv.Type()) {
+		rv.Set(val.Convert(rv.Type()))
+		return nil
+	}
+	return invalidValueError("can not unmarshal %T with Value %v into %s", value, value, rv.Type())
+}
+
+// setMessageID stores id in the MessageID rv, nil is the zero Value
+func setMessageID(rv reflect.Value, id MessageID) {
+	if id == nil {
+		rv.Set(reflect.Zero(rv.Type()))
+		return
+	}
+	rv.Set(reflect.ValueOf(id))
+}
+
+// checkDescriptor returns an error when the descriptor does not match the described struct type
+func checkDescriptor(t reflect.Type, descriptor interface{}) error {
+	c, err := compositeOf(t)
+	if err != nil || !c.described {
+		return err
+	}
+	if name, ok := descriptor.(Symbol); ok && name == c.descriptorName {
+		return nil
+	}
+	if code, ok := DescriptorCode(descriptor); ok && c.descriptorCode != 0 && code == c.descriptorCode {
+		return nil
+	}
+	return invalidValueError("descriptor %v does not match %s (%s)", descriptor, t, c.descriptorName)
+}
+
+// assignComposite stores the items of a list in the fields of a struct, see compositeOf
+func assignComposite(rv reflect.Value, items []interface{}) error {
+	c, err := compositeOf(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	for i, field := range c.fields {
+		fv := rv.Field(field.index)
+		var item interface{}
+		if i < len(items) {
+			item = items[i]
+		}
+
+		if item == nil {
+			if field.mandatory {
+				return decodeErrorAt(invalidValueError("mandatory field is null"), 0, field.name)
+			}
+			if field.hasDefault {
+				fv.Set(field.newDefault())
+			} else {
+				fv.Set(reflect.Zero(fv.Type()))
+			}
+			continue
+		}
+
+		if err := assignValue(fv, item); err != nil {
+			return decodeErrorAt(err, 0, field.name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package amqpx
+
+import (
+	"fmt"
+)
+
+// ParsePerformativeClose reads a close performative from buffer.
+func ParsePerformativeClose(buffer []byte) (closeParameters CloseParameters, bytesUsed uint32, err error) {
+	bytesUsed, err = Unmarshal(buffer, &closeParameters)
+	return closeParameters, bytesUsed, err
+}
+
+func (closeParameters CloseParameters) String() string {
+	type plain CloseParameters
+	return fmt.Sprintf("close%+v", plain(closeParameters))
+}
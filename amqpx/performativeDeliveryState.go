@@ -0,0 +1,52 @@
+package amqpx
+
+import (
+	"reflect"
+)
+
+// DeliveryState is the State of a delivery carried by the transfer and disposition
+// performatives: Received, or one of the outcomes Accepted, Rejected, Released and Modified
+type DeliveryState interface {
+	// Descriptor returns the descriptor code of the delivery State
+	Descriptor() uint64
+	deliveryState()
+}
+
+// Outcome is a terminal delivery State: Accepted, Rejected, Released or Modified.
+// A Source lists the outcomes it supports by their descriptor names
+type Outcome interface {
+	DeliveryState
+	outcome()
+}
+
+// Outcome descriptor names as listed in the outcomes of a Source
+const (
+	OutcomeAccepted Symbol = "amqp:accepted:list"
+	OutcomeRejected Symbol = "amqp:rejected:list"
+	OutcomeReleased Symbol = "amqp:released:list"
+	OutcomeModified Symbol = "amqp:modified:list"
+)
+
+func (Received) deliveryState() {}
+func (Accepted) deliveryState() {}
+func (Rejected) deliveryState() {}
+func (Released) deliveryState() {}
+func (Modified) deliveryState() {}
+
+func (Accepted) outcome() {}
+func (Rejected) outcome() {}
+func (Released) outcome() {}
+func (Modified) outcome() {}
+
+// registerDeliveryState registers the parser of the type of state
+func registerDeliveryState(name Symbol, state DeliveryState) {
+	t := reflect.TypeOf(state)
+	RegisterDescribedType(state.Descriptor(), name, func(buffer []byte) (interface{}, uint32, error) {
+		ptr := reflect.New(t)
+		bytesUsed, err := Unmarshal(buffer, ptr.Interface())
+		if err != nil {
+			return nil, 0, err
+		}
+		return ptr.Elem().Interface(), bytesUsed, nil
+	})
+}
@@ -0,0 +1,16 @@
+package amqpx
+
+import (
+	"fmt"
+)
+
+// ParsePerformativeDetach reads a detach performative from buffer.
+func ParsePerformativeDetach(buffer []byte) (detach DetachParameters, bytesUsed uint32, err error) {
+	bytesUsed, err = Unmarshal(buffer, &detach)
+	return detach, bytesUsed, err
+}
+
+func (detach DetachParameters) String() string {
+	type plain DetachParameters
+	return fmt.Sprintf("detach%+v", plain(detach))
+}
@@ -0,0 +1,16 @@
+package amqpx
+
+import (
+	"fmt"
+)
+
+// ParsePerformativeEnd reads a end performative from buffer.
+func ParsePerformativeEnd(buffer []byte) (end EndParameters, bytesUsed uint32, err error) {
+	bytesUsed, err = Unmarshal(buffer, &end)
+	return end, bytesUsed, err
+}
+
+func (end EndParameters) String() string {
+	type plain EndParameters
+	return fmt.Sprintf("end%+v", plain(end))
+}
@@ -0,0 +1,64 @@
+package amqpx
+
+import (
+	"fmt"
+)
+
+// descriptorError is the descriptor code of the error composite
+const descriptorError uint64 = 0x1d
+
+// Standard error conditions, spec section 2.8.15 - 2.8.18
+const (
+	// amqp-error
+	ErrorInternalError         Symbol = "amqp:internal-error"
+	ErrorNotFound              Symbol = "amqp:not-found"
+	ErrorUnauthorizedAccess    Symbol = "amqp:unauthorized-access"
+	ErrorDecodeError           Symbol = "amqp:decode-error"
+	ErrorResourceLimitExceeded Symbol = "amqp:resource-limit-exceeded"
+	ErrorNotAllowed            Symbol = "amqp:not-allowed"
+	ErrorInvalidField          Symbol = "amqp:invalid-field"
+	ErrorNotImplemented        Symbol = "amqp:not-implemented"
+	ErrorResourceLocked        Symbol = "amqp:resource-locked"
+	ErrorPreconditionFailed    Symbol = "amqp:precondition-failed"
+	ErrorResourceDeleted       Symbol = "amqp:resource-deleted"
+	ErrorIllegalState          Symbol = "amqp:illegal-state"
+	ErrorFrameSizeTooSmall     Symbol = "amqp:frame-size-too-small"
+
+	// connection-error
+	ErrorConnectionForced       Symbol = "amqp:connection:forced"
+	ErrorConnectionFramingError Symbol = "amqp:connection:framing-error"
+	ErrorConnectionRedirect     Symbol = "amqp:connection:redirect"
+
+	// session-error
+	ErrorSessionWindowViolation  Symbol = "amqp:session:window-violation"
+	ErrorSessionErrantLink       Symbol = "amqp:session:errant-link"
+	ErrorSessionHandleInUse      Symbol = "amqp:session:handle-in-use"
+	ErrorSessionUnattachedHandle Symbol = "amqp:session:unattached-handle"
+
+	// link-error
+	ErrorLinkDetachForced          Symbol = "amqp:link:detach-forced"
+	ErrorLinkTransferLimitExceeded Symbol = "amqp:link:transfer-limit-exceeded"
+	ErrorLinkMessageSizeExceeded   Symbol = "amqp:link:message-size-exceeded"
+	ErrorLinkRedirect              Symbol = "amqp:link:redirect"
+	ErrorLinkStolen                Symbol = "amqp:link:stolen"
+)
+
+// NewError returns an Error with condition and a formatted description
+func NewError(condition Symbol, format string, args ...interface{}) *Error {
+	description := fmt.Sprintf(format, args...)
+	return &Error{Condition: condition, Description: &description}
+}
+
+// Error lets an Error received from the peer be returned as a go error
+func (e Error) Error() string {
+	if e.Description == nil || *e.Description == "" {
+		return string(e.Condition)
+	}
+	return string(e.Condition) + ": " + *e.Description
+}
+
+// ParseError reads an error composite from buffer
+func ParseError(buffer []byte) (amqpError Error, bytesUsed uint32, err error) {
+	bytesUsed, err = Unmarshal(buffer, &amqpError)
+	return amqpError, bytesUsed, err
+}
@@ -2,6 +2,7 @@ package amqpx
 
 import (
 	"errors"
+	"fmt"
 
 	log "github.com/mgutz/logxi/v1"
 )
@@ -9,130 +10,30 @@ import (
 // TransferNumber is a sequenceno which is a uint
 type TransferNumber SequenceNo
 
-// FlowParameters .. gathered in the Flow performative
-type FlowParameters struct {
-	NextIncoming   TransferNumber `json:"nextIncoming"`
-	IncomingWindow uint32         `json:"nextIncoming"`
-	NextOutgoing   TransferNumber `json:"nextOutgoing"`
-	OutgoingWindow uint32         `json:"outgoingWindow"`
-	Handle         Handle         `json:"Handle"`
-	DeliveryCount  SequenceNo     `json:"DeliveryCount"`
-	LinkCredit     uint32         `json:"linkCredit"`
-	Available      uint32         `json:"available"`
-	Drain          BooleanChoice  `json:"drain"`
-	// echo           BooleanChoice
-	//properties
+// IsLinkFlow tells whether the flow carries the state of a link as well as of the session
+func (flowParameters FlowParameters) IsLinkFlow() bool {
+	return flowParameters.Handle != nil
 }
 
-// ParsePerformativeFlow reads a flow performative from buffer.
-func ParsePerformativeFlow(buffer []byte) (flowParameters FlowParameters, inx uint32, err error) {
-	err = nil
-	inx = uint32(0)
-	advanceInx := uint32(0)
-	// header
-
-	_, countItems, _, advanceInx, err := ParseListPrimitive(buffer[inx:])
-	if err != nil {
-		return flowParameters, inx, errors.New(err.Error() + "\nReadFlowPerformative() failed compound list")
-	}
-	inx += advanceInx
-	advanceInx = 0
-
-	transferNumber, advanceInx, err := ParseUintPrimitive(buffer[inx:])
-	if err != nil {
-		return flowParameters, inx, errors.New(err.Error() + "\nReadFlowPerformative() failed reading NextIncoming from list")
-	}
-	flowParameters.NextIncoming = TransferNumber(transferNumber)
-	log.Debug("flow.NextIncoming:", flowParameters.NextIncoming)
-	inx += advanceInx
-	advanceInx = 0
-	countItems--
-
-	flowParameters.IncomingWindow, advanceInx, err = ParseUintPrimitive(buffer[inx:])
-	if err != nil {
-		return flowParameters, inx, errors.New(err.Error() + "\nReadFlowPerformative() failed reading IncomingWindow from list")
-	}
-	log.Debug("flow.IncomingWindow:", flowParameters.IncomingWindow)
-	inx += advanceInx
-	advanceInx = 0
-	countItems--
-
-	transferNumber, advanceInx, err = ParseUintPrimitive(buffer[inx:])
-	if err != nil {
-		return flowParameters, inx, errors.New(err.Error() + "\nReadFlowPerformative() failed reading NextOutgoing from list")
+// validate checks that only a link flow carries link state
+func (flowParameters FlowParameters) validate() error {
+	if !flowParameters.IsLinkFlow() && (flowParameters.DeliveryCount != nil || flowParameters.LinkCredit != nil || flowParameters.Available != nil) {
+		return errors.New("link state without a handle")
 	}
-	flowParameters.NextOutgoing = TransferNumber(transferNumber)
-	log.Debug("flow.NextOutgoing:", flowParameters.NextOutgoing)
-	inx += advanceInx
-	advanceInx = 0
-	countItems--
-
-	flowParameters.OutgoingWindow, advanceInx, err = ParseUintPrimitive(buffer[inx:])
-	if err != nil {
-		return flowParameters, inx, errors.New(err.Error() + "\nReadFlowPerformative() failed reading OutgoingWindow from list")
-	}
-	log.Debug("flow.OutgoingWindow:", flowParameters.OutgoingWindow)
-	inx += advanceInx
-	advanceInx = 0
-	countItems--
-
-	handle, advanceInx, err := ParseUintPrimitive(buffer[inx:])
-	if err != nil {
-		return flowParameters, inx, errors.New(err.Error() + "\nReadFlowPerformative() failed reading Handle from list")
-	}
-	flowParameters.Handle = Handle(handle)
-	log.Debug("flow.Handle:", flowParameters.Handle)
-	inx += advanceInx
-	advanceInx = 0
-	countItems--
-
-	sequenceNo, advanceInx, err := ParseUintPrimitive(buffer[inx:])
-	if err != nil {
-		return flowParameters, inx, errors.New(err.Error() + "\nReadFlowPerformative() failed reading DeliveryCount from list")
-	}
-	flowParameters.DeliveryCount = SequenceNo(sequenceNo)
-	log.Debug("flow.DeliveryCount:", flowParameters.DeliveryCount)
-	inx += advanceInx
-	advanceInx = 0
-	countItems--
-
-	flowParameters.LinkCredit, advanceInx, err = ParseUintPrimitive(buffer[inx:])
-	if err != nil {
-		return flowParameters, inx, errors.New(err.Error() + "\nReadFlowPerformative() failed reading LinkCredit from list")
-	}
-	log.Debug("flow.LinkCredit:", flowParameters.LinkCredit)
-	inx += advanceInx
-	advanceInx = 0
-	countItems--
+	return nil
+}
 
-	flowParameters.Available, advanceInx, err = ParseUintPrimitive(buffer[inx:])
-	if err != nil {
-		return flowParameters, inx, errors.New(err.Error() + "\nReadFlowPerformative() failed reading Available from list")
-	}
-	log.Debug("flow.Available:", flowParameters.Available)
-	inx += advanceInx
-	advanceInx = 0
-	countItems--
+func (flowParameters FlowParameters) String() string {
+	type plain FlowParameters
+	return fmt.Sprintf("flow%+v", plain(flowParameters))
+}
 
-	choiceBoolean, advanceInx, err := ParseBooleanPrimitive(buffer[inx:])
+// ParsePerformativeFlow reads a flow performative from buffer.
+func ParsePerformativeFlow(buffer []byte) (flowParameters FlowParameters, bytesUsed uint32, err error) {
+	bytesUsed, err = Unmarshal(buffer, &flowParameters)
 	if err != nil {
-		return flowParameters, inx, errors.New(err.Error() + "\nReadFlowPerformative() failed reading Drain from list")
+		return flowParameters, bytesUsed, err
 	}
-	flowParameters.Drain = BooleanChoice(choiceBoolean)
-	log.Debug("flow.Drain:", flowParameters.Drain)
-	inx += advanceInx
-	advanceInx = 0
-	countItems--
-
-	// choiceBoolean, advanceInx, err = ParseBooleanPrimitive(buffer[inx:])
-	// if err != nil {
-	// 	return flowParameters, errors.New(err.Error() + "\nReadFlowPerformative() failed reading echo from list")
-	// }
-	// flowParameters.echo = BooleanChoice(choiceBoolean)
-	// log.Debug("flow.echo:", flowParameters.echo)
-	// inx += advanceInx
-	// advanceInx = 0
-	// countItems--
-
-	return flowParameters, inx, nil
+	log.Debug("flow:", flowParameters)
+	return flowParameters, bytesUsed, nil
 }
@@ -0,0 +1,110 @@
+package amqpx
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Descriptors of the SASL frame bodies, sent in frames of type FrameTypeSASL.
+// Spec section 5.3.3 Security Frame Bodies
+const (
+	PerfSaslMechanisms byte = 0x40
+	PerfSaslInit       byte = 0x41
+	PerfSaslChallenge  byte = 0x42
+	PerfSaslResponse   byte = 0x43
+	PerfSaslOutcome    byte = 0x44
+)
+
+// SaslCode is the outcome of the SASL exchange
+//
+//	<type name="sasl-code" class="restricted" source="ubyte">
+type SaslCode uint8
+
+// Spec choices of sasl-code
+const (
+	// SaslCodeOk connection authentication succeeded
+	SaslCodeOk SaslCode = 0
+	// SaslCodeAuth connection authentication failed due To an unspecified problem with the supplied credentials
+	SaslCodeAuth SaslCode = 1
+	// SaslCodeSys connection authentication failed due To a system error
+	SaslCodeSys SaslCode = 2
+	// SaslCodeSysPerm connection authentication failed due To a system error that is unlikely To be corrected without intervention
+	SaslCodeSysPerm SaslCode = 3
+	// SaslCodeSysTemp connection authentication failed due To a transient system error
+	SaslCodeSysTemp SaslCode = 4
+)
+
+func (mechanisms SaslMechanisms) String() string {
+	type plain SaslMechanisms
+	return fmt.Sprintf("sasl-mechanisms%+v", plain(mechanisms))
+}
+
+// String leaves out the initial response, it may hold a password
+func (saslInit SaslInit) String() string {
+	hostname := ""
+	if saslInit.Hostname != nil {
+		hostname = *saslInit.Hostname
+	}
+	return fmt.Sprintf("sasl-init{Mechanism:%s Hostname:%s}", saslInit.Mechanism, hostname)
+}
+
+func (challenge SaslChallenge) String() string {
+	return fmt.Sprintf("sasl-challenge{%d bytes}", len(challenge.Challenge))
+}
+
+// String leaves out the response, it may hold a password
+func (response SaslResponse) String() string {
+	return fmt.Sprintf("sasl-response{%d bytes}", len(response.Response))
+}
+
+func (outcome SaslOutcome) String() string {
+	type plain SaslOutcome
+	return fmt.Sprintf("sasl-outcome%+v", plain(outcome))
+}
+
+// newSaslPerformative returns an empty SASL frame body for a descriptor code, nil if the code is not one
+func newSaslPerformative(code uint64) Performative {
+	switch code {
+	case uint64(PerfSaslMechanisms):
+		return &SaslMechanisms{}
+	case uint64(PerfSaslInit):
+		return &SaslInit{}
+	case uint64(PerfSaslChallenge):
+		return &SaslChallenge{}
+	case uint64(PerfSaslResponse):
+		return &SaslResponse{}
+	case uint64(PerfSaslOutcome):
+		return &SaslOutcome{}
+	}
+	return nil
+}
+
+// registerSaslPerformative registers the parser of a SASL frame body, found as a described Value
+func registerSaslPerformative(name Symbol, performative Performative) {
+	t := reflect.TypeOf(performative).Elem()
+	RegisterDescribedType(performative.Descriptor(), name, func(buffer []byte) (interface{}, uint32, error) {
+		ptr := reflect.New(t)
+		bytesUsed, err := ptr.Interface().(Performative).Unmarshal(buffer)
+		if err != nil {
+			return nil, 0, err
+		}
+		return ptr.Elem().Interface(), bytesUsed, nil
+	})
+}
+
+// ParseSaslFrameBody reads the frame body of a SASL frame
+func ParseSaslFrameBody(buffer []byte) (performative Performative, err error) {
+	descriptor, _, err := ParseDescriptor(buffer)
+	if err != nil {
+		return nil, decodeErrorAt(err, 0, "sasl-frame-body")
+	}
+	code, _ := DescriptorCode(descriptor)
+	performative = newSaslPerformative(code)
+	if performative == nil {
+		return nil, decodeErrorAt(invalidValueError("descriptor %v is not a SASL frame body", descriptor), 0, "sasl-frame-body")
+	}
+	if _, err = performative.Unmarshal(buffer); err != nil {
+		return nil, err
+	}
+	return performative, nil
+}
@@ -1,8 +1,12 @@
 package amqpx
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestReadProtocolHeader(t *testing.T) {
@@ -219,3 +223,838 @@ func TestReadTransferFraming(t *testing.T) {
 		}
 	}
 }
+
+func TestOpenPropertiesRoundTrip(t *testing.T) {
+	connParameters := ConnectionParameters{
+		ContainerId:         "amqpx-container",
+		Hostname:            ptrTo("testhost"),
+		MaxFrameSize:        ptrTo(DefaultMaxFrameSize),
+		ChannelMax:          ptrTo(uint16(0x7fff)),
+		IdleTimeoutMs:       ptrTo(Milliseconds(30000)),
+		DesiredCapabilities: []Symbol{CapabilityAnonymousRelay},
+		Properties:          Fields{"product": "amqpx", "version": "0.1.0"},
+	}
+	buf, err := connParameters.Marshal()
+	if err != nil {
+		t.Fatalf("%s\nConnectionParameters.Marshal was incorrect, expected no errors", err.Error())
+	}
+
+	parsed, bytesUsed, err := ParsePerformativeOpen(buf)
+	if err != nil {
+		t.Fatalf("%s\nReadOpenPerformative was incorrect, expected no errors", err.Error())
+	}
+	if bytesUsed != uint32(len(buf)) {
+		t.Errorf("ReadOpenPerformative was incorrect bytesUsed, \n\texpected: \"%d\" \n\tgot:\"%d\"", len(buf), bytesUsed)
+	}
+	if !reflect.DeepEqual(connParameters, parsed) {
+		t.Errorf("ReadOpenPerformative was incorrect, \n\texpected: \"%#v\" \n\tgot:\"%#v\"", connParameters, parsed)
+	}
+}
+
+func TestOpenLocalesAndFrameSize(t *testing.T) {
+	want := &ConnectionParameters{
+		ContainerId:         "amqpx-container",
+		MaxFrameSize:        ptrTo(uint32(65536)),
+		ChannelMax:          ptrTo(uint16(255)),
+		OutgoingLocales:     []Symbol{DefaultLocale, "de-DE"},
+		IncomingLocales:     []Symbol{DefaultLocale},
+		OfferedCapabilities: []Symbol{CapabilitySoleConnection},
+		Properties:          Fields{PropertyProduct: "amqpx", PropertyVersion: "0.1.0", PropertyPlatform: "go"},
+	}
+	buf, err := want.Marshal()
+	if err != nil {
+		t.Fatalf("%s\nMarshal was incorrect, expected no errors", err.Error())
+	}
+	got, _, err := ParseFrameBody(buf)
+	if err != nil {
+		t.Fatalf("%s\nParseFrameBody was incorrect, expected no errors", err.Error())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Open round trip was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", want, got)
+	}
+
+	// an open with only the container-id takes the defaults
+	open, _, err := ParsePerformativeOpen(SerializeList(SerializeStringPrimitive("peer")))
+	if err != nil || open.ChannelLimit() != DefaultChannelMax || open.FrameSizeLimit() != DefaultMaxFrameSize {
+		t.Errorf("ReadOpenPerformative defaults were incorrect, \n\texpected: \"%d %d\" \n\tgot:\"%d %d\" %v", DefaultChannelMax, DefaultMaxFrameSize, open.ChannelLimit(), open.FrameSizeLimit(), err)
+	}
+	if size := NegotiatedFrameSize(*want, open); size != 65536 {
+		t.Errorf("NegotiatedFrameSize was incorrect, \n\texpected: \"%d\" \n\tgot:\"%d\"", 65536, size)
+	}
+
+	// a channel-max that is not set is null, the peer takes the default
+	buf, err = (&ConnectionParameters{ContainerId: "x"}).Marshal()
+	if err != nil {
+		t.Fatalf("%s\nMarshal was incorrect, expected no errors", err.Error())
+	}
+	got, _, err = ParseFrameBody(buf)
+	if err != nil {
+		t.Fatalf("%s\nParseFrameBody was incorrect, expected no errors", err.Error())
+	}
+	if channelMax := got.(*ConnectionParameters).ChannelLimit(); channelMax != DefaultChannelMax {
+		t.Errorf("Open round trip was incorrect channel-max, \n\texpected: \"%d\" \n\tgot:\"%d\"", DefaultChannelMax, channelMax)
+	}
+	if limit := (ConnectionParameters{}).ChannelLimit(); limit != DefaultChannelMax {
+		t.Errorf("ChannelLimit was incorrect, \n\texpected: \"%d\" \n\tgot:\"%d\"", DefaultChannelMax, limit)
+	}
+
+	// a max-frame-size under 512 is not allowed
+	small := SerializeList(SerializeStringPrimitive("peer"), SerializeNullPrimitive(), SerializeUintPrimitive(256))
+	if _, _, err = ParsePerformativeOpen(small); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("ReadOpenPerformative was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", ErrInvalidValue, err)
+	}
+	if _, err = (ConnectionParameters{ContainerId: "peer", MaxFrameSize: ptrTo(uint32(256))}).Marshal(); err == nil {
+		t.Errorf("ConnectionParameters.Marshal was incorrect, expected an error for max-frame-size 256")
+	}
+}
+
+func TestReadAttachUnsettledAndProperties(t *testing.T) {
+	unsettled, _ := SerializeMapPrimitive(Map{BinaryKey([]byte{0x01}): nil})
+	properties, _ := SerializeFieldsPrimitive(Fields{"priority": int32(3)})
+	null := SerializeNullPrimitive()
+	terminus := SerializeList(null, SerializeUintPrimitive(0), null, SerializeUintPrimitive(0), SerializeBooleanPrimitive(false))
+	attachBuf := SerializeList(
+		SerializeStringPrimitive("link"),
+		SerializeUintPrimitive(1),
+		SerializeBooleanPrimitive(true),
+		SerializeUbytePrimitive(0),
+		SerializeUbytePrimitive(0),
+		append([]byte{0x00, 0x53, 0x28}, terminus...),
+		append([]byte{0x00, 0x53, 0x29}, terminus...),
+		unsettled,
+		null,
+		SerializeUintPrimitive(0),
+		null,
+		null,
+		null,
+		properties)
+
+	attach, _, err := ParsePerformativeAttach(attachBuf)
+	if err != nil {
+		t.Fatalf("%s\nReadAttachPerformative was incorrect, expected no errors", err.Error())
+	}
+	if _, ok := attach.Unsettled[BinaryKey([]byte{0x01})]; !ok || len(attach.Unsettled) != 1 {
+		t.Errorf("ReadAttachPerformative was incorrect Unsettled, got:%#v", attach.Unsettled)
+	}
+	if attach.Properties["priority"] != int32(3) {
+		t.Errorf("ReadAttachPerformative was incorrect Properties, got:%#v", attach.Properties)
+	}
+}
+
+func TestBeginCapabilitiesRoundTrip(t *testing.T) {
+	session := SessionParameters{
+		NextOutgoing:        1,
+		IncomingWindow:      0x12345678,
+		OutgoingWindow:      0x87654321,
+		HandleMax:           ptrTo(Handle(4294967295)),
+		OfferedCapabilities: []Symbol{CapabilityAnonymousRelay},
+	}
+	buf, err := session.Marshal()
+	if err != nil {
+		t.Fatalf("%s\nSessionParameters.Marshal was incorrect, expected no errors", err.Error())
+	}
+
+	parsed, bytesUsed, err := ParsePerformativeBegin(buf)
+	if err != nil {
+		t.Fatalf("%s\nReadBeginPerformative was incorrect, expected no errors", err.Error())
+	}
+	if bytesUsed != uint32(len(buf)) {
+		t.Errorf("ReadBeginPerformative was incorrect bytesUsed, \n\texpected: \"%d\" \n\tgot:\"%d\"", len(buf), bytesUsed)
+	}
+	if !reflect.DeepEqual(session, parsed) {
+		t.Errorf("ReadBeginPerformative was incorrect, \n\texpected: \"%#v\" \n\tgot:\"%#v\"", session, parsed)
+	}
+}
+
+func TestBeginHandleMaxRoundTrip(t *testing.T) {
+	// a handle-max that is not set is null and reads as the default
+	for _, handleMax := range []*Handle{nil, ptrTo(Handle(0)), ptrTo(Handle(7))} {
+		session := SessionParameters{
+			NextOutgoing:   1,
+			IncomingWindow: 100,
+			OutgoingWindow: 100,
+			HandleMax:      handleMax,
+		}
+		buf, err := session.Marshal()
+		if err != nil {
+			t.Fatalf("%s\nSessionParameters.Marshal was incorrect, expected no errors", err.Error())
+		}
+		if session.HandleMax == nil {
+			session.HandleMax = ptrTo(Handle(4294967295))
+		}
+
+		parsed, bytesUsed, err := ParsePerformativeBegin(buf)
+		if err != nil {
+			t.Fatalf("%s\nReadBeginPerformative was incorrect, expected no errors", err.Error())
+		}
+		if bytesUsed != uint32(len(buf)) {
+			t.Errorf("ReadBeginPerformative was incorrect bytesUsed, \n\texpected: \"%d\" \n\tgot:\"%d\"", len(buf), bytesUsed)
+		}
+		if !reflect.DeepEqual(session, parsed) {
+			t.Errorf("ReadBeginPerformative was incorrect, \n\texpected: \"%#v\" \n\tgot:\"%#v\"", session, parsed)
+		}
+	}
+}
+
+func TestReadMessagePropertiesUuidMessageId(t *testing.T) {
+	uuid, _ := ParseUUID("f81d4fae-7dec-11d0-a765-00a0c91e6bf6")
+	null := SerializeNullPrimitive()
+	buf := SerializeList(SerializeUuidPrimitive(uuid), null, null, null, null, SerializeUuidPrimitive(uuid),
+		null, null, null, null, null, null, null)
+
+	properties, bytesUsed, err := ParseMessageProperties(buf)
+	if err != nil {
+		t.Fatalf("%s\nReadMessageProperties was incorrect, expected no errors", err.Error())
+	}
+	if bytesUsed != uint32(len(buf)) {
+		t.Errorf("ReadMessageProperties was incorrect bytesUsed, \n\texpected: \"%d\" \n\tgot:\"%d\"", len(buf), bytesUsed)
+	}
+	if properties.MessageId != MessageIDUUID(uuid) || properties.CorrelationId != MessageIDUUID(uuid) {
+		t.Errorf("ReadMessageProperties was incorrect ids, got:%v %v", properties.MessageId, properties.CorrelationId)
+	}
+}
+
+func TestParseTruncatedPerformatives(t *testing.T) {
+	null := SerializeNullPrimitive()
+	terminus := SerializeList(SerializeStringPrimitive("queue"), SerializeUintPrimitive(0), null, SerializeUintPrimitive(0), SerializeBooleanPrimitive(false))
+	attachBuf := SerializeList(SerializeStringPrimitive("link"), SerializeUintPrimitive(1), SerializeBooleanPrimitive(true),
+		SerializeUbytePrimitive(0), SerializeUbytePrimitive(0), append([]byte{0x00, 0x53, 0x28}, terminus...),
+		null, null, null, null, null, null, null, null)
+	headerBuf := []byte{0x00, 0x53, 0x70, 0xc0, 0x08, 0x05, 0x42, 0x50, 0x04, 0x40, 0x42, 0x52, 0x00}
+	dispositionBuf := []byte{0x00, 0x53, 0x15, 0xc0, 0x0b, 0x05, 0x41, 0x52, 0x00, 0x52, 0x09, 0x41, 0x00, 0x53, 0x24, 0x45}
+	propertiesBuf := SerializeList(SerializeBinaryPrimitive([]byte("id")), null, SerializeStringPrimitive("to"),
+		null, null, null, null, null, null, null, null, null, null)
+
+	parsers := []struct {
+		name   string
+		buffer []byte
+		parse  func([]byte) error
+	}{
+		{"attach", attachBuf, func(b []byte) error { _, _, err := ParsePerformativeAttach(b); return err }},
+		{"header", headerBuf, func(b []byte) error { _, _, err := ParseMessageHeader(b); return err }},
+		{"disposition", dispositionBuf, func(b []byte) error { _, _, err := ParsePerformativeDisposition(b); return err }},
+		{"properties", propertiesBuf, func(b []byte) error { _, _, err := ParseMessageProperties(b); return err }},
+		{"framing", []byte{0x00, 0x00, 0x00, 0x0a, 0x02, 0x00, 0x00, 0x00}, func(b []byte) error { _, _, _, err := ParseFraming(b); return err }},
+	}
+	for _, p := range parsers {
+		if err := p.parse(p.buffer); err != nil {
+			t.Fatalf("%s\n%s was incorrect, expected no errors", err.Error(), p.name)
+		}
+		for i := 0; i < len(p.buffer); i++ {
+			err := p.parse(p.buffer[:i])
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) || !errors.Is(err, ErrTruncated) {
+				t.Errorf("%s of %d bytes was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", p.name, i, ErrTruncated, err)
+			}
+		}
+	}
+}
+
+func TestParseAttachDecodeErrorField(t *testing.T) {
+	null := SerializeNullPrimitive()
+	terminus := SerializeList(SerializeUintPrimitive(7), SerializeUintPrimitive(0), null, SerializeUintPrimitive(0), SerializeBooleanPrimitive(false))
+	source := append([]byte{0x00, 0x53, 0x28}, terminus...)
+	attachBuf := SerializeList(SerializeStringPrimitive("link"), SerializeUintPrimitive(1), SerializeBooleanPrimitive(true),
+		SerializeUbytePrimitive(0), SerializeUbytePrimitive(0), source)
+
+	_, _, err := ParsePerformativeAttach(attachBuf)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("ReadAttachPerformative was incorrect, \n\texpected: \"%s\" \n\tgot:\"%v\"", "*DecodeError", err)
+	}
+	offset := uint32(bytes.Index(attachBuf, source) + 3 + 3)
+	if decodeErr.Field != "attach.source.address" || decodeErr.Offset != offset {
+		t.Errorf("ReadAttachPerformative was incorrect field, \n\texpected: \"%s at %d\" \n\tgot:\"%s at %d\"", "attach.source.address", offset, decodeErr.Field, decodeErr.Offset)
+	}
+	if !errors.Is(err, ErrUnexpectedConstructor) || decodeErr.Actual != uintSmallCode || attachBuf[decodeErr.Offset] != uintSmallCode {
+		t.Errorf("ReadAttachPerformative was incorrect constructor, \n\texpected: \"0x%02x\" \n\tgot:\"%v\"", uintSmallCode, err)
+	}
+}
+
+func TestUnmarshalDecodeErrorField(t *testing.T) {
+	// priority is a string instead of an ubyte
+	buf := []byte{0x00, 0x53, 0x70, 0xc0, 0x05, 0x02, 0x42, 0xa1, 0x01, 0x78}
+	_, _, err := ParseMessageHeader(buf)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("ReadMessageHeader was incorrect, \n\texpected: \"%s\" \n\tgot:\"%v\"", "*DecodeError", err)
+	}
+	if decodeErr.Field != "header.priority" || decodeErr.Offset != 7 {
+		t.Errorf("ReadMessageHeader was incorrect field, \n\texpected: \"%s at %d\" \n\tgot:\"%s at %d\"", "header.priority", 7, decodeErr.Field, decodeErr.Offset)
+	}
+
+	// role of a disposition is mandatory
+	buf = []byte{0x00, 0x53, 0x15, 0xc0, 0x03, 0x02, 0x40, 0x43}
+	_, _, err = ParsePerformativeDisposition(buf)
+	if !errors.As(err, &decodeErr) || !errors.Is(err, ErrInvalidValue) || decodeErr.Field != "disposition.role" || decodeErr.Offset != 6 {
+		t.Errorf("ReadDispositionPerformative was incorrect, \n\texpected: \"%s at %d\" \n\tgot:\"%v\"", "disposition.role", 6, err)
+	}
+}
+
+func TestParsePerformativeMandatoryFields(t *testing.T) {
+	null, one := SerializeNullPrimitive(), SerializeUintPrimitive(1)
+	parsers := []struct {
+		field  string
+		buffer []byte
+		parse  func([]byte) error
+	}{
+		// the string after the empty list is not its container-id
+		{"open.container-id", []byte{0x45, 0xa1, 0x01, 0x41}, func(b []byte) error { _, _, err := ParsePerformativeOpen(b); return err }},
+		{"open.container-id", SerializeList(null), func(b []byte) error { _, _, err := ParsePerformativeOpen(b); return err }},
+		{"begin.next-outgoing-id", []byte{0x45, 0x43, 0x43, 0x43}, func(b []byte) error { _, _, err := ParsePerformativeBegin(b); return err }},
+		{"begin.incoming-window", SerializeList(null, one), func(b []byte) error { _, _, err := ParsePerformativeBegin(b); return err }},
+		{"begin.outgoing-window", SerializeList(null, one, one, null), func(b []byte) error { _, _, err := ParsePerformativeBegin(b); return err }},
+		{"attach.name", []byte{0x45, 0xa1, 0x01, 0x41}, func(b []byte) error { _, _, err := ParsePerformativeAttach(b); return err }},
+		{"attach.handle", SerializeList(SerializeStringPrimitive("link")), func(b []byte) error { _, _, err := ParsePerformativeAttach(b); return err }},
+		{"attach.role", SerializeList(SerializeStringPrimitive("link"), one, null), func(b []byte) error { _, _, err := ParsePerformativeAttach(b); return err }},
+	}
+	for _, p := range parsers {
+		err := p.parse(p.buffer)
+		var decodeErr *DecodeError
+		if !errors.As(err, &decodeErr) || !errors.Is(err, ErrInvalidValue) || decodeErr.Field != p.field {
+			t.Errorf("parse of % x was incorrect, \n\texpected: \"%s\" \n\tgot:\"%v\"", p.buffer, p.field, err)
+		}
+	}
+}
+
+func TestParseFrameBody(t *testing.T) {
+	transferBody := []byte{
+		0x00, 0x53, 0x14, 0xd0, 0x00, 0x00, 0x00, 0x18,
+		0x00, 0x00, 0x00, 0x08, 0x52, 0x01, 0x52, 0x00, 0xa0, 0x08, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x52, 0x00, 0x42, 0x42, 0x40, 0x40, 0x00, 0x53, 0x70, 0x45}
+	performative, payload, err := ParseFrameBody(transferBody)
+	if err != nil {
+		t.Fatalf("%s\nParseFrameBody was incorrect, expected no errors", err.Error())
+	}
+	transfer, ok := performative.(*TransferParameters)
+	if !ok || transfer.Handle != 1 {
+		t.Errorf("ParseFrameBody was incorrect, \n\texpected: \"transfer with handle 1\" \n\tgot:\"%v\"", performative)
+	}
+	if !bytes.Equal(payload, []byte{0x00, 0x53, 0x70, 0x45}) {
+		t.Errorf("ParseFrameBody payload was incorrect, \n\texpected: \"00 53 70 45\" \n\tgot:\"% x\"", payload)
+	}
+
+	performatives := []Performative{
+		&ConnectionParameters{ContainerId: "amqpx-container", Hostname: ptrTo("testhost"), MaxFrameSize: ptrTo(DefaultMaxFrameSize),
+			ChannelMax: ptrTo(uint16(0x7fff)), IdleTimeoutMs: ptrTo(Milliseconds(30000))},
+		&SessionParameters{NextOutgoing: 1, IncomingWindow: 2048, OutgoingWindow: 2048, HandleMax: ptrTo(Handle(4294967295))},
+		&DispositionParameters{Role: true, First: 3, Last: ptrTo[DeliveryNumber](5), Settled: true},
+		&DetachParameters{Handle: 1, Closed: true},
+		&EndParameters{},
+		&CloseParameters{},
+	}
+	for _, want := range performatives {
+		buf, err := want.Marshal()
+		if err != nil {
+			t.Fatalf("%s\n%v Marshal was incorrect, expected no errors", err.Error(), want)
+		}
+		if !bytes.Equal(buf[:3], []byte{0x00, 0x53, byte(want.Descriptor())}) {
+			t.Errorf("Marshal was incorrect descriptor, \n\texpected: \"00 53 %02x\" \n\tgot:\"% x\"", want.Descriptor(), buf[:3])
+		}
+		got, payload, err := ParseFrameBody(buf)
+		if err != nil {
+			t.Fatalf("%s\nParseFrameBody was incorrect, expected no errors", err.Error())
+		}
+		if !reflect.DeepEqual(got, want) || len(payload) != 0 {
+			t.Errorf("ParseFrameBody round trip was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", want, got)
+		}
+	}
+
+	// a message section is not a performative
+	_, _, err = ParseFrameBody([]byte{0x00, 0x53, 0x70, 0x45})
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Field != "frame-body" {
+		t.Errorf("ParseFrameBody was incorrect, \n\texpected: \"frame-body DecodeError\" \n\tgot:\"%v\"", err)
+	}
+
+	// Unmarshal checks the descriptor
+	var begin SessionParameters
+	if _, err = begin.Unmarshal([]byte{0x00, 0x53, 0x10, 0x45}); err == nil {
+		t.Errorf("Unmarshal was incorrect, expected an error for an open descriptor")
+	}
+}
+
+func TestParseFrameBodyTrailingFields(t *testing.T) {
+	// a later version of the protocol may append fields, the payload starts after the whole list
+	null, unknown := SerializeNullPrimitive(), SerializeUintPrimitive(7)
+	nulls := func(n int) (bufs [][]byte) {
+		for i := 0; i < n; i++ {
+			bufs = append(bufs, null)
+		}
+		return bufs
+	}
+	items := func(bufs ...[]byte) []byte {
+		return SerializeList(append(bufs, unknown)...)
+	}
+	section := []byte{0x00, 0x53, 0x77, 0xa1, 0x01, 0x41}
+
+	bodies := []struct {
+		name  string
+		code  byte
+		list  []byte
+		check func(performative Performative) bool
+	}{
+		{"open", PerfOpen, items(append([][]byte{SerializeStringPrimitive("peer")}, nulls(9)...)...), func(p Performative) bool {
+			return p.(*ConnectionParameters).ContainerId == "peer"
+		}},
+		{"begin", PerfBegin, items(append([][]byte{null, SerializeUintPrimitive(1), SerializeUintPrimitive(2048), SerializeUintPrimitive(2048)}, nulls(4)...)...), func(p Performative) bool {
+			return p.(*SessionParameters).NextOutgoing == 1 && p.(*SessionParameters).OutgoingWindow == 2048
+		}},
+		{"attach", PerfAttach, items(append([][]byte{SerializeStringPrimitive("link"), SerializeUintPrimitive(1), SerializeBooleanPrimitive(true)}, nulls(11)...)...), func(p Performative) bool {
+			return p.(*AttachParameters).Name == "link" && p.(*AttachParameters).Handle == 1
+		}},
+		{"flow", PerfFlow, items(append([][]byte{null, SerializeUintPrimitive(2048), SerializeUintPrimitive(1), SerializeUintPrimitive(2048)}, nulls(7)...)...), func(p Performative) bool {
+			return p.(*FlowParameters).NextOutgoing == 1 && p.(*FlowParameters).Handle == nil
+		}},
+		{"transfer", PerfTransfer, items(append([][]byte{SerializeUintPrimitive(1), SerializeUintPrimitive(3), SerializeBinaryPrimitive([]byte{0x01})}, nulls(8)...)...), func(p Performative) bool {
+			return p.(*TransferParameters).Handle == 1 && *p.(*TransferParameters).DeliveryId == 3
+		}},
+	}
+	for _, body := range bodies {
+		buf := append(SerializeDescribedPrimitive(uint64(body.code), body.list), section...)
+		performative, payload, err := ParseFrameBody(buf)
+		if err != nil {
+			t.Fatalf("%s\nParseFrameBody %s was incorrect, expected no errors", err.Error(), body.name)
+		}
+		if !body.check(performative) {
+			t.Errorf("ParseFrameBody %s was incorrect, got:\"%v\"", body.name, performative)
+		}
+		if !bytes.Equal(payload, section) {
+			t.Errorf("ParseFrameBody %s payload was incorrect, \n\texpected: \"% x\" \n\tgot:\"% x\"", body.name, section, payload)
+		}
+	}
+}
+
+func TestDetachEndCloseError(t *testing.T) {
+	// detach handle 0, closed, error amqp:link:detach-forced
+	condition := append([]byte{0xa3, byte(len(ErrorLinkDetachForced))}, ErrorLinkDetachForced...)
+	errorList := append([]byte{0x00, 0x53, 0x1d, 0xc0, byte(len(condition) + 1), 0x01}, condition...)
+	detachBody := append([]byte{0x00, 0x53, 0x16, 0xc0, byte(len(errorList) + 3), 0x03, 0x43, 0x41}, errorList...)
+	performative, _, err := ParseFrameBody(detachBody)
+	if err != nil {
+		t.Fatalf("%s\nParseFrameBody was incorrect, expected no errors", err.Error())
+	}
+	detach, ok := performative.(*DetachParameters)
+	if !ok || !bool(detach.Closed) || detach.Error == nil || detach.Error.Condition != ErrorLinkDetachForced {
+		t.Errorf("ParseFrameBody was incorrect, \n\texpected: \"closed detach with %s\" \n\tgot:\"%v\"", ErrorLinkDetachForced, performative)
+	}
+
+	performatives := []Performative{
+		&DetachParameters{Handle: 3, Error: &Error{Condition: ErrorNotFound}},
+		&EndParameters{Error: NewError(ErrorSessionUnattachedHandle, "handle %d is not attached", 7)},
+		&CloseParameters{Error: &Error{Condition: ErrorConnectionForced, Description: ptrTo("shutting down"), Info: Fields{"retry": uint32(10)}}},
+	}
+	for _, want := range performatives {
+		buf, err := want.Marshal()
+		if err != nil {
+			t.Fatalf("%s\n%v Marshal was incorrect, expected no errors", err.Error(), want)
+		}
+		got, _, err := ParseFrameBody(buf)
+		if err != nil {
+			t.Fatalf("%s\nParseFrameBody was incorrect, expected no errors", err.Error())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseFrameBody round trip was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", want, got)
+		}
+	}
+
+	var amqpError error = NewError(ErrorSessionUnattachedHandle, "handle %d is not attached", 7)
+	if amqpError.Error() != "amqp:session:unattached-handle: handle 7 is not attached" {
+		t.Errorf("Error was incorrect, got:\"%s\"", amqpError.Error())
+	}
+
+	// condition is mandatory
+	if _, _, err = ParseError([]byte{0x00, 0x53, 0x1d, 0xc0, 0x01, 0x00}); err == nil {
+		t.Errorf("ParseError was incorrect, expected an error for a missing condition")
+	}
+	if _, err = (&CloseParameters{Error: &Error{}}).Marshal(); err == nil {
+		t.Errorf("Marshal was incorrect, expected an error for an empty condition")
+	}
+}
+
+func TestDeliveryStates(t *testing.T) {
+	states := []DeliveryState{
+		Received{SectionNumber: 2, SectionOffset: 1024},
+		Accepted{},
+		Rejected{Error: &Error{Condition: ErrorDecodeError, Description: ptrTo("poison message")}},
+		Released{},
+		Modified{DeliveryFailed: ptrTo[BooleanChoice](true), UndeliverableHere: ptrTo[BooleanChoice](true), MessageAnnotations: Fields{"x-opt-reason": "retry"}},
+	}
+	for _, state := range states {
+		want := &DispositionParameters{Role: true, First: 1, Settled: true, State: state}
+		buf, err := want.Marshal()
+		if err != nil {
+			t.Fatalf("%s\nMarshal was incorrect, expected no errors", err.Error())
+		}
+		got, _, err := ParseFrameBody(buf)
+		if err != nil {
+			t.Fatalf("%s\nParseFrameBody was incorrect, expected no errors", err.Error())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Disposition State round trip was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", want, got)
+		}
+
+		stateBuf, err := SerializeDeliveryStatePrimitive(state)
+		if err != nil || stateBuf[2] != byte(state.Descriptor()) {
+			t.Errorf("SerializeDeliveryStatePrimitive was incorrect, \n\texpected: \"descriptor 0x%02x\" \n\tgot:\"% x\" %v", state.Descriptor(), stateBuf, err)
+		}
+	}
+
+	// transfer with State rejected carrying an error
+	rejected, _ := SerializeDeliveryStatePrimitive(Rejected{Error: &Error{Condition: ErrorNotAllowed}})
+	transferList := SerializeList(SerializeUintPrimitive(0), SerializeUintPrimitive(1), SerializeBinaryPrimitive([]byte{0x01}),
+		SerializeUintPrimitive(0), SerializeBooleanPrimitive(true), SerializeBooleanPrimitive(false), SerializeNullPrimitive(), rejected)
+	transfer, _, err := ParsePerformativeTransfer(transferList)
+	if err != nil {
+		t.Fatalf("%s\nParsePerformativeTransfer was incorrect, expected no errors", err.Error())
+	}
+	if state, ok := transfer.State.(Rejected); !ok || state.Error.Condition != ErrorNotAllowed {
+		t.Errorf("ParsePerformativeTransfer State was incorrect, \n\texpected: \"%s\" \n\tgot:\"%v\"", ErrorNotAllowed, transfer.State)
+	}
+
+	// a number is not a delivery State
+	if _, _, err = ParseDeliveryStatePrimitive([]byte{0x52, 0x24}); err == nil {
+		t.Errorf("ParseDeliveryStatePrimitive was incorrect, expected an error for a smalluint")
+	}
+	if state, bytesUsed, err := ParseDeliveryStatePrimitive([]byte{nullCode}); err != nil || state != nil || bytesUsed != 1 {
+		t.Errorf("ParseDeliveryStatePrimitive was incorrect, \n\texpected: \"<nil>\" \n\tgot:\"%v\" %v", state, err)
+	}
+}
+
+func TestAttachTerminiRoundTrip(t *testing.T) {
+	selector := DescribedType{Descriptor: Symbol("apache.org:selector-filter:string"), Value: "color = 'red'"}
+	sender := &AttachParameters{
+		Name:          "orders-sender",
+		Handle:        3,
+		Role:          false,
+		SndSettleMode: ptrTo(SenderSettleModeChoice(mix)),
+		Source: &Source{
+			Address:          ptrTo("orders"),
+			Durable:          TerminusDurabilityUnsettled,
+			ExpiryPolicy:     ptrTo(TerminusExpiryNever),
+			Timeout:          30,
+			DistributionMode: ptrTo(DistributionModeCopy),
+			Filter:           FilterSet{"selector": selector},
+			DefaultOutcome:   Released{},
+			Outcomes:         []Symbol{OutcomeAccepted, OutcomeReleased},
+			Capabilities:     []Symbol{CapabilityQueue},
+		},
+		Target: Target{
+			Address:               ptrTo("replies"),
+			ExpiryPolicy:          ptrTo(TerminusExpiryLinkDetach),
+			Dynamic:               true,
+			DynamicNodeProperties: Fields{"lifetime-policy": "delete-on-close"},
+		},
+		InitialDeliveryCount: ptrTo(SequenceNo(7)),
+		MaxMessageSize:       ptrTo(uint64(1 << 40)),
+	}
+	coordinator := &AttachParameters{
+		Name:          "txn-controller",
+		Handle:        4,
+		SndSettleMode: ptrTo(SenderSettleModeChoice(mix)),
+		Source: &Source{
+			ExpiryPolicy: ptrTo(TerminusExpirySessionEnd),
+			Outcomes:     []Symbol{OutcomeAccepted, OutcomeRejected},
+		},
+		Target: Coordinator{Capabilities: []Symbol{TxnCapabilityLocalTransactions}},
+	}
+
+	for _, want := range []*AttachParameters{sender, coordinator} {
+		buf, err := want.Marshal()
+		if err != nil {
+			t.Fatalf("%s\nMarshal was incorrect, expected no errors", err.Error())
+		}
+		got, _, err := ParseFrameBody(buf)
+		if err != nil {
+			t.Fatalf("%s\nParseFrameBody was incorrect, expected no errors", err.Error())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Attach termini round trip was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", want, got)
+		}
+	}
+
+	// absent or null settle modes are mixed and first
+	name, handle, role := SerializeStringPrimitive("minimal"), SerializeUintPrimitive(5), SerializeBooleanPrimitive(true)
+	for _, fields := range [][][]byte{
+		{name, handle, role},
+		{name, handle, role, SerializeNullPrimitive(), SerializeNullPrimitive()},
+	} {
+		want := &AttachParameters{Name: "minimal", Handle: 5, Role: true, SndSettleMode: ptrTo(SenderSettleModeChoice(mix)), RcvSettleMode: ReceiverSettleModeChoice(first)}
+		got, _, err := ParseFrameBody(SerializeDescribedPrimitive(uint64(PerfAttach), SerializePerformative(fields...)))
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("Attach settle mode defaults were incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", want, got, err)
+		}
+	}
+
+	// an absent expiry-policy is session-end
+	source, _, err := ReadSourceList(SerializeList(SerializeStringPrimitive("orders")))
+	if err != nil || source.ExpiryPolicy == nil || *source.ExpiryPolicy != TerminusExpirySessionEnd {
+		t.Errorf("ReadSourceList ExpiryPolicy was incorrect, \n\texpected: \"%s\" \n\tgot:\"%v\" %v", TerminusExpirySessionEnd, source.ExpiryPolicy, err)
+	}
+}
+
+func TestTransferOptionalFields(t *testing.T) {
+	deliveryId, messageFormat, settled, rcvSettleMode := DeliveryNumber(7), MessageFormat(0), BooleanChoice(false), ReceiverSettleModeChoice(1)
+	first := &TransferParameters{
+		Handle:        2,
+		DeliveryId:    &deliveryId,
+		DeliveryTag:   DeliveryTag{0x07},
+		MessageFormat: &messageFormat,
+		Settled:       &settled,
+		More:          true,
+		RcvSettleMode: &rcvSettleMode,
+		State:         Received{SectionNumber: 1},
+		Batchable:     true,
+	}
+	continuation := &TransferParameters{Handle: 2, More: true}
+	aborted := &TransferParameters{Handle: 2, Resume: true, Aborted: true}
+	for _, want := range []*TransferParameters{first, continuation, aborted} {
+		buf, err := want.Marshal()
+		if err != nil {
+			t.Fatalf("%s\nMarshal was incorrect, expected no errors", err.Error())
+		}
+		got, _, err := ParseFrameBody(buf)
+		if err != nil {
+			t.Fatalf("%s\nParseFrameBody was incorrect, expected no errors", err.Error())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Transfer round trip was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", want, got)
+		}
+	}
+
+	// a continuation frame elides everything after more
+	buf, _ := continuation.Marshal()
+	if expected := SerializeDescribedPrimitive(uint64(PerfTransfer), SerializeList(SerializeUintPrimitive(2), SerializeNullPrimitive(), SerializeNullPrimitive(),
+		SerializeNullPrimitive(), SerializeNullPrimitive(), []byte{booleanTrue})); !bytes.Equal(buf, expected) {
+		t.Errorf("TransferParameters.Marshal was incorrect, \n\texpected: \"% x\" \n\tgot:\"% x\"", expected, buf)
+	}
+
+	// only the handle is mandatory
+	handleOnly := SerializeList(SerializeUintPrimitive(3))
+	transfer, bytesUsed, err := ParsePerformativeTransfer(handleOnly)
+	if err != nil || transfer.Handle != 3 || transfer.DeliveryId != nil || transfer.Settled != nil || bytesUsed != uint32(len(handleOnly)) {
+		t.Errorf("ParsePerformativeTransfer was incorrect, \n\texpected: \"handle 3 only\" \n\tgot:\"%v\" %v", transfer, err)
+	}
+	if _, _, err = ParsePerformativeTransfer(SerializeList()); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("ParsePerformativeTransfer was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", ErrInvalidValue, err)
+	}
+	if _, err = (TransferParameters{DeliveryTag: make(DeliveryTag, 33)}).Marshal(); err == nil {
+		t.Errorf("TransferParameters.Marshal was incorrect, expected an error for a 33 byte delivery-tag")
+	}
+}
+
+func TestFlowSessionAndLink(t *testing.T) {
+	nextIncoming, handle, deliveryCount, linkCredit := TransferNumber(1), Handle(2), SequenceNo(10), uint32(100)
+	session := &FlowParameters{NextIncoming: &nextIncoming, IncomingWindow: 2048, NextOutgoing: 1, OutgoingWindow: 2048, Echo: true}
+	link := &FlowParameters{IncomingWindow: 2048, NextOutgoing: 1, OutgoingWindow: 2048, Handle: &handle,
+		DeliveryCount: &deliveryCount, LinkCredit: &linkCredit, Drain: true, Properties: Fields{"x-opt-reason": "drain"}}
+	for _, want := range []*FlowParameters{session, link} {
+		buf, err := want.Marshal()
+		if err != nil {
+			t.Fatalf("%s\nMarshal was incorrect, expected no errors", err.Error())
+		}
+		got, _, err := ParseFrameBody(buf)
+		if err != nil {
+			t.Fatalf("%s\nParseFrameBody was incorrect, expected no errors", err.Error())
+		}
+		if !reflect.DeepEqual(got, want) || got.(*FlowParameters).IsLinkFlow() != (want.Handle != nil) {
+			t.Errorf("Flow round trip was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", want, got)
+		}
+	}
+
+	// a session flow ends after outgoing-window unless it echoes
+	buf, _ := FlowParameters{IncomingWindow: 1, NextOutgoing: 2, OutgoingWindow: 3}.Marshal()
+	if expected := SerializeDescribedPrimitive(uint64(PerfFlow), SerializeList(SerializeNullPrimitive(), SerializeUintPrimitive(1), SerializeUintPrimitive(2), SerializeUintPrimitive(3))); !bytes.Equal(buf, expected) {
+		t.Errorf("FlowParameters.Marshal was incorrect, \n\texpected: \"% x\" \n\tgot:\"% x\"", expected, buf)
+	}
+	if _, err := (FlowParameters{LinkCredit: &linkCredit}).Marshal(); err == nil {
+		t.Errorf("FlowParameters.Marshal was incorrect, expected an error for link-credit without a handle")
+	}
+	if _, _, err := ParsePerformativeFlow(SerializeList(SerializeNullPrimitive(), SerializeUintPrimitive(1))); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("ReadFlowPerformative was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", ErrInvalidValue, err)
+	}
+}
+
+func TestMessageSections(t *testing.T) {
+	messages := []Message{
+		{
+			Header:                &MessageHeader{Durable: true, Priority: ptrTo(byte(4))},
+			DeliveryAnnotations:   Annotations{Symbol("x-opt-lock-token"): "abc"},
+			MessageAnnotations:    Annotations{Symbol("x-opt-partition-key"): "p1"},
+			Properties:            &MessageProperties{MessageId: MessageIDString("id-1"), To: ptrTo("orders"), Subject: ptrTo("created")},
+			ApplicationProperties: Map{"count": int32(2)},
+			Data:                  []Binary{Binary("hello "), Binary("world")},
+			Footer:                Annotations{Symbol("x-opt-checksum"): uint32(7)},
+		},
+		{AmqpSequence: [][]interface{}{{"a", int32(1)}, {true}}},
+		{AmqpValue: &MessageAmqpValue{Value: "any type of value"}},
+		{Properties: &MessageProperties{ContentType: ptrTo[Symbol]("text/plain")}, AmqpValue: &MessageAmqpValue{Value: map[interface{}]interface{}{"k": "v"}}},
+	}
+	for _, want := range messages {
+		buf, err := want.Marshal()
+		if err != nil {
+			t.Fatalf("%s\nMessage.Marshal was incorrect, expected no errors", err.Error())
+		}
+		var got Message
+		bytesUsed, err := got.Unmarshal(buf)
+		if err != nil {
+			t.Fatalf("%s\nMessage.Unmarshal was incorrect, expected no errors", err.Error())
+		}
+		if !reflect.DeepEqual(got, want) || bytesUsed != uint32(len(buf)) {
+			t.Errorf("Message round trip was incorrect, \n\texpected: \"%+v\" \n\tgot:\"%+v\"", want, got)
+		}
+	}
+
+	if _, err := (Message{Data: []Binary{Binary("x")}, AmqpValue: &MessageAmqpValue{}}).Marshal(); err == nil {
+		t.Errorf("Message.Marshal was incorrect, expected an error for data and amqp-value in one body")
+	}
+
+	// sections may carry symbolic descriptors
+	var symbolic []byte
+	applicationProperties, _ := SerializeMapPrimitive(Map{"k": "v"})
+	for _, section := range []struct {
+		name  Symbol
+		value []byte
+	}{
+		{"amqp:application-properties:map", applicationProperties},
+		{"amqp:data:binary", SerializeBinaryPrimitive([]byte("x"))},
+	} {
+		symbolic = append(append(append(symbolic, 0x00), SerializeSymbolPrimitive(section.name)...), section.value...)
+	}
+	var message Message
+	if _, err := message.Unmarshal(symbolic); err != nil || len(message.Data) != 1 || message.ApplicationProperties["k"] != "v" {
+		t.Errorf("Message.Unmarshal (symbolic descriptors) was incorrect, \n\texpected: \"%v\" \n\tgot:\"%+v\" %v", "k=v and data x", message, err)
+	}
+
+	header, _ := Marshal(MessageHeader{Durable: true})
+	properties, _ := Marshal(MessageProperties{To: ptrTo("orders")})
+	data := SerializeDescribedPrimitive(uint64(PerfData), SerializeBinaryPrimitive([]byte("x")))
+	value, _ := Marshal(MessageAmqpValue{Value: "x"})
+	outOfOrder := [][]byte{
+		append(append([]byte{}, properties...), header...),
+		append(append([]byte{}, header...), header...),
+		append(append([]byte{}, data...), value...),
+		append(append([]byte{}, value...), value...),
+	}
+	for _, buf := range outOfOrder {
+		var message Message
+		if _, err := message.Unmarshal(buf); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("Message.Unmarshal was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" for % x", ErrInvalidValue, err, buf)
+		}
+	}
+}
+
+func TestMessagePropertiesIds(t *testing.T) {
+	uuid, _ := ParseUUID("f81d4fae-7dec-11d0-a765-00a0c91e6bf6")
+	created := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	ids := []MessageID{MessageIDUlong(42), MessageIDUUID(uuid), MessageIDBinary{0x01, 0x02}, MessageIDString("order-1")}
+	for _, id := range ids {
+		want := MessageProperties{MessageId: id, UserId: Binary("guest"), CorrelationId: id, ReplyTo: ptrTo("replies")}
+		want.SetCreated(created)
+		buf, err := want.Marshal()
+		if err != nil {
+			t.Fatalf("%s\nMessageProperties.Marshal was incorrect, expected no errors", err.Error())
+		}
+		var got MessageProperties
+		bytesUsed, err := Unmarshal(buf, &got)
+		if err != nil {
+			t.Fatalf("%s\nUnmarshal MessageProperties was incorrect, expected no errors", err.Error())
+		}
+		if !reflect.DeepEqual(got, want) || bytesUsed != uint32(len(buf)) {
+			t.Errorf("MessageProperties round trip was incorrect, \n\texpected: \"%+v\" \n\tgot:\"%+v\"", want, got)
+		}
+		if !got.Created().Equal(created) || !got.AbsoluteExpiryTime().IsZero() {
+			t.Errorf("MessageProperties times were incorrect, \n\texpected: \"%v\" \n\tgot:\"%v %v\"", created, got.Created(), got.AbsoluteExpiryTime())
+		}
+	}
+
+	// message-ids read by ParseAny, as the items of a list
+	var bufs [][]byte
+	for _, id := range ids {
+		buf, _ := id.MarshalAMQP()
+		bufs = append(bufs, buf)
+	}
+	var got []MessageID
+	if _, err := Unmarshal(SerializeList(bufs...), &got); err != nil {
+		t.Fatalf("%s\nUnmarshal []MessageID was incorrect, expected no errors", err.Error())
+	}
+	if !reflect.DeepEqual(got, ids) {
+		t.Errorf("Unmarshal []MessageID was incorrect, \n\texpected: \"%#v\" \n\tgot:\"%#v\"", ids, got)
+	}
+
+	if _, _, err := ParseMessageIDPrimitive([]byte{booleanTrue}); !errors.Is(err, ErrUnexpectedConstructor) {
+		t.Errorf("ParseMessageIDPrimitive was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", ErrUnexpectedConstructor, err)
+	}
+	if id, bytesUsed, err := ParseMessageIDPrimitive([]byte{nullCode}); id != nil || bytesUsed != 1 || err != nil {
+		t.Errorf("ParseMessageIDPrimitive was incorrect, \n\texpected: \"<nil>\" \n\tgot:\"%v\" %v", id, err)
+	}
+}
+
+func TestDeliveryFragmentAndAssemble(t *testing.T) {
+	want := Message{
+		Properties: &MessageProperties{MessageId: MessageIDUlong(1)},
+		Data:       []Binary{bytes.Repeat([]byte("0123456789"), 200)},
+	}
+	payload, err := want.Marshal()
+	if err != nil {
+		t.Fatalf("%s\nMessage.Marshal was incorrect, expected no errors", err.Error())
+	}
+	deliveryId, messageFormat := DeliveryNumber(5), MessageFormat(0)
+	transfer := TransferParameters{Handle: 1, DeliveryId: &deliveryId, DeliveryTag: DeliveryTag{0x05}, MessageFormat: &messageFormat}
+
+	frames, err := FragmentDelivery(transfer, payload, MinMaxFrameSize)
+	if err != nil {
+		t.Fatalf("%s\nFragmentDelivery was incorrect, expected no errors", err.Error())
+	}
+	if len(frames) < 4 {
+		t.Errorf("FragmentDelivery was incorrect, \n\texpected: \"at least 4 frames\" \n\tgot:\"%d\"", len(frames))
+	}
+
+	var assembler DeliveryAssembler
+	var delivery *Delivery
+	for i, frame := range frames {
+		body, err := frame.Marshal()
+		if err != nil || szFrameHeader+len(body) > int(MinMaxFrameSize) {
+			t.Fatalf("TransferFrame.Marshal was incorrect, \n\texpected: \"<= %d bytes\" \n\tgot:\"%d\" %v", MinMaxFrameSize, szFrameHeader+len(body), err)
+		}
+		performative, framePayload, err := ParseFrameBody(body)
+		if err != nil {
+			t.Fatalf("%s\nParseFrameBody was incorrect, expected no errors", err.Error())
+		}
+		if delivery, err = assembler.Add(*performative.(*TransferParameters), framePayload); err != nil {
+			t.Fatalf("%s\nDeliveryAssembler.Add was incorrect, expected no errors", err.Error())
+		}
+		if (delivery != nil) != (i == len(frames)-1) || assembler.InProgress() != (i < len(frames)-1) {
+			t.Errorf("DeliveryAssembler.Add was incorrect after frame %d of %d, got:%v", i+1, len(frames), delivery)
+		}
+	}
+	got, err := delivery.Message()
+	if err != nil || !reflect.DeepEqual(got, want) || *delivery.Transfer.DeliveryId != deliveryId {
+		t.Errorf("Delivery.Message was incorrect, \n\texpected: \"%+v\" \n\tgot:\"%+v\" %v", want, got, err)
+	}
+
+	// an aborted delivery is dropped
+	assembler.Add(frames[0].Transfer, frames[0].Payload)
+	if delivery, err = assembler.Add(TransferParameters{Handle: 1, Aborted: true}, nil); delivery != nil || err != nil || assembler.InProgress() {
+		t.Errorf("DeliveryAssembler.Add was incorrect, \n\texpected: \"aborted\" \n\tgot:\"%v\" %v", delivery, err)
+	}
+
+	// a delivery larger than max-message-size is refused
+	assembler.MaxMessageSize = 1000
+	var amqpError *Error
+	for _, frame := range frames {
+		if _, err = assembler.Add(frame.Transfer, frame.Payload); err != nil {
+			break
+		}
+	}
+	if !errors.As(err, &amqpError) || amqpError.Condition != ErrorLinkMessageSizeExceeded || assembler.InProgress() {
+		t.Errorf("DeliveryAssembler.Add was incorrect, \n\texpected: \"%s\" \n\tgot:\"%v\"", ErrorLinkMessageSizeExceeded, err)
+	}
+
+	if _, err = assembler.Add(TransferParameters{Handle: 1}, nil); !errors.As(err, &amqpError) || amqpError.Condition != ErrorInvalidField {
+		t.Errorf("DeliveryAssembler.Add was incorrect, \n\texpected: \"%s\" \n\tgot:\"%v\"", ErrorInvalidField, err)
+	}
+	if _, err = FragmentDelivery(transfer, payload, 16); err == nil {
+		t.Errorf("FragmentDelivery was incorrect, expected an error for a 16 byte max-frame-size")
+	}
+}
@@ -3,8 +3,6 @@ package amqpx
 import (
 	"encoding/binary"
 	"encoding/json"
-	"errors"
-	"fmt"
 
 	log "github.com/mgutz/logxi/v1"
 )
@@ -25,11 +23,24 @@ const (
 	PerfFlow        byte = 0x13
 	PerfTransfer    byte = 0x14
 	PerfDisposition byte = 0x15
+	PerfDetach      byte = 0x16
+	PerfEnd         byte = 0x17
+	PerfClose       byte = 0x18
 	PerfHeader      byte = 0x70
 	PerfProperties  byte = 0x73
 	PerfAmqpValue   byte = 0x77
 )
 
+// Descriptors of the message sections that have no performative of their own
+const (
+	PerfDeliveryAnnotations   byte = 0x71
+	PerfMessageAnnotations    byte = 0x72
+	PerfApplicationProperties byte = 0x74
+	PerfData                  byte = 0x75
+	PerfAmqpSequence          byte = 0x76
+	PerfFooter                byte = 0x78
+)
+
 const (
 	szFrameHeader = 8
 	szInt32       = 4
@@ -39,16 +50,17 @@ const (
 
 var amqp100 = []byte{0x41, 0x4d, 0x51, 0x50, 0x00, 0x01, 0x00, 0x00}
 
-// SerializePerformative a List from given buffers
-func SerializePerformative(performative byte, bufs ...[]byte) (retBuf []byte) {
+// SerializePerformative the 32bit List of a performative from given buffers.
+// The descriptor is written by the caller, see SerializeDescribedPrimitive
+func SerializePerformative(bufs ...[]byte) (retBuf []byte) {
 	listCount := len(bufs)
 	listSize := 0
 	for _, buf := range bufs {
 		listSize += len(buf)
 	}
-	retBuf = make([]byte, listSize+5+4) // 5: 0xd0-list code plus 4byte for items count
-	retBuf[0] = 0xd0
-	binary.BigEndian.PutUint32(retBuf[1:], uint32(performative))
+	retBuf = make([]byte, listSize+5+4) // 5: 0xd0-list code plus 4byte Size, 4byte for items count
+	retBuf[0] = list32Code
+	binary.BigEndian.PutUint32(retBuf[1:], uint32(listSize+szInt32)) // Size includes the count
 	binary.BigEndian.PutUint32(retBuf[5:], uint32(listCount))
 	inx := 9
 	for _, buf := range bufs {
@@ -58,18 +70,34 @@ func SerializePerformative(performative byte, bufs ...[]byte) (retBuf []byte) {
 	return retBuf
 }
 
-// ParseProtocolHeader reads the Protocol version
+// Protocol ids of the protocol header, each layer of a connection starts with its header.
+// Spec sections 2.2 Version Negotiation, 5.1 TLS and 5.3 SASL
+const (
+	ProtocolIdAMQP byte = 0
+	ProtocolIdTLS  byte = 2
+	ProtocolIdSASL byte = 3
+)
+
+// ParseProtocolHeader reads the Protocol version, the protocol id is one of AMQP, TLS or SASL
 func ParseProtocolHeader(buffer []byte) (protocolVersion ProtoocolVersion, bytesUsed uint32, err error) {
 	bytesUsed = 0
 	if len(buffer) < szFrameHeader {
-		return protocolVersion, bytesUsed, errors.New("amqllib: Protocol header does not include enough data")
+		return protocolVersion, bytesUsed, truncatedError(szFrameHeader, len(buffer))
 	}
 
 	for i, v := range amqp100 {
+		if i == 4 {
+			continue
+		}
 		if v != buffer[i] {
-			return protocolVersion, bytesUsed, errors.New("amqpx Protocol version mismatch")
+			return protocolVersion, bytesUsed, decodeErrorAt(invalidValueError("Protocol version mismatch"), uint32(i), "protocol-header")
 		}
 	}
+	switch buffer[4] {
+	case ProtocolIdAMQP, ProtocolIdTLS, ProtocolIdSASL:
+	default:
+		return protocolVersion, bytesUsed, decodeErrorAt(invalidValueError("unknown protocol id %d", buffer[4]), 4, "protocol-header")
+	}
 
 	copy(protocolVersion.Protocol[:], buffer[0:4])
 	protocolVersion.ProtocolId = buffer[4]
@@ -80,60 +108,146 @@ func ParseProtocolHeader(buffer []byte) (protocolVersion ProtoocolVersion, bytes
 	return protocolVersion, bytesUsed, nil
 }
 
+// Frame types, the type code of the frame header
+const (
+	FrameTypeAMQP byte = 0x00
+	FrameTypeSASL byte = 0x01
+)
+
 // Frame ... gather in the beginning of a frame
 type Frame struct {
 	Size     uint32 `json:"size"`
 	Doff     byte   `json:"Doff"`
 	TypeCode byte   `json:"typeCode"`
 	Channel  uint16 `json:"Channel"`
+	// ExtendedHeader is what lies between the 8 byte header and the data offset, ignored by AMQP 1.0
+	ExtendedHeader []byte `json:"extendedHeader,omitempty"`
+	// Body is the frame body, empty for a heartbeat frame
+	Body []byte `json:"body,omitempty"`
 }
 
-// ParseFraming ... reads the frame header
+// IsEmpty tells whether the frame has no body, empty frames keep an idle connection alive
+func (frame Frame) IsEmpty() bool {
+	return len(frame.Body) == 0
+}
+
+// ParseFraming ... reads the frame header, and the extended header, body and the descriptor
+// of the body when buffer holds them. bytesUsed points past the descriptor
 func ParseFraming(buffer []byte) (frame Frame, bytesUsed uint32, performative byte, err error) {
 	bytesUsed = 0
 	performative = 0
 	inx := uint32(0)
 	// Read required header: fixed 8 bytes
 	if len(buffer) < szFrameHeader {
-		return frame, bytesUsed, performative, errors.New("amqpx: not enough buffer for frame header")
+		return frame, bytesUsed, performative, decodeErrorAt(truncatedError(szFrameHeader, len(buffer)), 0, "frame")
 	}
 
 	frame.Size = binary.BigEndian.Uint32(buffer[inx:])
 	inx += szInt32
 	if frame.Size < szFrameHeader {
-		return frame, bytesUsed, performative, errors.New("amqpx: Size can not be less than the Size of require frame header: 8")
+		return frame, bytesUsed, performative, decodeErrorAt(invalidValueError("Size %d can not be less than the Size of require frame header: 8", frame.Size), 0, "frame.size")
 	}
 
 	frame.Doff = buffer[inx]
 	inx++
 	if frame.Doff < 2 {
-		return frame, bytesUsed, performative, errors.New("amqpx: Doff can not be less than '2' the Size of require frame header: 2x4")
+		return frame, bytesUsed, performative, decodeErrorAt(invalidValueError("Doff %d can not be less than '2' the Size of require frame header: 2x4", frame.Doff), 4, "frame.doff")
+	}
+	if 4*uint32(frame.Doff) > frame.Size {
+		return frame, bytesUsed, performative, decodeErrorAt(invalidValueError("Doff %d points past the end of the frame of Size %d", frame.Doff, frame.Size), 4, "frame.doff")
 	}
 
 	frame.TypeCode = buffer[inx]
 	inx++
-	if frame.TypeCode != 0 {
-		return frame, bytesUsed, performative, errors.New("amqpx: we only Handle AMQP types for now")
+	if frame.TypeCode != FrameTypeAMQP && frame.TypeCode != FrameTypeSASL {
+		return frame, bytesUsed, performative, decodeErrorAt(invalidValueError("frame type 0x%02x is neither AMQP nor SASL", frame.TypeCode), 5, "frame.type")
 	}
 
 	frame.Channel = binary.BigEndian.Uint16(buffer[inx:])
 	inx += szInt16
-	// nothing To check with Channel
-
-	// Skipping ..Read optional extended header: variable
-	fmt.Printf("Checking buffer[%d] for ParseBlockType\n", inx)
-	performative, used, _ := ParseBlockType(buffer[inx:])
-	inx += used
+	// nothing To check with Channel, a SASL frame ignores it
 
-	fmt.Printf("performative is 0x% x used:%d\n", performative, used)
+	// Read optional extended header: variable, when the buffer holds it
+	doff := 4 * uint32(frame.Doff)
+	if uint32(len(buffer)) < doff {
+		return frame, inx, performative, nil
+	}
+	frame.ExtendedHeader = buffer[inx:doff]
+	inx = doff
+	if uint32(len(buffer)) >= frame.Size {
+		frame.Body = buffer[doff:frame.Size]
+	}
 
-	// Read optional frame body: variable
+	// Read optional frame body: variable, the performative is read when present
 	// Returning the bytesUsed so the framebody can get handled there
-	//bytesUsed = 4 * uint32(frame.Doff)
+	if frame.Size > doff && uint32(len(buffer)) > doff {
+		if blockType, used, err := ParseBlockType(buffer[inx:]); err == nil {
+			performative = blockType
+			inx += used
+		}
+	}
 	bytesUsed = inx
 	return frame, bytesUsed, performative, nil
 }
 
+// Performative is the frame body of an AMQP frame: open, begin, attach, flow,
+// transfer, disposition, detach, end or close
+type Performative interface {
+	// Descriptor returns the descriptor code of the performative
+	Descriptor() uint64
+	// Marshal serializes the performative including its descriptor
+	Marshal() ([]byte, error)
+	// Unmarshal reads the performative from buffer, the descriptor is optional
+	Unmarshal(buffer []byte) (bytesUsed uint32, err error)
+	String() string
+}
+
+// newPerformative returns an empty performative for a descriptor code, nil if the code is not a performative
+func newPerformative(code uint64) Performative {
+	switch code {
+	case uint64(PerfOpen):
+		return &ConnectionParameters{}
+	case uint64(PerfBegin):
+		return &SessionParameters{}
+	case uint64(PerfAttach):
+		return &AttachParameters{}
+	case uint64(PerfFlow):
+		return &FlowParameters{}
+	case uint64(PerfTransfer):
+		return &TransferParameters{}
+	case uint64(PerfDisposition):
+		return &DispositionParameters{}
+	case uint64(PerfDetach):
+		return &DetachParameters{}
+	case uint64(PerfEnd):
+		return &EndParameters{}
+	case uint64(PerfClose):
+		return &CloseParameters{}
+	}
+	return nil
+}
+
+// ParseFrameBody reads the performative at the start of a frame body, buffer starts
+// at the data offset of the frame. The payload is what follows the performative,
+// e.g. the message sections of a transfer
+func ParseFrameBody(buffer []byte) (performative Performative, payload []byte, err error) {
+	descriptor, _, err := ParseDescriptor(buffer)
+	if err != nil {
+		return nil, nil, decodeErrorAt(err, 0, "frame-body")
+	}
+	code, _ := DescriptorCode(descriptor)
+	performative = newPerformative(code)
+	if performative == nil {
+		return nil, nil, decodeErrorAt(invalidValueError("descriptor %v is not a performative", descriptor), 0, "frame-body")
+	}
+
+	bytesUsed, err := performative.Unmarshal(buffer)
+	if err != nil {
+		return nil, nil, err
+	}
+	return performative, buffer[bytesUsed:], nil
+}
+
 func displayJsonStruct(v interface{}) {
 	jsonData, _ := json.Marshal(v)
 	log.Debug(string(jsonData))
@@ -5,6 +5,10 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
+	"sort"
+	"time"
+	"unicode/utf8"
 
 	log "github.com/mgutz/logxi/v1"
 )
@@ -30,6 +34,7 @@ const (
 	longCode       byte = 0x81 // "fixed-width="8" label="64-bit two's-complement integer in network byte order"
 	longSmallCode  byte = 0x55 // "fixed-width="1" label="8-bit two's-complement integer"
 	floatCode      byte = 0x72 // "fixed-width="4" label="IEEE 754-2008 binary32"
+	doubleCode     byte = 0x82 // "fixed-width="8" label="IEEE 754-2008 binary64"
 	decimal32Code  byte = 0x74 // "fixed-width="4" label="IEEE 754-2008 decimal32 using the Binary Integer Decimal encoding"
 	decimal64Code  byte = 0x84 // "fixed-width="8" label="IEEE 754-2008 decimal64 using the Binary Integer Decimal encoding"
 	decimal128Code byte = 0x94 // "fixed-width="16" label="IEEE 754-2008 decimal128 using the Binary Integer Decimal encoding"
@@ -57,9 +62,25 @@ const (
 	booleanCodeTrue  byte = 0x01
 )
 
-// Timestamp is an int64 Value
+// Timestamp is an int64 Value, milliseconds since the unix epoch
 type Timestamp int64
 
+// NewTimestamp returns the timestamp of t, the zero time is the zero timestamp
+func NewTimestamp(t time.Time) Timestamp {
+	if t.IsZero() {
+		return 0
+	}
+	return Timestamp(t.UnixMilli())
+}
+
+// Time returns the timestamp as a time.Time, the zero timestamp is the zero time
+func (timestamp Timestamp) Time() time.Time {
+	if timestamp == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(timestamp))
+}
+
 // Binary is a byte array max from binary32Code
 type Binary []byte
 
@@ -89,6 +110,11 @@ func SerializeNullPrimitive() (buf []byte) {
 	return buf
 }
 
+// isNullPrimitive reports whether buffer starts with null, an empty buffer is left To the parser of the field
+func isNullPrimitive(buffer []byte) bool {
+	return len(buffer) > 0 && buffer[0] == nullCode
+}
+
 // SerializeBooleanPrimitive serialized boolean
 func SerializeBooleanPrimitive(value bool) (buf []byte) {
 	buf = make([]byte, 2)
@@ -106,9 +132,12 @@ func ParseBooleanPrimitive(buffer []byte) (retVal bool, bytesUsed uint32, err er
 	retVal = false
 	bytesUsed = 0
 	inx := 0
+	if len(buffer) < 1 {
+		return retVal, bytesUsed, truncatedError(1, 0)
+	}
 	if buffer[inx] == booleanCode {
 		if len(buffer) < 2 {
-			return retVal, bytesUsed, errors.New("amqpx: buffer len must be 2 or More for booleanCode")
+			return retVal, bytesUsed, truncatedError(2, len(buffer))
 		}
 		inx++
 		if buffer[inx] == 0x00 {
@@ -116,7 +145,7 @@ func ParseBooleanPrimitive(buffer []byte) (retVal bool, bytesUsed uint32, err er
 		} else if buffer[inx] == 0x01 {
 			retVal = true
 		} else {
-			return retVal, bytesUsed, errors.New("amqpx: booleanCode expected false(0x00) or true(0x01) got :" + fmt.Sprintf("0x%x", buffer[inx]))
+			return retVal, bytesUsed, invalidValueError("booleanCode expected false(0x00) or true(0x01) got :0x%x", buffer[inx])
 		}
 		inx++
 		bytesUsed = uint32(inx)
@@ -128,7 +157,7 @@ func ParseBooleanPrimitive(buffer []byte) (retVal bool, bytesUsed uint32, err er
 	} else if buffer[inx] == booleanFalse {
 		retVal = false
 	} else {
-		return retVal, bytesUsed, errors.New("amqpx: expected true(0x41) or false(0x42) got :" + fmt.Sprintf("0x%x", buffer[inx]))
+		return retVal, bytesUsed, constructorError(buffer, booleanTrue, booleanFalse, booleanCode)
 	}
 	inx++
 	bytesUsed = uint32(inx)
@@ -171,10 +200,13 @@ func ParseUbytePrimitive(buffer []byte) (retVal byte, bytesUsed uint32, err erro
 	bytesUsed = 0
 	inx := uint32(0)
 
+	if len(buffer) < 1 {
+		return retVal, bytesUsed, truncatedError(1, 0)
+	}
 	switch buffer[inx] {
 	case ubyteCode:
 		if len(buffer) < 2 {
-			return retVal, bytesUsed, errors.New("amqpx: buffer len must be 2 or More for ubyteCode")
+			return retVal, bytesUsed, truncatedError(2, len(buffer))
 		}
 		inx++
 		retVal = buffer[inx]
@@ -182,7 +214,7 @@ func ParseUbytePrimitive(buffer []byte) (retVal byte, bytesUsed uint32, err erro
 		bytesUsed = inx
 		return retVal, bytesUsed, nil
 	default:
-		return retVal, bytesUsed, errors.New("amqpx: contructor not ubyteCode")
+		return retVal, bytesUsed, constructorError(buffer, ubyteCode)
 	}
 }
 
@@ -200,10 +232,13 @@ func PraseUshortPrimitive(buffer []byte) (retVal uint16, bytesUsed uint32, err e
 	bytesUsed = 0
 
 	inx := uint32(0)
+	if len(buffer) < 1 {
+		return retVal, bytesUsed, truncatedError(1, 0)
+	}
 	switch buffer[inx] {
 	case ushortCode:
 		if len(buffer) < 3 {
-			return retVal, bytesUsed, errors.New("amqpx: buffer len must be 3 or More for ushortCode")
+			return retVal, bytesUsed, truncatedError(3, len(buffer))
 		}
 		inx++
 		retVal = binary.BigEndian.Uint16(buffer[inx:])
@@ -211,16 +246,15 @@ func PraseUshortPrimitive(buffer []byte) (retVal uint16, bytesUsed uint32, err e
 		bytesUsed = inx
 		return retVal, bytesUsed, nil
 	default:
-		return retVal, bytesUsed, errors.New("amqpx: contructor not ushortCode")
+		return retVal, bytesUsed, constructorError(buffer, ushortCode)
 	}
 }
 
 // SerializeUintPrimitive serialized uint
 func SerializeUintPrimitive(value uint32) (buf []byte) {
 	if value == 0 {
-		buf = make([]byte, 2)
+		buf = make([]byte, 1)
 		buf[0] = uint0Code
-		buf[1] = 0
 	} else if value <= 0xff {
 		buf = make([]byte, 2)
 		buf[0] = uintSmallCode
@@ -239,10 +273,13 @@ func ParseUintPrimitive(buffer []byte) (retVal uint32, bytesUsed uint32, err err
 	bytesUsed = 0
 	inx := 0
 
+	if len(buffer) < 1 {
+		return retVal, bytesUsed, truncatedError(1, 0)
+	}
 	switch buffer[inx] {
 	case uintCode:
 		if len(buffer) < 5 {
-			return retVal, bytesUsed, errors.New("amqpx: buffer len must be 5 or More for uintCode")
+			return retVal, bytesUsed, truncatedError(5, len(buffer))
 		}
 		inx++
 		retVal = binary.BigEndian.Uint32(buffer[inx:])
@@ -251,7 +288,7 @@ func ParseUintPrimitive(buffer []byte) (retVal uint32, bytesUsed uint32, err err
 		return retVal, bytesUsed, nil
 	case uintSmallCode:
 		if len(buffer) < 2 {
-			return retVal, bytesUsed, errors.New("amqpx: buffer len must be 2 or More for uintSmallCode")
+			return retVal, bytesUsed, truncatedError(2, len(buffer))
 		}
 		inx++
 		retVal = uint32(buffer[inx])
@@ -269,27 +306,43 @@ func ParseUintPrimitive(buffer []byte) (retVal uint32, bytesUsed uint32, err err
 		bytesUsed = uint32(inx)
 		return retVal, bytesUsed, nil
 	default:
-		return retVal, bytesUsed, errors.New("amqpx: contructor not uintCode, uintSmallCode or uint0Code")
+		return retVal, bytesUsed, constructorError(buffer, uintCode, uintSmallCode, uint0Code)
 	}
 }
 
-// SerializeDeliveryStatePrimitive serialized DeliveryState
-func SerializeDeliveryStatePrimitive(value DeliveryState) (buf []byte) {
-	return SerializeUintPrimitive(uint32(value))
+// SerializeDeliveryStatePrimitive serialized DeliveryState as its described list, nil as null
+func SerializeDeliveryStatePrimitive(value DeliveryState) (buf []byte, err error) {
+	if value == nil {
+		return SerializeNullPrimitive(), nil
+	}
+	return Marshal(value)
 }
 
-// ParseDeliveryStatePrimitive reads a uint
+// ParseDeliveryStatePrimitive reads a described delivery State, null is read as nil
 func ParseDeliveryStatePrimitive(buffer []byte) (retVal DeliveryState, bytesUsed uint32, err error) {
-	state, advanceInx, err := ParseUintPrimitive(buffer[0:])
-	return DeliveryState(state), advanceInx, err
+	if len(buffer) < 1 {
+		return nil, 0, truncatedError(1, len(buffer))
+	}
+	if buffer[0] == nullCode {
+		return nil, 1, nil
+	}
+
+	value, bytesUsed, err := ParseDescribedPrimitive(buffer)
+	if err != nil {
+		return nil, 0, err
+	}
+	retVal, ok := value.(DeliveryState)
+	if !ok {
+		return nil, 0, invalidValueError("expected a delivery State, got %#v", value)
+	}
+	return retVal, bytesUsed, nil
 }
 
 // SerializeUlongPrimitive serialized ulong
 func SerializeUlongPrimitive(value uint64) (buf []byte) {
 	if value == 0 {
-		buf = make([]byte, 2)
+		buf = make([]byte, 1)
 		buf[0] = ulong0Code
-		buf[1] = 0
 	} else if value <= 0xff {
 		buf = make([]byte, 2)
 		buf[0] = ulongSmallCode
@@ -308,10 +361,13 @@ func ParseUlongPrimitive(buffer []byte) (retVal uint64, bytesUsed uint32, err er
 	bytesUsed = 0
 	inx := uint32(0)
 
+	if len(buffer) < 1 {
+		return retVal, bytesUsed, truncatedError(1, 0)
+	}
 	switch buffer[inx] {
 	case ulongCode:
 		if len(buffer) < 9 {
-			return retVal, bytesUsed, errors.New("amqpx: buffer len must be 9 or More for ulongCode")
+			return retVal, bytesUsed, truncatedError(9, len(buffer))
 		}
 		inx++
 		retVal = binary.BigEndian.Uint64(buffer[inx:])
@@ -320,7 +376,7 @@ func ParseUlongPrimitive(buffer []byte) (retVal uint64, bytesUsed uint32, err er
 		return retVal, bytesUsed, nil
 	case ulongSmallCode:
 		if len(buffer) < 2 {
-			return retVal, bytesUsed, errors.New("amqpx: buffer len must be 2 or More for ulongSmallCode")
+			return retVal, bytesUsed, truncatedError(2, len(buffer))
 		}
 		inx++
 		retVal = uint64(buffer[inx])
@@ -333,7 +389,7 @@ func ParseUlongPrimitive(buffer []byte) (retVal uint64, bytesUsed uint32, err er
 		bytesUsed = inx
 		return retVal, bytesUsed, nil
 	default:
-		return retVal, bytesUsed, errors.New("amqpx: contructor not ulongCode, ulongSmallCode or ulong0Code")
+		return retVal, bytesUsed, constructorError(buffer, ulongCode, ulongSmallCode, ulong0Code)
 	}
 }
 
@@ -351,10 +407,13 @@ func ParseBytePrimitive(buffer []byte) (retVal byte, bytesUsed uint32, err error
 	bytesUsed = 0
 	inx := uint32(0)
 
+	if len(buffer) < 1 {
+		return retVal, bytesUsed, truncatedError(1, 0)
+	}
 	switch buffer[inx] {
 	case byteCode:
 		if len(buffer) < 2 {
-			return retVal, bytesUsed, errors.New("amqpx: buffer len must be 2 or More for byteCode")
+			return retVal, bytesUsed, truncatedError(2, len(buffer))
 		}
 		inx++
 		retVal = buffer[inx]
@@ -362,7 +421,7 @@ func ParseBytePrimitive(buffer []byte) (retVal byte, bytesUsed uint32, err error
 		bytesUsed = inx
 		return retVal, bytesUsed, nil
 	default:
-		return retVal, bytesUsed, errors.New("amqpx: contructor not byteCode")
+		return retVal, bytesUsed, constructorError(buffer, byteCode)
 	}
 }
 
@@ -378,6 +437,9 @@ func ParseRawBytePrimitive(buffer []byte) (retVal byte, bytesUsed uint32, err er
 	retVal = 0
 	bytesUsed = 0
 	inx := uint32(0)
+	if len(buffer) < 1 {
+		return retVal, bytesUsed, truncatedError(1, 0)
+	}
 
 	retVal = buffer[inx]
 	inx++
@@ -400,10 +462,13 @@ func ParseShortPrimitive(buffer []byte) (retVal int16, bytesUsed uint32, err err
 	bytesUsed = 0
 
 	inx := uint32(0)
+	if len(buffer) < 1 {
+		return retVal, bytesUsed, truncatedError(1, 0)
+	}
 	switch buffer[inx] {
 	case shortCode:
 		if len(buffer) < 3 {
-			return retVal, bytesUsed, errors.New("amqpx: buffer len must be 3 or More for shortCode")
+			return retVal, bytesUsed, truncatedError(3, len(buffer))
 		}
 		inx++
 		retVal = int16(binary.BigEndian.Uint16(buffer[inx:]))
@@ -411,14 +476,14 @@ func ParseShortPrimitive(buffer []byte) (retVal int16, bytesUsed uint32, err err
 		bytesUsed = inx
 		return retVal, bytesUsed, nil
 	default:
-		return retVal, bytesUsed, errors.New("amqpx: contructor not shortCode")
+		return retVal, bytesUsed, constructorError(buffer, shortCode)
 	}
 }
 
 // SerializeIntPrimitive serialized int
 func SerializeIntPrimitive(value int32) (buf []byte) {
 	uvalue := uint32(value)
-	if uvalue <= 0xff {
+	if value >= math.MinInt8 && value <= math.MaxInt8 {
 		buf = make([]byte, 2)
 		buf[0] = intSmallCode
 		buf[1] = byte(uvalue)
@@ -436,10 +501,13 @@ func ParseIntPrimitive(buffer []byte) (retVal int32, bytesUsed uint32, err error
 	bytesUsed = 0
 
 	inx := uint32(0)
+	if len(buffer) < 1 {
+		return retVal, bytesUsed, truncatedError(1, 0)
+	}
 	switch buffer[inx] {
 	case intCode:
 		if len(buffer) < 5 {
-			return retVal, bytesUsed, errors.New("amqpx: buffer len must be 5 or More for intCode")
+			return retVal, bytesUsed, truncatedError(5, len(buffer))
 		}
 		inx++
 		retVal = int32(binary.BigEndian.Uint32(buffer[inx:]))
@@ -448,22 +516,22 @@ func ParseIntPrimitive(buffer []byte) (retVal int32, bytesUsed uint32, err error
 		return retVal, bytesUsed, nil
 	case intSmallCode:
 		if len(buffer) < 2 {
-			return retVal, bytesUsed, errors.New("amqpx: buffer len must be 2 or More for intSmallCode")
+			return retVal, bytesUsed, truncatedError(2, len(buffer))
 		}
 		inx++
-		retVal = int32(buffer[inx])
+		retVal = int32(int8(buffer[inx]))
 		inx++
 		bytesUsed = inx
 		return retVal, bytesUsed, nil
 	default:
-		return retVal, bytesUsed, errors.New("amqpx: contructor not intCode or intSmallCode")
+		return retVal, bytesUsed, constructorError(buffer, intCode, intSmallCode)
 	}
 }
 
 // SerializeLongPrimitive serialized int
 func SerializeLongPrimitive(value int64) (buf []byte) {
 	uvalue := uint64(value)
-	if uvalue <= 0xff {
+	if value >= math.MinInt8 && value <= math.MaxInt8 {
 		buf = make([]byte, 2)
 		buf[0] = longSmallCode
 		buf[1] = byte(uvalue)
@@ -481,10 +549,13 @@ func ParseLongPrimitive(buffer []byte) (retVal int64, bytesUsed uint32, err erro
 	bytesUsed = 0
 
 	inx := uint32(0)
+	if len(buffer) < 1 {
+		return retVal, bytesUsed, truncatedError(1, 0)
+	}
 	switch buffer[inx] {
 	case longCode:
 		if len(buffer) < 9 {
-			return retVal, bytesUsed, errors.New("amqpx: buffer len must be 9 or More for longCode")
+			return retVal, bytesUsed, truncatedError(9, len(buffer))
 		}
 		inx++
 		retVal = int64(binary.BigEndian.Uint64(buffer[inx:]))
@@ -493,25 +564,120 @@ func ParseLongPrimitive(buffer []byte) (retVal int64, bytesUsed uint32, err erro
 		return retVal, bytesUsed, nil
 	case longSmallCode:
 		if len(buffer) < 2 {
-			return retVal, bytesUsed, errors.New("amqpx: buffer len must be 2 or More for intSmallCode")
+			return retVal, bytesUsed, truncatedError(2, len(buffer))
 		}
 		inx++
-		retVal = int64(buffer[inx])
+		retVal = int64(int8(buffer[inx]))
 		inx++
 		bytesUsed = inx
 		return retVal, bytesUsed, nil
 	default:
-		return retVal, bytesUsed, errors.New("amqpx: contructor not longCode or longSmallCode")
+		return retVal, bytesUsed, constructorError(buffer, longCode, longSmallCode)
+	}
+}
+
+// SerializeFloatPrimitive serialized float
+func SerializeFloatPrimitive(value float32) (buf []byte) {
+	buf = make([]byte, szInt32+1)
+	buf[0] = floatCode
+	binary.BigEndian.PutUint32(buf[1:], math.Float32bits(value))
+	return buf
+}
+
+// ParseFloatPrimitive reads an IEEE 754 binary32 Value from buffer
+func ParseFloatPrimitive(buffer []byte) (retVal float32, bytesUsed uint32, err error) {
+	data, bytesUsed, err := parseFixedWidthPrimitive(buffer, floatCode, "floatCode")
+	if err != nil {
+		return 0, bytesUsed, err
+	}
+	return math.Float32frombits(binary.BigEndian.Uint32(data)), bytesUsed, nil
+}
+
+// SerializeDoublePrimitive serialized double
+func SerializeDoublePrimitive(value float64) (buf []byte) {
+	buf = make([]byte, 9)
+	buf[0] = doubleCode
+	binary.BigEndian.PutUint64(buf[1:], math.Float64bits(value))
+	return buf
+}
+
+// ParseDoublePrimitive reads an IEEE 754 binary64 Value from buffer
+func ParseDoublePrimitive(buffer []byte) (retVal float64, bytesUsed uint32, err error) {
+	data, bytesUsed, err := parseFixedWidthPrimitive(buffer, doubleCode, "doubleCode")
+	if err != nil {
+		return 0, bytesUsed, err
 	}
+	return math.Float64frombits(binary.BigEndian.Uint64(data)), bytesUsed, nil
 }
 
-// Skipping
-// ReadFloatPrimitive
-// ReadDoublePrimitive
-// ReadDecimal32Primitive
-// ReadDecimal64Primitive
-// ReadDecimal128Primitive
-// ReadCharPrimitive
+// SerializeDecimal32Primitive serialized decimal32
+func SerializeDecimal32Primitive(value Decimal32) (buf []byte) {
+	return append([]byte{decimal32Code}, value[:]...)
+}
+
+// ParseDecimal32Primitive reads a decimal32 Value from buffer
+func ParseDecimal32Primitive(buffer []byte) (retVal Decimal32, bytesUsed uint32, err error) {
+	data, bytesUsed, err := parseFixedWidthPrimitive(buffer, decimal32Code, "decimal32Code")
+	copy(retVal[:], data)
+	return retVal, bytesUsed, err
+}
+
+// SerializeDecimal64Primitive serialized decimal64
+func SerializeDecimal64Primitive(value Decimal64) (buf []byte) {
+	return append([]byte{decimal64Code}, value[:]...)
+}
+
+// ParseDecimal64Primitive reads a decimal64 Value from buffer
+func ParseDecimal64Primitive(buffer []byte) (retVal Decimal64, bytesUsed uint32, err error) {
+	data, bytesUsed, err := parseFixedWidthPrimitive(buffer, decimal64Code, "decimal64Code")
+	copy(retVal[:], data)
+	return retVal, bytesUsed, err
+}
+
+// SerializeDecimal128Primitive serialized decimal128
+func SerializeDecimal128Primitive(value Decimal128) (buf []byte) {
+	return append([]byte{decimal128Code}, value[:]...)
+}
+
+// ParseDecimal128Primitive reads a decimal128 Value from buffer
+func ParseDecimal128Primitive(buffer []byte) (retVal Decimal128, bytesUsed uint32, err error) {
+	data, bytesUsed, err := parseFixedWidthPrimitive(buffer, decimal128Code, "decimal128Code")
+	copy(retVal[:], data)
+	return retVal, bytesUsed, err
+}
+
+// SerializeCharPrimitive serialized char as UTF-32BE
+func SerializeCharPrimitive(value rune) (buf []byte) {
+	buf = make([]byte, szInt32+1)
+	buf[0] = charCode
+	binary.BigEndian.PutUint32(buf[1:], uint32(value))
+	return buf
+}
+
+// ParseCharPrimitive reads a UTF-32BE encoded character from buffer
+func ParseCharPrimitive(buffer []byte) (retVal rune, bytesUsed uint32, err error) {
+	data, bytesUsed, err := parseFixedWidthPrimitive(buffer, charCode, "charCode")
+	if err != nil {
+		return 0, bytesUsed, err
+	}
+	retVal = rune(binary.BigEndian.Uint32(data))
+	if !utf8.ValidRune(retVal) {
+		return 0, 0, invalidValueError("charCode holds invalid unicode code point 0x%x", uint32(retVal))
+	}
+	return retVal, bytesUsed, nil
+}
+
+// parseFixedWidthPrimitive checks the constructor and returns the data following it
+func parseFixedWidthPrimitive(buffer []byte, constructor byte, name string) (data []byte, bytesUsed uint32, err error) {
+	width, _ := fixedWidth(constructor)
+	if len(buffer) < 1 || buffer[0] != constructor {
+		return nil, 0, constructorError(buffer, constructor)
+	}
+	if uint32(len(buffer)) < width+1 {
+		return nil, 0, truncatedError(int(width)+1, len(buffer))
+	}
+	return buffer[1 : width+1], width + 1, nil
+}
 
 // SerializeTimestampPrimitive serialized timestamp
 func SerializeTimestampPrimitive(value Timestamp) (buf []byte) {
@@ -527,10 +693,13 @@ func ParseTimestampPrimitive(buffer []byte) (retVal Timestamp, bytesUsed uint32,
 	bytesUsed = 0
 
 	inx := uint32(0)
+	if len(buffer) < 1 {
+		return retVal, bytesUsed, truncatedError(1, 0)
+	}
 	switch buffer[inx] {
 	case timestampCode:
 		if len(buffer) < 9 {
-			return retVal, bytesUsed, errors.New("amqpx: buffer len must be 9 or More for timestampCode")
+			return retVal, bytesUsed, truncatedError(9, len(buffer))
 		}
 		inx++
 		retVal = Timestamp(binary.BigEndian.Uint64(buffer[inx:]))
@@ -539,23 +708,37 @@ func ParseTimestampPrimitive(buffer []byte) (retVal Timestamp, bytesUsed uint32,
 		return retVal, bytesUsed, nil
 
 	default:
-		return retVal, bytesUsed, errors.New("amqpx: contructor not timestampCode")
+		return retVal, bytesUsed, constructorError(buffer, timestampCode)
 	}
 }
 
-// Skipping
-// ReadUuidPrimitive
+// SerializeUuidPrimitive serialized uuid
+func SerializeUuidPrimitive(value UUID) (buf []byte) {
+	return append([]byte{uuidCode}, value[:]...)
+}
+
+// ParseUuidPrimitive reads a uuid from buffer
+func ParseUuidPrimitive(buffer []byte) (retVal UUID, bytesUsed uint32, err error) {
+	data, bytesUsed, err := parseFixedWidthPrimitive(buffer, uuidCode, "uuidCode")
+	copy(retVal[:], data)
+	return retVal, bytesUsed, err
+}
 
 // SerializeBinaryPrimitive serialized binary
 func SerializeBinaryPrimitive(value []byte) (buf []byte) {
+	inx := 1
 	if len(value) <= 0xff {
-		buf = make([]byte, len(value)+1)
+		buf = make([]byte, len(value)+2)
 		buf[0] = binary8Code
+		buf[1] = byte(len(value))
+		inx += szByte
 	} else {
-		buf = make([]byte, len(value)+1)
+		buf = make([]byte, len(value)+5)
 		buf[0] = binary32Code
+		binary.BigEndian.PutUint32(buf[1:], uint32(len(value)))
+		inx += szInt32
 	}
-	copy(buf[1:], value)
+	copy(buf[inx:], value)
 	return buf
 }
 
@@ -564,16 +747,15 @@ func ParseBinaryPrimitive(buffer []byte) (retVal []byte, bytesUsed uint32, err e
 	retVal = []byte{}
 	bytesUsed = 0
 
-	if len(buffer) < 2 {
-		return retVal, bytesUsed, errors.New("amqpx: buffer must include at least constructor, Length byte, and one character")
-	}
-
 	inx := uint32(0)
 	binaryLen := uint32(0)
+	if len(buffer) < 1 {
+		return retVal, bytesUsed, truncatedError(1, 0)
+	}
 	switch buffer[inx] {
 	case binary8Code:
 		if len(buffer) < 2 {
-			return retVal, bytesUsed, errors.New("amqpx: buffer len must be 2 or More for binary8Code")
+			return retVal, bytesUsed, truncatedError(2, len(buffer))
 		}
 		inx++
 		binaryLen = uint32(buffer[inx])
@@ -581,18 +763,18 @@ func ParseBinaryPrimitive(buffer []byte) (retVal []byte, bytesUsed uint32, err e
 		bytesUsed = inx
 	case binary32Code:
 		if len(buffer) < 5 {
-			return retVal, bytesUsed, errors.New("amqpx: buffer len must be 5 or More for binary32Code")
+			return retVal, bytesUsed, truncatedError(5, len(buffer))
 		}
 		inx++
 		binaryLen = binary.BigEndian.Uint32(buffer[inx:])
 		inx += 4
 		bytesUsed = inx
 	default:
-		return retVal, bytesUsed, errors.New("amqpx: contructor not binary8Code or binary32Code")
+		return retVal, bytesUsed, constructorError(buffer, binary8Code, binary32Code)
 	}
 
-	if uint32(len(buffer)) < (binaryLen + inx) {
-		return retVal, bytesUsed, errors.New("amqpx: buffer not large enough To contain binary")
+	if uint64(len(buffer)) < uint64(binaryLen)+uint64(inx) {
+		return retVal, bytesUsed, truncatedError(int(uint64(binaryLen)+uint64(inx)), len(buffer))
 	}
 
 	retVal = buffer[inx:(binaryLen + inx)]
@@ -604,15 +786,16 @@ func ParseBinaryPrimitive(buffer []byte) (retVal []byte, bytesUsed uint32, err e
 func SerializeStringPrimitive(value string) (buf []byte) {
 	length := len(value)
 	inx := uint32(0)
-	if length == 0 {
-		buf = make([]byte, 1)
-		buf[0] = nullCode
-	} else if length < 0xff {
-		buf = make([]byte, length+1)
+	if length <= 0xff {
+		buf = make([]byte, length+2)
+		buf[inx] = string8Code
+		inx++
 		buf[inx] = byte(length)
 		inx++
 	} else {
-		buf = make([]byte, length+szInt32)
+		buf = make([]byte, length+szInt32+1)
+		buf[inx] = string32Code
+		inx++
 		binary.BigEndian.PutUint32(buf[inx:], uint32(length))
 		inx += szInt32
 	}
@@ -628,28 +811,31 @@ func ParseStringPrimitive(buffer []byte) (retString string, bytesUsed uint32, er
 
 	stringLen := uint32(0)
 	inx := uint32(0)
+	if len(buffer) < 1 {
+		return retString, bytesUsed, truncatedError(1, 0)
+	}
 	switch buffer[inx] {
 	case string8Code:
-		if len(buffer) < 3 {
-			return "", bytesUsed, errors.New("amqpx: buffer len must be 2 or More for string8Code")
+		if len(buffer) < 2 {
+			return "", bytesUsed, truncatedError(2, len(buffer))
 		}
 		inx++
 		stringLen = uint32(buffer[inx])
 		inx++
 	case string32Code:
 		if len(buffer) < 5 {
-			return "", bytesUsed, errors.New("amqpx: buffer len must be 5 or More for string32Code")
+			return "", bytesUsed, truncatedError(5, len(buffer))
 		}
 		inx++
 		stringLen = binary.BigEndian.Uint32(buffer[inx:])
 		inx += 4
 
 	default:
-		return retString, bytesUsed, errors.New("amqpx: contructor not string8Code or string32Code")
+		return retString, bytesUsed, constructorError(buffer, string8Code, string32Code)
 	}
 
-	if uint32(len(buffer)) < (stringLen + inx) {
-		return retString, bytesUsed, errors.New("amqpx: buffer not large enough To contain string")
+	if uint64(len(buffer)) < uint64(stringLen)+uint64(inx) {
+		return retString, bytesUsed, truncatedError(int(uint64(stringLen)+uint64(inx)), len(buffer))
 	}
 	retString = string(buffer[inx:(stringLen + inx)])
 	bytesUsed = inx + uint32(len(retString))
@@ -668,10 +854,10 @@ func SerializeSymbolPrimitive(symValue Symbol) (buf []byte) {
 		copy(buf[2:], symValue)
 	} else {
 		buf = make([]byte, length+5)
-		buf[0] = symbol8Code
+		buf[0] = symbol32Code
 		binary.BigEndian.PutUint32(buf[1:], uint32(length))
-		// copy Value into buf[6:]
-		copy(buf[6:], symValue)
+		// copy Value into buf[5:]
+		copy(buf[5:], symValue)
 	}
 	return buf
 }
@@ -683,28 +869,31 @@ func ParseSymbolPrimitive(buffer []byte) (retString Symbol, bytesUsed uint32, er
 
 	stringLen := uint32(0)
 	inx := uint32(0)
+	if len(buffer) < 1 {
+		return retString, bytesUsed, truncatedError(1, 0)
+	}
 	switch buffer[inx] {
 	case symbol8Code:
-		if len(buffer) < 3 {
-			return "", bytesUsed, errors.New("amqpx: buffer len must be 2 or More for symbol8Code")
+		if len(buffer) < 2 {
+			return "", bytesUsed, truncatedError(2, len(buffer))
 		}
 		inx++
 		stringLen = uint32(buffer[inx])
 		inx++
 	case symbol32Code:
 		if len(buffer) < 5 {
-			return "", bytesUsed, errors.New("amqpx: buffer len must be 5 or More for symbol32Code")
+			return "", bytesUsed, truncatedError(5, len(buffer))
 		}
 		inx++
 		stringLen = binary.BigEndian.Uint32(buffer[inx:])
 		inx += 4
 
 	default:
-		return retString, bytesUsed, errors.New("amqpx: contructor not symbol8Code or symbol32Code")
+		return retString, bytesUsed, constructorError(buffer, symbol8Code, symbol32Code)
 	}
 
-	if uint32(len(buffer)) < (stringLen + inx) {
-		return retString, bytesUsed, errors.New("amqpx: buffer not large enough To contain string")
+	if uint64(len(buffer)) < uint64(stringLen)+uint64(inx) {
+		return retString, bytesUsed, truncatedError(int(uint64(stringLen)+uint64(inx)), len(buffer))
 	}
 	retString = Symbol(buffer[inx:(stringLen + inx)])
 	bytesUsed = inx + uint32(len(retString))
@@ -713,20 +902,38 @@ func ParseSymbolPrimitive(buffer []byte) (retString Symbol, bytesUsed uint32, er
 
 // SerializeList a List from given buffers
 func SerializeList(bufs ...[]byte) (retBuf []byte) {
-	listSize := 0
+	if len(bufs) == 0 {
+		return []byte{list0Code}
+	}
+	return serializeCompound(list8Code, list32Code, bufs)
+}
+
+// serializeCompound writes constructor, size, count and the given buffers.
+// The 8bit form is used when both size and count fit into a byte
+func serializeCompound(code8 byte, code32 byte, bufs [][]byte) (retBuf []byte) {
+	itemsSize := 0
 	for _, buf := range bufs {
-		listSize += len(buf)
+		itemsSize += len(buf)
 	}
-	retBuf = make([]byte, listSize+1) // plus 1 for list code
 
-	if listSize == 0 {
-		retBuf[0] = list0Code
-	} else if listSize < 0xff {
-		retBuf[0] = list8Code
+	inx := 0
+	if itemsSize+szByte <= 0xff && len(bufs) <= 0xff {
+		retBuf = make([]byte, 1+2*szByte+itemsSize)
+		retBuf[inx] = code8
+		inx++
+		retBuf[inx] = byte(itemsSize + szByte)
+		inx++
+		retBuf[inx] = byte(len(bufs))
+		inx++
 	} else {
-		retBuf[0] = list32Code
+		retBuf = make([]byte, 1+2*szInt32+itemsSize)
+		retBuf[inx] = code32
+		inx++
+		binary.BigEndian.PutUint32(retBuf[inx:], uint32(itemsSize+szInt32))
+		inx += szInt32
+		binary.BigEndian.PutUint32(retBuf[inx:], uint32(len(bufs)))
+		inx += szInt32
 	}
-	inx := 1
 	for _, buf := range bufs {
 		copy(retBuf[inx:], buf)
 		inx += len(buf)
@@ -734,18 +941,47 @@ func SerializeList(bufs ...[]byte) (retBuf []byte) {
 	return retBuf
 }
 
+// listEnd returns the offset after the list at the start of buffer, its header and
+// the size of the list, an error when the list does not fit in buffer
+func listEnd(buffer []byte) (end uint32, err error) {
+	size, _, inxFirstItem, bytesUsed, err := ParseListPrimitive(buffer)
+	if err != nil {
+		return 0, err
+	}
+	if inxFirstItem == 0 {
+		// list0 is the constructor alone
+		return bytesUsed, nil
+	}
+	// size counts the count field and the items, the count field is 1 byte in list8 and 4 in list32
+	countWidth := uint64(inxFirstItem-1) / 2
+	if uint64(size) < countWidth {
+		return 0, invalidValueError("list size %d is less than its count", size)
+	}
+	end64 := uint64(inxFirstItem) + uint64(size) - countWidth
+	if end64 > uint64(len(buffer)) {
+		return 0, truncatedError(int(end64), len(buffer))
+	}
+	return uint32(end64), nil
+}
+
 // ParseListPrimitive reads a compound primitive from buffer.
 func ParseListPrimitive(buffer []byte) (size uint32, countItems uint32, inxFirstItem uint32, bytesUsed uint32, err error) {
 	size, countItems, inxFirstItem, bytesUsed = 0, 0, 0, 0
 	err = nil
 
 	inx := uint32(0)
+	if len(buffer) < 1 {
+		return size, countItems, inxFirstItem, bytesUsed, truncatedError(1, 0)
+	}
 	switch buffer[inx] {
 	case list0Code:
 		inx++
 		bytesUsed = inx
 		return size, countItems, inxFirstItem, bytesUsed, nil
 	case list8Code:
+		if len(buffer) < 3 {
+			return size, countItems, inxFirstItem, bytesUsed, truncatedError(3, len(buffer))
+		}
 		inx++
 		size = uint32(buffer[inx])
 		inx++
@@ -755,6 +991,9 @@ func ParseListPrimitive(buffer []byte) (size uint32, countItems uint32, inxFirst
 		bytesUsed = inx
 		return size, countItems, inxFirstItem, bytesUsed, nil
 	case list32Code:
+		if len(buffer) < 9 {
+			return size, countItems, inxFirstItem, bytesUsed, truncatedError(9, len(buffer))
+		}
 		inx++
 		size = binary.BigEndian.Uint32(buffer[inx:])
 		inx += 4
@@ -764,13 +1003,266 @@ func ParseListPrimitive(buffer []byte) (size uint32, countItems uint32, inxFirst
 		bytesUsed = inx
 		return size, countItems, inxFirstItem, bytesUsed, nil
 	default:
-		return size, countItems, inxFirstItem, bytesUsed, errors.New("amqpx: contructor not list0Code, list8Code or list32Code")
+		return size, countItems, inxFirstItem, bytesUsed, constructorError(buffer, list0Code, list8Code, list32Code)
 	}
 }
 
-// Skipping
-// ReadMapPrimitive
-// ReadArrayPrimitive
+// Map is an AMQP map, keys and values can be any AMQP Value
+type Map map[interface{}]interface{}
+
+// Fields is an AMQP map keyed by symbols (spec type "fields")
+type Fields map[Symbol]interface{}
+
+// Annotations is an AMQP map keyed by symbols or ulongs (spec type "annotations")
+type Annotations map[interface{}]interface{}
+
+// SerializeMap a Map from given buffers, alternating key and Value
+func SerializeMap(bufs ...[]byte) (retBuf []byte) {
+	return serializeCompound(map8Code, map32Code, bufs)
+}
+
+// serializeMapPairs serializes keys and values. Pairs are ordered by their
+// encoded key so the same map always serializes To the same bytes
+func serializeMapPairs(keys []interface{}, values []interface{}) (buf []byte, err error) {
+	type pair struct{ key, value []byte }
+	pairs := make([]pair, len(keys))
+	for i := range keys {
+		if pairs[i].key, err = SerializeAny(keys[i]); err != nil {
+			return nil, errors.New(err.Error() + "\nSerializeMap() failed serializing key")
+		}
+		if pairs[i].value, err = SerializeAny(values[i]); err != nil {
+			return nil, errors.New(err.Error() + "\nSerializeMap() failed serializing Value")
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return bytes.Compare(pairs[i].key, pairs[j].key) < 0 })
+
+	bufs := make([][]byte, 0, 2*len(pairs))
+	for _, p := range pairs {
+		bufs = append(bufs, p.key, p.value)
+	}
+	return SerializeMap(bufs...), nil
+}
+
+// parseMapItems reads a map, null is read as a nil map
+func parseMapItems(buffer []byte) (retVal map[interface{}]interface{}, bytesUsed uint32, err error) {
+	if len(buffer) < 1 {
+		return nil, 0, truncatedError(1, len(buffer))
+	}
+	switch buffer[0] {
+	case nullCode:
+		return nil, 1, nil
+	case map8Code, map32Code:
+	default:
+		return nil, 0, constructorError(buffer, map8Code, map32Code)
+	}
+
+	value, bytesUsed, err := ParseAny(buffer)
+	if err != nil {
+		return nil, 0, err
+	}
+	return value.(map[interface{}]interface{}), bytesUsed, nil
+}
+
+// SerializeMapPrimitive serializes a Map, a nil Map is serialized as null
+func SerializeMapPrimitive(value Map) (buf []byte, err error) {
+	if value == nil {
+		return SerializeNullPrimitive(), nil
+	}
+	keys := make([]interface{}, 0, len(value))
+	values := make([]interface{}, 0, len(value))
+	for k, v := range value {
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+	return serializeMapPairs(keys, values)
+}
+
+// ParseMapPrimitive reads a map, null is read as a nil Map
+func ParseMapPrimitive(buffer []byte) (retVal Map, bytesUsed uint32, err error) {
+	m, bytesUsed, err := parseMapItems(buffer)
+	return Map(m), bytesUsed, err
+}
+
+// SerializeFieldsPrimitive serializes Fields, nil Fields are serialized as null
+func SerializeFieldsPrimitive(value Fields) (buf []byte, err error) {
+	if value == nil {
+		return SerializeNullPrimitive(), nil
+	}
+	keys := make([]interface{}, 0, len(value))
+	values := make([]interface{}, 0, len(value))
+	for k, v := range value {
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+	return serializeMapPairs(keys, values)
+}
+
+// ParseFieldsPrimitive reads a symbol keyed map, null is read as nil Fields
+func ParseFieldsPrimitive(buffer []byte) (retVal Fields, bytesUsed uint32, err error) {
+	m, bytesUsed, err := parseMapItems(buffer)
+	if err != nil || m == nil {
+		return nil, bytesUsed, err
+	}
+	retVal = make(Fields, len(m))
+	for k, v := range m {
+		key, ok := k.(Symbol)
+		if !ok {
+			return nil, 0, invalidValueError("fields key must be a symbol, got %T", k)
+		}
+		retVal[key] = v
+	}
+	return retVal, bytesUsed, nil
+}
+
+// SerializeAnnotationsPrimitive serializes Annotations, nil Annotations are serialized as null
+func SerializeAnnotationsPrimitive(value Annotations) (buf []byte, err error) {
+	if value == nil {
+		return SerializeNullPrimitive(), nil
+	}
+	keys := make([]interface{}, 0, len(value))
+	values := make([]interface{}, 0, len(value))
+	for k, v := range value {
+		switch k.(type) {
+		case Symbol, uint64:
+		default:
+			return nil, fmt.Errorf("amqpx: annotations key must be a symbol or ulong, got %T", k)
+		}
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+	return serializeMapPairs(keys, values)
+}
+
+// ParseAnnotationsPrimitive reads a symbol or ulong keyed map, null is read as nil Annotations
+func ParseAnnotationsPrimitive(buffer []byte) (retVal Annotations, bytesUsed uint32, err error) {
+	m, bytesUsed, err := parseMapItems(buffer)
+	if err != nil || m == nil {
+		return nil, bytesUsed, err
+	}
+	for k := range m {
+		switch k.(type) {
+		case Symbol, uint64:
+		default:
+			return nil, 0, invalidValueError("annotations key must be a symbol or ulong, got %T", k)
+		}
+	}
+	return Annotations(m), bytesUsed, nil
+}
+
+// SerializeArray an Array from the given element constructor and element buffers.
+// Element buffers must not include the constructor
+func SerializeArray(constructor byte, bufs ...[]byte) (retBuf []byte) {
+	itemsSize := 0
+	for _, buf := range bufs {
+		itemsSize += len(buf)
+	}
+
+	inx := 0
+	// size counts the count field, the element constructor and the elements
+	if itemsSize+2*szByte <= 0xff && len(bufs) <= 0xff {
+		retBuf = make([]byte, 1+3*szByte+itemsSize)
+		retBuf[inx] = array8Code
+		inx++
+		retBuf[inx] = byte(itemsSize + 2*szByte)
+		inx++
+		retBuf[inx] = byte(len(bufs))
+		inx++
+	} else {
+		retBuf = make([]byte, 1+2*szInt32+szByte+itemsSize)
+		retBuf[inx] = array32Code
+		inx++
+		binary.BigEndian.PutUint32(retBuf[inx:], uint32(itemsSize+szInt32+szByte))
+		inx += szInt32
+		binary.BigEndian.PutUint32(retBuf[inx:], uint32(len(bufs)))
+		inx += szInt32
+	}
+	retBuf[inx] = constructor
+	inx++
+	for _, buf := range bufs {
+		copy(retBuf[inx:], buf)
+		inx += len(buf)
+	}
+	return retBuf
+}
+
+// ParseArrayPrimitive reads an array, null is read as a nil slice
+func ParseArrayPrimitive(buffer []byte) (retVal []interface{}, bytesUsed uint32, err error) {
+	if len(buffer) < 1 {
+		return nil, 0, truncatedError(1, len(buffer))
+	}
+	switch buffer[0] {
+	case nullCode:
+		return nil, 1, nil
+	case array8Code, array32Code:
+	default:
+		return nil, 0, constructorError(buffer, array8Code, array32Code)
+	}
+
+	value, bytesUsed, err := ParseAny(buffer)
+	if err != nil {
+		return nil, 0, err
+	}
+	return value.([]interface{}), bytesUsed, nil
+}
+
+// SerializeSymbolArrayPrimitive serializes symbols as an array, a nil slice is serialized as null
+func SerializeSymbolArrayPrimitive(value []Symbol) (buf []byte) {
+	if value == nil {
+		return SerializeNullPrimitive()
+	}
+
+	constructor := symbol8Code
+	for _, sym := range value {
+		if len(sym) > 0xff {
+			constructor = symbol32Code
+			break
+		}
+	}
+
+	bufs := make([][]byte, len(value))
+	for i, sym := range value {
+		if constructor == symbol8Code {
+			bufs[i] = make([]byte, szByte+len(sym))
+			bufs[i][0] = byte(len(sym))
+			copy(bufs[i][szByte:], sym)
+		} else {
+			bufs[i] = make([]byte, szInt32+len(sym))
+			binary.BigEndian.PutUint32(bufs[i], uint32(len(sym)))
+			copy(bufs[i][szInt32:], sym)
+		}
+	}
+	return SerializeArray(constructor, bufs...)
+}
+
+// ParseSymbolArrayPrimitive reads a multiple symbol field. By spec a single
+// symbol may be sent instead of an array holding one symbol. null is read as a nil slice
+func ParseSymbolArrayPrimitive(buffer []byte) (retVal []Symbol, bytesUsed uint32, err error) {
+	if len(buffer) < 1 {
+		return nil, 0, truncatedError(1, len(buffer))
+	}
+	switch buffer[0] {
+	case symbol8Code, symbol32Code:
+		sym, bytesUsed, err := ParseSymbolPrimitive(buffer)
+		if err != nil {
+			return nil, 0, err
+		}
+		return []Symbol{sym}, bytesUsed, nil
+	}
+
+	items, bytesUsed, err := ParseArrayPrimitive(buffer)
+	if err != nil || items == nil {
+		return nil, bytesUsed, err
+	}
+	retVal = make([]Symbol, len(items))
+	for i, item := range items {
+		sym, ok := item.(Symbol)
+		if !ok {
+			return nil, 0, invalidValueError("array element must be a symbol, got %T", item)
+		}
+		retVal[i] = sym
+	}
+	return retVal, bytesUsed, nil
+}
 
 ///////////////
 
@@ -796,7 +1288,7 @@ func ParseDeliveryNumberPrimitive(buffer []byte) (retVal DeliveryNumber, bytesUs
 	return DeliveryNumber(seqNumber), bytesUsed, err
 }
 
-// SerializeBlockType serializes a blocktype
+// SerializeBlockType serializes the described type constructor and the smallulong code
 func SerializeBlockType() (retBuf []byte) {
 	retBuf = make([]byte, 2)
 	retBuf[0] = 0x00
@@ -804,31 +1296,30 @@ func SerializeBlockType() (retBuf []byte) {
 	return retBuf
 }
 
-// ParseBlockType reads a boolean code or a boolean Value. Returns true or false
+// ParseBlockType reads the descriptor of a performative or message section.
+// Numeric (any ulong encoding) and registered symbolic descriptors are accepted
 func ParseBlockType(buffer []byte) (retVal byte, bytesUsed uint32, err error) {
 	retVal = 0x00
 	bytesUsed = 0
 	if len(buffer) < 3 {
 		log.Error("*** ParseBlockType: buffer < 3")
-		return retVal, bytesUsed, errors.New("amqpx: Not enough bytes in buffer To determine BlockType")
+		return retVal, bytesUsed, truncatedError(3, len(buffer))
 	}
 
-	inx := uint32(0)
-	if buffer[inx] != 0x00 {
-		errStr := fmt.Sprintf("amqpx: Expected First byte To be zero, got 0x%x", buffer[inx])
-		return retVal, bytesUsed, errors.New(errStr)
+	descriptor, inx, err := ParseDescriptor(buffer)
+	if err != nil {
+		return retVal, bytesUsed, err
 	}
-	inx++
 
-	if buffer[inx] != 0x53 {
-		errStr := fmt.Sprintf("amqpx: Expected First byte To be 0x53, got 0x%x", buffer[inx])
-		return retVal, bytesUsed, errors.New(errStr)
+	code, ok := DescriptorCode(descriptor)
+	if !ok {
+		return retVal, bytesUsed, invalidValueError("unknown descriptor %v", descriptor)
+	}
+	if code > 0xff {
+		return retVal, bytesUsed, invalidValueError("descriptor 0x%x is not a performative or section", code)
 	}
-	inx++
 
-	retVal = buffer[inx]
-	inx++
+	retVal = byte(code)
 	bytesUsed = inx
-
 	return retVal, bytesUsed, nil
 }
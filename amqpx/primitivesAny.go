@@ -0,0 +1,320 @@
+package amqpx
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+)
+
+// BinaryKey is a binary map key. Binary can not be used as a go map key
+// so binary keys (e.g. delivery tags in unsettled maps) are decoded as BinaryKey
+type BinaryKey string
+
+// ParseAny reads any AMQP encoded Value from buffer and returns it as a native go Value:
+//
+//	null                    -> nil
+//	boolean                 -> bool
+//	ubyte, ushort, uint     -> uint8, uint16, uint32
+//	ulong                   -> uint64
+//	byte, short, int, long  -> int8, int16, int32, int64
+//	float, double           -> float32, float64
+//	decimal32/64/128        -> Decimal32, Decimal64, Decimal128
+//	char                    -> rune
+//	timestamp               -> Timestamp
+//	uuid                    -> UUID
+//	binary                  -> Binary
+//	string                  -> string
+//	symbol                  -> Symbol
+//	list, array             -> []interface{}
+//	map                     -> map[interface{}]interface{} (binary keys as BinaryKey)
+//	described               -> registered go type (see RegisterDescribedType) or DescribedType
+func ParseAny(buffer []byte) (retVal interface{}, bytesUsed uint32, err error) {
+	if len(buffer) < 1 {
+		return nil, 0, truncatedError(1, 0)
+	}
+
+	if buffer[0] == 0x00 {
+		return ParseDescribedPrimitive(buffer)
+	}
+
+	retVal, bytesUsed, err = parseAnyBody(buffer[0], buffer[1:])
+	if err != nil {
+		return nil, 0, decodeErrorAt(err, 1, "")
+	}
+	return retVal, bytesUsed + 1, nil
+}
+
+// fixedWidth returns the width of the data following a fixed-width constructor
+func fixedWidth(constructor byte) (width uint32, ok bool) {
+	switch constructor {
+	case nullCode, booleanTrue, booleanFalse, uint0Code, ulong0Code, list0Code:
+		return 0, true
+	case booleanCode, ubyteCode, uintSmallCode, ulongSmallCode, byteCode, intSmallCode, longSmallCode:
+		return 1, true
+	case ushortCode, shortCode:
+		return 2, true
+	case uintCode, intCode, floatCode, decimal32Code, charCode:
+		return 4, true
+	case ulongCode, longCode, doubleCode, decimal64Code, timestampCode:
+		return 8, true
+	case decimal128Code, uuidCode:
+		return 16, true
+	}
+	return 0, false
+}
+
+// parseAnyBody reads the Value that follows the given constructor.
+// buffer starts right after the constructor, bytesUsed and error offsets do not include the constructor
+func parseAnyBody(constructor byte, buffer []byte) (retVal interface{}, bytesUsed uint32, err error) {
+	if width, ok := fixedWidth(constructor); ok {
+		if uint32(len(buffer)) < width {
+			return nil, 0, truncatedError(int(width), len(buffer))
+		}
+		retVal, err = parseFixedWidth(constructor, buffer[:width])
+		return retVal, width, err
+	}
+
+	switch constructor {
+	case binary8Code, binary32Code, string8Code, string32Code, symbol8Code, symbol32Code:
+		data, advanceInx, err := readVariableWidth(constructor, buffer)
+		if err != nil {
+			return nil, 0, err
+		}
+		switch constructor {
+		case binary8Code, binary32Code:
+			return Binary(data), advanceInx, nil
+		case string8Code, string32Code:
+			return string(data), advanceInx, nil
+		default:
+			return Symbol(data), advanceInx, nil
+		}
+	case list8Code, list32Code:
+		count, inx, end, err := readCompoundHeader(constructor, buffer)
+		if err != nil {
+			return nil, 0, err
+		}
+		list := make([]interface{}, 0, count)
+		for i := uint32(0); i < count; i++ {
+			item, advanceInx, err := ParseAny(buffer[inx:end])
+			if err != nil {
+				return nil, 0, decodeErrorAt(err, inx, "")
+			}
+			list = append(list, item)
+			inx += advanceInx
+		}
+		return list, end, nil
+	case map8Code, map32Code:
+		count, inx, end, err := readCompoundHeader(constructor, buffer)
+		if err != nil {
+			return nil, 0, err
+		}
+		if count%2 != 0 {
+			return nil, 0, invalidValueError("map must contain an even number of items")
+		}
+		m := make(map[interface{}]interface{}, count/2)
+		for i := uint32(0); i < count; i += 2 {
+			key, advanceInx, err := ParseAny(buffer[inx:end])
+			if err != nil {
+				return nil, 0, decodeErrorAt(err, inx, "")
+			}
+			inx += advanceInx
+			value, advanceInx, err := ParseAny(buffer[inx:end])
+			if err != nil {
+				return nil, 0, decodeErrorAt(err, inx, "")
+			}
+			inx += advanceInx
+
+			if binaryKey, ok := key.(Binary); ok {
+				key = BinaryKey(binaryKey)
+			}
+			if key != nil && !reflect.TypeOf(key).Comparable() {
+				return nil, 0, invalidValueError("map key of type %T can not be used as a go map key", key)
+			}
+			m[key] = value
+		}
+		return m, end, nil
+	case array8Code, array32Code:
+		return parseAnyArray(constructor, buffer)
+	}
+
+	return nil, 0, invalidValueError("unknown constructor 0x%x", constructor)
+}
+
+// parseFixedWidth converts the data of a fixed-width constructor
+func parseFixedWidth(constructor byte, data []byte) (retVal interface{}, err error) {
+	switch constructor {
+	case nullCode:
+		return nil, nil
+	case booleanTrue:
+		return true, nil
+	case booleanFalse:
+		return false, nil
+	case booleanCode:
+		if data[0] > booleanCodeTrue {
+			return nil, invalidValueError("booleanCode expected false(0x00) or true(0x01) got :0x%x", data[0])
+		}
+		return data[0] == booleanCodeTrue, nil
+	case ubyteCode:
+		return data[0], nil
+	case ushortCode:
+		return binary.BigEndian.Uint16(data), nil
+	case uintCode:
+		return binary.BigEndian.Uint32(data), nil
+	case uintSmallCode:
+		return uint32(data[0]), nil
+	case uint0Code:
+		return uint32(0), nil
+	case ulongCode:
+		return binary.BigEndian.Uint64(data), nil
+	case ulongSmallCode:
+		return uint64(data[0]), nil
+	case ulong0Code:
+		return uint64(0), nil
+	case byteCode:
+		return int8(data[0]), nil
+	case shortCode:
+		return int16(binary.BigEndian.Uint16(data)), nil
+	case intCode:
+		return int32(binary.BigEndian.Uint32(data)), nil
+	case intSmallCode:
+		return int32(int8(data[0])), nil
+	case longCode:
+		return int64(binary.BigEndian.Uint64(data)), nil
+	case longSmallCode:
+		return int64(int8(data[0])), nil
+	case floatCode:
+		return math.Float32frombits(binary.BigEndian.Uint32(data)), nil
+	case doubleCode:
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	case decimal32Code:
+		var d Decimal32
+		copy(d[:], data)
+		return d, nil
+	case decimal64Code:
+		var d Decimal64
+		copy(d[:], data)
+		return d, nil
+	case decimal128Code:
+		var d Decimal128
+		copy(d[:], data)
+		return d, nil
+	case charCode:
+		return rune(binary.BigEndian.Uint32(data)), nil
+	case timestampCode:
+		return Timestamp(binary.BigEndian.Uint64(data)), nil
+	case uuidCode:
+		var u UUID
+		copy(u[:], data)
+		return u, nil
+	case list0Code:
+		return []interface{}{}, nil
+	}
+	return nil, invalidValueError("constructor 0x%x is not fixed-width", constructor)
+}
+
+// readVariableWidth reads the length prefixed data of a binary, string or symbol
+func readVariableWidth(constructor byte, buffer []byte) (data []byte, bytesUsed uint32, err error) {
+	inx := uint32(0)
+	length := uint32(0)
+	if constructor&0xf0 == 0xa0 {
+		if len(buffer) < 1 {
+			return nil, 0, truncatedError(1, len(buffer))
+		}
+		length = uint32(buffer[inx])
+		inx++
+	} else {
+		if len(buffer) < szInt32 {
+			return nil, 0, truncatedError(szInt32, len(buffer))
+		}
+		length = binary.BigEndian.Uint32(buffer[inx:])
+		inx += szInt32
+	}
+
+	if uint64(len(buffer)) < uint64(inx)+uint64(length) {
+		return nil, 0, truncatedError(int(uint64(inx)+uint64(length)), len(buffer))
+	}
+	return buffer[inx : inx+length], inx + length, nil
+}
+
+// readCompoundHeader reads the size and count of a list, map or array.
+// inx is the index of the first item and end is the index after the last item
+func readCompoundHeader(constructor byte, buffer []byte) (count uint32, inx uint32, end uint32, err error) {
+	size := uint32(0)
+	width := uint32(szInt32)
+	switch constructor {
+	case list8Code, map8Code, array8Code:
+		width = szByte
+		if len(buffer) < 2 {
+			return 0, 0, 0, truncatedError(2, len(buffer))
+		}
+		size = uint32(buffer[0])
+		count = uint32(buffer[1])
+	default:
+		if len(buffer) < 8 {
+			return 0, 0, 0, truncatedError(8, len(buffer))
+		}
+		size = binary.BigEndian.Uint32(buffer)
+		count = binary.BigEndian.Uint32(buffer[szInt32:])
+	}
+
+	// size counts the bytes after the size field, including the count field
+	if size < width || uint64(len(buffer)) < uint64(width)+uint64(size) {
+		return 0, 0, 0, truncatedError(int(uint64(width)+uint64(size)), len(buffer))
+	}
+	inx = 2 * width
+	end = width + size
+	// every item needs at least one byte
+	if count > end-inx {
+		return 0, 0, 0, invalidValueError("compound count %d exceeds its size %d", count, size)
+	}
+	return count, inx, end, nil
+}
+
+// parseAnyArray reads an array: a single element constructor followed by count element bodies
+func parseAnyArray(constructor byte, buffer []byte) (retVal interface{}, bytesUsed uint32, err error) {
+	count, inx, end, err := readCompoundHeader(constructor, buffer)
+	if err != nil {
+		return nil, 0, err
+	}
+	if inx >= end {
+		return nil, 0, invalidValueError("array is missing its element constructor")
+	}
+
+	// element constructor, possibly described
+	var descriptor interface{}
+	elementConstructor := buffer[inx]
+	inx++
+	if elementConstructor == 0x00 {
+		var advanceInx uint32
+		descriptor, advanceInx, err = ParseAny(buffer[inx:end])
+		if err != nil {
+			return nil, 0, decodeErrorAt(err, inx, "")
+		}
+		inx += advanceInx
+		if inx >= end {
+			return nil, 0, invalidValueError("array is missing its element constructor")
+		}
+		elementConstructor = buffer[inx]
+		inx++
+	}
+
+	array := make([]interface{}, 0, count)
+	for i := uint32(0); i < count; i++ {
+		item, advanceInx, err := parseAnyBody(elementConstructor, buffer[inx:end])
+		if err != nil {
+			return nil, 0, decodeErrorAt(err, inx, "")
+		}
+		if descriptor != nil {
+			item = DescribedType{Descriptor: descriptor, Value: item}
+		}
+		array = append(array, item)
+		inx += advanceInx
+	}
+	return array, end, nil
+}
+
+// SerializeAny serializes a native go Value, it is the reverse of ParseAny.
+// Named types (e.g. Handle) are serialized by their underlying kind, structs as by Marshal
+func SerializeAny(value interface{}) (buf []byte, err error) {
+	return Marshal(value)
+}
@@ -0,0 +1,186 @@
+package amqpx
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Decimal32 is the raw IEEE 754-2008 decimal32 encoding (Binary Integer Decimal)
+type Decimal32 [4]byte
+
+// Decimal64 is the raw IEEE 754-2008 decimal64 encoding (Binary Integer Decimal)
+type Decimal64 [8]byte
+
+// Decimal128 is the raw IEEE 754-2008 decimal128 encoding (Binary Integer Decimal)
+type Decimal128 [16]byte
+
+// Decimal is a decoded decimal Value: (-1)^Negative * Coefficient * 10^Exponent.
+// Infinite and NaN Values ignore Coefficient and Exponent
+type Decimal struct {
+	Negative    bool     `json:"negative"`
+	Coefficient *big.Int `json:"coefficient"`
+	Exponent    int32    `json:"exponent"`
+	Infinite    bool     `json:"infinite,omitempty"`
+	NaN         bool     `json:"nan,omitempty"`
+}
+
+// bidFormat describes one of the BID interchange formats
+type bidFormat struct {
+	bits      uint // total width
+	expBits   uint // width of the biased exponent
+	coefBits  uint // width of the coefficient in the small form
+	bias      int64
+	maxDigits int64
+}
+
+var (
+	bid32  = bidFormat{bits: 32, expBits: 8, coefBits: 23, bias: 101, maxDigits: 7}
+	bid64  = bidFormat{bits: 64, expBits: 10, coefBits: 53, bias: 398, maxDigits: 16}
+	bid128 = bidFormat{bits: 128, expBits: 14, coefBits: 113, bias: 6176, maxDigits: 34}
+)
+
+func bitMask(width uint) *big.Int {
+	mask := new(big.Int).Lsh(big.NewInt(1), width)
+	return mask.Sub(mask, big.NewInt(1))
+}
+
+// decode splits raw BID bits into sign, exponent and coefficient
+func (format bidFormat) decode(data []byte) (d Decimal) {
+	raw := new(big.Int).SetBytes(data)
+	d.Negative = raw.Bit(int(format.bits-1)) == 1
+	d.Coefficient = new(big.Int)
+
+	field := func(shift uint, width uint) *big.Int {
+		v := new(big.Int).Rsh(raw, shift)
+		return v.And(v, bitMask(width))
+	}
+
+	combination := field(format.bits-6, 5).Uint64()
+	switch {
+	case combination == 0x1e:
+		d.Infinite = true
+		return d
+	case combination == 0x1f:
+		d.NaN = true
+		return d
+	case combination>>3 == 0x3:
+		// large form: implicit 0b100 prefix on the coefficient
+		d.Exponent = int32(field(format.coefBits-2, format.expBits).Int64() - format.bias)
+		d.Coefficient.Or(field(0, format.coefBits-2), new(big.Int).Lsh(big.NewInt(4), format.coefBits-2))
+	default:
+		d.Exponent = int32(field(format.coefBits, format.expBits).Int64() - format.bias)
+		d.Coefficient = field(0, format.coefBits)
+	}
+
+	// non canonical coefficients are treated as zero
+	maxCoefficient := new(big.Int).Exp(big.NewInt(10), big.NewInt(format.maxDigits), nil)
+	if d.Coefficient.Cmp(maxCoefficient) >= 0 {
+		d.Coefficient.SetInt64(0)
+	}
+	return d
+}
+
+// encode writes the Decimal into len(data) bytes of BID bits
+func (format bidFormat) encode(d Decimal, data []byte) error {
+	raw := new(big.Int)
+	if d.Negative {
+		raw.SetBit(raw, int(format.bits-1), 1)
+	}
+
+	switch {
+	case d.NaN:
+		raw.Or(raw, new(big.Int).Lsh(big.NewInt(0x1f), format.bits-6))
+	case d.Infinite:
+		raw.Or(raw, new(big.Int).Lsh(big.NewInt(0x1e), format.bits-6))
+	default:
+		coefficient := d.Coefficient
+		if coefficient == nil {
+			coefficient = new(big.Int)
+		}
+		maxCoefficient := new(big.Int).Exp(big.NewInt(10), big.NewInt(format.maxDigits), nil)
+		if coefficient.Sign() < 0 || coefficient.Cmp(maxCoefficient) >= 0 {
+			return fmt.Errorf("amqpx: decimal coefficient must have at most %d digits", format.maxDigits)
+		}
+		biased := int64(d.Exponent) + format.bias
+		// the two top exponent bits can not both be set
+		if biased < 0 || biased >= 3<<(format.expBits-2) {
+			return fmt.Errorf("amqpx: decimal exponent %d out of range", d.Exponent)
+		}
+
+		if coefficient.BitLen() <= int(format.coefBits) {
+			raw.Or(raw, new(big.Int).Lsh(big.NewInt(biased), format.coefBits))
+			raw.Or(raw, coefficient)
+		} else {
+			raw.Or(raw, new(big.Int).Lsh(big.NewInt(3), format.bits-3))
+			raw.Or(raw, new(big.Int).Lsh(big.NewInt(biased), format.coefBits-2))
+			raw.Or(raw, new(big.Int).And(coefficient, bitMask(format.coefBits-2)))
+		}
+	}
+
+	raw.FillBytes(data)
+	return nil
+}
+
+// Decimal decodes the BID bits
+func (d Decimal32) Decimal() Decimal {
+	return bid32.decode(d[:])
+}
+
+// Decimal decodes the BID bits
+func (d Decimal64) Decimal() Decimal {
+	return bid64.decode(d[:])
+}
+
+// Decimal decodes the BID bits
+func (d Decimal128) Decimal() Decimal {
+	return bid128.decode(d[:])
+}
+
+// NewDecimal32 encodes a Decimal with up To 7 digits
+func NewDecimal32(d Decimal) (retVal Decimal32, err error) {
+	err = bid32.encode(d, retVal[:])
+	return retVal, err
+}
+
+// NewDecimal64 encodes a Decimal with up To 16 digits
+func NewDecimal64(d Decimal) (retVal Decimal64, err error) {
+	err = bid64.encode(d, retVal[:])
+	return retVal, err
+}
+
+// NewDecimal128 encodes a Decimal with up To 34 digits
+func NewDecimal128(d Decimal) (retVal Decimal128, err error) {
+	err = bid128.encode(d, retVal[:])
+	return retVal, err
+}
+
+// String formats the Decimal as <coefficient>E<exponent>, e.g. -12345E-2
+func (d Decimal) String() string {
+	sign := ""
+	if d.Negative {
+		sign = "-"
+	}
+	switch {
+	case d.NaN:
+		return "NaN"
+	case d.Infinite:
+		return sign + "Inf"
+	case d.Coefficient == nil:
+		return sign + "0E" + fmt.Sprint(d.Exponent)
+	}
+	return fmt.Sprintf("%s%sE%d", sign, d.Coefficient.String(), d.Exponent)
+}
+
+// Float64 returns the nearest float64 To the Decimal
+func (d Decimal) Float64() (float64, error) {
+	if d.NaN || d.Infinite {
+		return 0, errors.New("amqpx: decimal is not a finite number")
+	}
+	value, _, err := big.ParseFloat(d.String(), 10, 64, big.ToNearestEven)
+	if err != nil {
+		return 0, err
+	}
+	f, _ := value.Float64()
+	return f, nil
+}
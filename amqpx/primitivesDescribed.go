@@ -0,0 +1,210 @@
+package amqpx
+
+import (
+	"sync"
+)
+
+// DescribedType is a Value annotated with a descriptor.
+// Descriptor is either an uint64 (numeric descriptor) or a Symbol (symbolic descriptor)
+type DescribedType struct {
+	Descriptor interface{} `json:"descriptor"`
+	Value      interface{} `json:"value"`
+}
+
+// DescribedParser reads the Value that follows a descriptor and returns the matching go type
+type DescribedParser func(buffer []byte) (retVal interface{}, bytesUsed uint32, err error)
+
+type describedEntry struct {
+	code  uint64
+	name  Symbol
+	parse DescribedParser
+}
+
+var describedRegistry = struct {
+	sync.RWMutex
+	byCode map[uint64]*describedEntry
+	byName map[Symbol]*describedEntry
+}{
+	byCode: map[uint64]*describedEntry{},
+	byName: map[Symbol]*describedEntry{},
+}
+
+// RegisterDescribedType registers the parser for Values described by either the
+// numeric code or the symbolic name. A later registration replaces an earlier one
+func RegisterDescribedType(code uint64, name Symbol, parse DescribedParser) {
+	entry := &describedEntry{code: code, name: name, parse: parse}
+	describedRegistry.Lock()
+	defer describedRegistry.Unlock()
+	describedRegistry.byCode[code] = entry
+	if name != "" {
+		describedRegistry.byName[name] = entry
+	}
+}
+
+// unregisterDescribedType drops the registration of code and name
+func unregisterDescribedType(code uint64, name Symbol) {
+	describedRegistry.Lock()
+	defer describedRegistry.Unlock()
+	delete(describedRegistry.byCode, code)
+	delete(describedRegistry.byName, name)
+}
+
+func lookupDescriptor(descriptor interface{}) *describedEntry {
+	describedRegistry.RLock()
+	defer describedRegistry.RUnlock()
+	switch d := descriptor.(type) {
+	case uint64:
+		return describedRegistry.byCode[d]
+	case Symbol:
+		return describedRegistry.byName[d]
+	}
+	return nil
+}
+
+// DescriptorCode returns the numeric code of a descriptor. Symbolic descriptors
+// are resolved through the registered types
+func DescriptorCode(descriptor interface{}) (code uint64, ok bool) {
+	if code, ok = descriptor.(uint64); ok {
+		return code, true
+	}
+	if entry := lookupDescriptor(descriptor); entry != nil {
+		return entry.code, true
+	}
+	return 0, false
+}
+
+// DescriptorName returns the symbolic name of a descriptor, numeric descriptors
+// are resolved through the registered types
+func DescriptorName(descriptor interface{}) (name Symbol, ok bool) {
+	if name, ok = descriptor.(Symbol); ok {
+		return name, true
+	}
+	if entry := lookupDescriptor(descriptor); entry != nil && entry.name != "" {
+		return entry.name, true
+	}
+	return "", false
+}
+
+// SerializeDescriptor serializes the described type constructor with a numeric descriptor
+func SerializeDescriptor(code uint64) (buf []byte) {
+	return append([]byte{0x00}, SerializeUlongPrimitive(code)...)
+}
+
+// SerializeDescribedPrimitive serializes a descriptor followed by an already serialized Value
+func SerializeDescribedPrimitive(code uint64, value []byte) (buf []byte) {
+	return append(SerializeDescriptor(code), value...)
+}
+
+// ParseDescriptor reads the described type constructor and the descriptor.
+// Returns an uint64 for numeric descriptors and a Symbol for symbolic descriptors
+func ParseDescriptor(buffer []byte) (descriptor interface{}, bytesUsed uint32, err error) {
+	if len(buffer) < 1 || buffer[0] != 0x00 {
+		return nil, 0, constructorError(buffer, 0x00)
+	}
+	if len(buffer) < 2 {
+		return nil, 0, truncatedError(2, len(buffer))
+	}
+
+	inx := uint32(1)
+	switch buffer[inx] {
+	case ulongCode, ulongSmallCode, ulong0Code:
+		code, advanceInx, err := ParseUlongPrimitive(buffer[inx:])
+		if err != nil {
+			return nil, 0, decodeErrorAt(err, inx, "")
+		}
+		return code, inx + advanceInx, nil
+	case symbol8Code, symbol32Code:
+		name, advanceInx, err := ParseSymbolPrimitive(buffer[inx:])
+		if err != nil {
+			return nil, 0, decodeErrorAt(err, inx, "")
+		}
+		return name, inx + advanceInx, nil
+	}
+	return nil, 0, decodeErrorAt(constructorError(buffer[inx:], ulongCode, ulongSmallCode, ulong0Code, symbol8Code, symbol32Code), inx, "")
+}
+
+// ParseDescribedPrimitive reads a described Value. Registered descriptors are
+// returned as their registered go type, unknown descriptors as a DescribedType
+func ParseDescribedPrimitive(buffer []byte) (retVal interface{}, bytesUsed uint32, err error) {
+	descriptor, inx, err := ParseDescriptor(buffer)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if entry := lookupDescriptor(descriptor); entry != nil {
+		value, advanceInx, err := entry.parse(buffer[inx:])
+		if err != nil {
+			return nil, 0, decodeErrorAt(err, inx, "")
+		}
+		return value, inx + advanceInx, nil
+	}
+
+	value, advanceInx, err := ParseAny(buffer[inx:])
+	if err != nil {
+		return nil, 0, decodeErrorAt(err, inx, "")
+	}
+	return DescribedType{Descriptor: descriptor, Value: value}, inx + advanceInx, nil
+}
+
+func init() {
+	RegisterDescribedType(uint64(PerfOpen), "amqp:open:list", func(buffer []byte) (interface{}, uint32, error) {
+		return ParsePerformativeOpen(buffer)
+	})
+	RegisterDescribedType(uint64(PerfBegin), "amqp:begin:list", func(buffer []byte) (interface{}, uint32, error) {
+		return ParsePerformativeBegin(buffer)
+	})
+	RegisterDescribedType(uint64(PerfAttach), "amqp:attach:list", func(buffer []byte) (interface{}, uint32, error) {
+		return ParsePerformativeAttach(buffer)
+	})
+	RegisterDescribedType(uint64(PerfFlow), "amqp:flow:list", func(buffer []byte) (interface{}, uint32, error) {
+		return ParsePerformativeFlow(buffer)
+	})
+	RegisterDescribedType(uint64(PerfTransfer), "amqp:transfer:list", func(buffer []byte) (interface{}, uint32, error) {
+		return ParsePerformativeTransfer(buffer)
+	})
+	RegisterDescribedType(uint64(PerfDisposition), "amqp:disposition:list", func(buffer []byte) (interface{}, uint32, error) {
+		return ParsePerformativeDisposition(buffer)
+	})
+	RegisterDescribedType(uint64(PerfDetach), "amqp:detach:list", func(buffer []byte) (interface{}, uint32, error) {
+		return ParsePerformativeDetach(buffer)
+	})
+	RegisterDescribedType(uint64(PerfEnd), "amqp:end:list", func(buffer []byte) (interface{}, uint32, error) {
+		return ParsePerformativeEnd(buffer)
+	})
+	RegisterDescribedType(uint64(PerfClose), "amqp:close:list", func(buffer []byte) (interface{}, uint32, error) {
+		return ParsePerformativeClose(buffer)
+	})
+
+	RegisterDescribedType(descriptorError, "amqp:error:list", func(buffer []byte) (interface{}, uint32, error) {
+		return ParseError(buffer)
+	})
+
+	registerDeliveryState("amqp:received:list", Received{})
+	registerDeliveryState(OutcomeAccepted, Accepted{})
+	registerDeliveryState(OutcomeRejected, Rejected{})
+	registerDeliveryState(OutcomeReleased, Released{})
+	registerDeliveryState(OutcomeModified, Modified{})
+
+	RegisterDescribedType(descriptorSource, "amqp:source:list", func(buffer []byte) (interface{}, uint32, error) {
+		return ReadSourceList(buffer)
+	})
+	RegisterDescribedType(descriptorTarget, "amqp:target:list", func(buffer []byte) (interface{}, uint32, error) {
+		return ReadTargetList(buffer)
+	})
+	RegisterDescribedType(descriptorCoordinator, "amqp:coordinator:list", func(buffer []byte) (interface{}, uint32, error) {
+		return ReadCoordinatorList(buffer)
+	})
+
+	registerSaslPerformative("amqp:sasl-mechanisms:list", &SaslMechanisms{})
+	registerSaslPerformative("amqp:sasl-init:list", &SaslInit{})
+	registerSaslPerformative("amqp:sasl-challenge:list", &SaslChallenge{})
+	registerSaslPerformative("amqp:sasl-response:list", &SaslResponse{})
+	registerSaslPerformative("amqp:sasl-outcome:list", &SaslOutcome{})
+
+	RegisterDescribedType(uint64(PerfHeader), "amqp:header:list", func(buffer []byte) (interface{}, uint32, error) {
+		return ParseMessageHeader(buffer)
+	})
+	RegisterDescribedType(uint64(PerfProperties), "amqp:properties:list", func(buffer []byte) (interface{}, uint32, error) {
+		return ParseMessageProperties(buffer)
+	})
+}
@@ -0,0 +1,96 @@
+package amqpx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Causes wrapped by a DecodeError, test for them with errors.Is
+var (
+	// ErrTruncated is the cause when the buffer ends inside a Value
+	ErrTruncated = errors.New("amqpx: buffer truncated")
+	// ErrUnexpectedConstructor is the cause when a constructor does not encode the type being read
+	ErrUnexpectedConstructor = errors.New("amqpx: unexpected constructor")
+	// ErrInvalidValue is the cause when an encoded Value is not valid for its type
+	ErrInvalidValue = errors.New("amqpx: invalid Value")
+)
+
+// DecodeError is returned by the Parse functions when a buffer can not be decoded
+type DecodeError struct {
+	// Offset of the failing Value from the start of the buffer given To the outermost Parse function
+	Offset uint32
+	// Field is the path of the failing field, e.g. attach.source.address
+	Field string
+	// Expected holds the accepted constructors when Actual was not one of them
+	Expected []byte
+	// Actual is the constructor found at Offset
+	Actual byte
+	// Err is the cause, ErrTruncated, ErrUnexpectedConstructor, ErrInvalidValue or a wrapped error
+	Err error
+}
+
+// Error returns the field, offset and cause of the decode error
+func (e *DecodeError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("amqpx: decoding")
+	if e.Field != "" {
+		sb.WriteString(" " + e.Field)
+	}
+	fmt.Fprintf(&sb, " at offset %d", e.Offset)
+	if len(e.Expected) > 0 {
+		codes := make([]string, len(e.Expected))
+		for i, code := range e.Expected {
+			codes[i] = fmt.Sprintf("0x%02x", code)
+		}
+		fmt.Fprintf(&sb, ": expected constructor %s, got 0x%02x", strings.Join(codes, " or "), e.Actual)
+	} else if e.Err != nil {
+		sb.WriteString(": " + strings.TrimPrefix(e.Err.Error(), "amqpx: "))
+	}
+	return sb.String()
+}
+
+// Unwrap returns the cause of the decode error
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// truncatedError is returned when a Value needs more bytes than the buffer holds
+func truncatedError(need int, have int) error {
+	return &DecodeError{Err: fmt.Errorf("%w, need %d bytes, have %d", ErrTruncated, need, have)}
+}
+
+// constructorError is returned when the constructor at the start of buffer is not one of expected
+func constructorError(buffer []byte, expected ...byte) error {
+	if len(buffer) < 1 {
+		return truncatedError(1, 0)
+	}
+	return &DecodeError{Expected: expected, Actual: buffer[0], Err: ErrUnexpectedConstructor}
+}
+
+// invalidValueError is returned when an encoded Value is not valid for its type
+func invalidValueError(format string, args ...interface{}) error {
+	return &DecodeError{Err: fmt.Errorf("%w: %s", ErrInvalidValue, fmt.Sprintf(format, args...))}
+}
+
+// decodeErrorAt places an error returned for buffer[offset:] at offset within field.
+// Composite parsers name their own fields (e.g. source.address), the parser of the
+// enclosing composite adds its name in front (attach.source.address)
+func decodeErrorAt(err error, offset uint32, field string) error {
+	if err == nil {
+		return nil
+	}
+	decodeErr, ok := err.(*DecodeError)
+	if !ok {
+		decodeErr = &DecodeError{Err: err}
+	}
+	decodeErr.Offset += offset
+	switch {
+	case field == "":
+	case decodeErr.Field == "":
+		decodeErr.Field = field
+	default:
+		decodeErr.Field = field + "." + decodeErr.Field
+	}
+	return decodeErr
+}
@@ -0,0 +1,47 @@
+package amqpx
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+)
+
+// UUID is a 16 byte universally unique identifier (RFC-4122)
+type UUID [16]byte
+
+// NewUUID returns a random (version 4) UUID
+func NewUUID() (uuid UUID, err error) {
+	if _, err = rand.Read(uuid[:]); err != nil {
+		return uuid, err
+	}
+	uuid[6] = (uuid[6] & 0x0f) | 0x40 // version 4
+	uuid[8] = (uuid[8] & 0x3f) | 0x80 // variant RFC-4122
+	return uuid, nil
+}
+
+// ParseUUID reads the canonical 8-4-4-4-12 hex form of a UUID
+func ParseUUID(value string) (uuid UUID, err error) {
+	if len(value) != 36 || value[8] != '-' || value[13] != '-' || value[18] != '-' || value[23] != '-' {
+		return uuid, errors.New("amqpx: uuid must be in the form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx")
+	}
+	digits := value[0:8] + value[9:13] + value[14:18] + value[19:23] + value[24:]
+	if _, err = hex.Decode(uuid[:], []byte(digits)); err != nil {
+		return uuid, errors.New(err.Error() + "\nParseUUID() failed decoding hex digits")
+	}
+	return uuid, nil
+}
+
+// String returns the canonical 8-4-4-4-12 hex form of the UUID
+func (uuid UUID) String() string {
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], uuid[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], uuid[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], uuid[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], uuid[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], uuid[10:])
+	return string(buf)
+}
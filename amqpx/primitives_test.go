@@ -1,6 +1,13 @@
 package amqpx
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -135,3 +142,734 @@ func TestReadListPrimitive(t *testing.T) {
 		// }
 	}
 }
+
+func TestParseAny(t *testing.T) {
+	tests := []struct {
+		name     string
+		buffer   []byte
+		expected interface{}
+	}{
+		{"null", []byte{0x40}, nil},
+		{"true", []byte{0x41}, true},
+		{"booleanCode false", []byte{0x56, 0x00}, false},
+		{"ubyte", []byte{0x50, 0x07}, uint8(7)},
+		{"ushort", []byte{0x60, 0x7f, 0xff}, uint16(0x7fff)},
+		{"uint0", []byte{0x43}, uint32(0)},
+		{"smalluint", []byte{0x52, 0xc8}, uint32(200)},
+		{"uint", []byte{0x70, 0x7f, 0xff, 0xff, 0xff}, uint32(0x7fffffff)},
+		{"smallulong", []byte{0x53, 0x28}, uint64(0x28)},
+		{"smallint", []byte{0x54, 0xff}, int32(-1)},
+		{"long", []byte{0x81, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe}, int64(-2)},
+		{"char", []byte{0x73, 0x00, 0x00, 0x00, 0x41}, 'A'},
+		{"timestamp", []byte{0x83, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00}, Timestamp(256)},
+		{"string8", []byte{0xa1, 0x02, 0x68, 0x69}, "hi"},
+		{"symbol8", []byte{0xa3, 0x02, 0x68, 0x69}, Symbol("hi")},
+		{"binary8", []byte{0xa0, 0x02, 0x01, 0x02}, Binary{0x01, 0x02}},
+		{"list0", []byte{0x45}, []interface{}{}},
+		{"list8", []byte{0xc0, 0x04, 0x02, 0x41, 0x52, 0x05}, []interface{}{true, uint32(5)}},
+		{"map8", []byte{0xc1, 0x06, 0x02, 0xa3, 0x01, 0x6b, 0x50, 0x01},
+			map[interface{}]interface{}{Symbol("k"): uint8(1)}},
+		{"map8 binary key", []byte{0xc1, 0x05, 0x02, 0xa0, 0x01, 0x01, 0x41},
+			map[interface{}]interface{}{BinaryKey([]byte{0x01}): true}},
+		{"array8 symbols", []byte{0xe0, 0x07, 0x02, 0xa3, 0x01, 0x61, 0x02, 0x62, 0x63},
+			[]interface{}{Symbol("a"), Symbol("bc")}},
+		{"described accepted", []byte{0x00, 0x53, 0x24, 0x45}, Accepted{}},
+		{"described unknown", []byte{0x00, 0x53, 0x99, 0x45},
+			DescribedType{Descriptor: uint64(0x99), Value: []interface{}{}}},
+		{"described symbolic", []byte{0x00, 0xa3, 0x01, 0x78, 0x40},
+			DescribedType{Descriptor: Symbol("x"), Value: nil}},
+	}
+
+	for _, test := range tests {
+		val, bytesUsed, err := ParseAny(test.buffer)
+		if err != nil {
+			t.Errorf("ParseAny (%s) was incorrect, expected no errors got: %s", test.name, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(val, test.expected) {
+			t.Errorf("ParseAny (%s) was incorrect, \n\texpected: \"%#v\" \n\tgot:\"%#v\"", test.name, test.expected, val)
+		}
+		if bytesUsed != uint32(len(test.buffer)) {
+			t.Errorf("ParseAny (%s) was incorrect bytesUsed, \n\texpected: \"%d\" \n\tgot:\"%d\"", test.name, len(test.buffer), bytesUsed)
+		}
+	}
+}
+
+func TestParseAnyTruncated(t *testing.T) {
+	buffers := [][]byte{
+		{},
+		{0x70, 0x00},
+		{0xa1, 0x05, 0x68},
+		{0xc0, 0x04, 0x02, 0x41},
+		{0xd0, 0x00, 0x00, 0x00, 0x04, 0xff, 0xff, 0xff, 0xff},
+		{0xc1, 0x03, 0x01, 0x41, 0x41},
+		{0xe0, 0x01, 0x00},
+		{0x00, 0x53},
+		{0xff},
+	}
+	for _, buffer := range buffers {
+		if _, _, err := ParseAny(buffer); err == nil {
+			t.Errorf("ParseAny (% x) was incorrect, expected an error", buffer)
+		}
+	}
+}
+
+func TestParseTrailingEmptyString(t *testing.T) {
+	// an empty string or symbol at the end of the buffer is the constructor and a zero length
+	str, bytesUsed, err := ParseStringPrimitive([]byte{0xa1, 0x00})
+	if err != nil {
+		t.Fatalf("%s\nParseStringPrimitive was incorrect, expected no errors", err.Error())
+	}
+	if str != "" || bytesUsed != 2 {
+		t.Errorf("ParseStringPrimitive was incorrect, \n\texpected: \"\"\" 2\" \n\tgot:\"%q %d\"", str, bytesUsed)
+	}
+
+	sym, bytesUsed, err := ParseSymbolPrimitive([]byte{0xa3, 0x00})
+	if err != nil {
+		t.Fatalf("%s\nParseSymbolPrimitive was incorrect, expected no errors", err.Error())
+	}
+	if sym != "" || bytesUsed != 2 {
+		t.Errorf("ParseSymbolPrimitive was incorrect, \n\texpected: \"\"\" 2\" \n\tgot:\"%q %d\"", sym, bytesUsed)
+	}
+
+	if _, _, err = ParseStringPrimitive([]byte{0xa1}); !errors.Is(err, ErrTruncated) {
+		t.Errorf("ParseStringPrimitive was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", ErrTruncated, err)
+	}
+	if _, _, err = ParseSymbolPrimitive([]byte{0xa3}); !errors.Is(err, ErrTruncated) {
+		t.Errorf("ParseSymbolPrimitive was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", ErrTruncated, err)
+	}
+}
+
+func TestParseAnyOpenList(t *testing.T) {
+	valBuf := []byte{0xd0, 0x00, 0x00, 0x00, 0x42, 0x00, 0x00, 0x00, 0x0a, 0xa1, 0x24, 0x63, 0x62, 0x64, 0x35, 0x65, 0x36, 0x33, 0x63, 0x2d, 0x33, 0x66, 0x34, 0x34, 0x2d, 0x34, 0x39, 0x32, 0x66, 0x2d, 0x38, 0x30, 0x37, 0x62, 0x2d, 0x65, 0x37, 0x35, 0x33, 0x36, 0x64, 0x39, 0x64, 0x62, 0x35, 0x30, 0x65, 0xa1, 0x08, 0x74, 0x65, 0x73, 0x74, 0x68, 0x6f, 0x73, 0x74, 0x40, 0x60, 0x7f, 0xff, 0x70, 0x00, 0x00, 0x75, 0x30, 0x40, 0x40, 0x40, 0x40, 0x40}
+
+	val, bytesUsed, err := ParseAny(valBuf)
+	if err != nil {
+		t.Fatalf("ParseAny was incorrect, expected no errors got: %s", err.Error())
+	}
+	list, ok := val.([]interface{})
+	if !ok || len(list) != 10 {
+		t.Fatalf("ParseAny was incorrect, expected a list of 10 items got: %#v", val)
+	}
+	if list[1] != "testhost" || list[3] != uint16(0x7fff) || list[4] != uint32(30000) {
+		t.Errorf("ParseAny was incorrect list items: %#v", list)
+	}
+	if bytesUsed != uint32(len(valBuf)) {
+		t.Errorf("ParseAny was incorrect bytesUsed, \n\texpected: \"%d\" \n\tgot:\"%d\"", len(valBuf), bytesUsed)
+	}
+}
+
+func TestMapPrimitive(t *testing.T) {
+	value := Map{
+		Symbol("product"):        "amqpx",
+		uint64(7):                true,
+		BinaryKey([]byte{0x01}):  Binary{0x02, 0x03},
+		"list":                   []interface{}{uint32(1), Symbol("two")},
+		Symbol("nested-map-key"): map[interface{}]interface{}{"a": int32(-1)},
+	}
+
+	buf, err := SerializeMapPrimitive(value)
+	if err != nil {
+		t.Fatalf("SerializeMapPrimitive was incorrect, expected no errors got: %s", err.Error())
+	}
+	parsed, bytesUsed, err := ParseMapPrimitive(buf)
+	if err != nil {
+		t.Fatalf("ParseMapPrimitive was incorrect, expected no errors got: %s", err.Error())
+	}
+	if bytesUsed != uint32(len(buf)) {
+		t.Errorf("ParseMapPrimitive was incorrect bytesUsed, \n\texpected: \"%d\" \n\tgot:\"%d\"", len(buf), bytesUsed)
+	}
+	if !reflect.DeepEqual(value, parsed) {
+		t.Errorf("ParseMapPrimitive was incorrect, \n\texpected: \"%#v\" \n\tgot:\"%#v\"", value, parsed)
+	}
+
+	again, _ := SerializeMapPrimitive(parsed)
+	if !reflect.DeepEqual(buf, again) {
+		t.Errorf("SerializeMapPrimitive was not deterministic, \n\texpected: \"% x\" \n\tgot:\"% x\"", buf, again)
+	}
+
+	parsed, bytesUsed, err = ParseMapPrimitive([]byte{0x40})
+	if err != nil || parsed != nil || bytesUsed != 1 {
+		t.Errorf("ParseMapPrimitive (null) was incorrect, got: %v %d %v", parsed, bytesUsed, err)
+	}
+}
+
+func TestFieldsAndAnnotationsPrimitive(t *testing.T) {
+	fields := Fields{"product": "amqpx", "version": "1.0.0", "max-sessions": uint16(4)}
+	buf, err := SerializeFieldsPrimitive(fields)
+	if err != nil {
+		t.Fatalf("SerializeFieldsPrimitive was incorrect, expected no errors got: %s", err.Error())
+	}
+	parsedFields, _, err := ParseFieldsPrimitive(buf)
+	if err != nil || !reflect.DeepEqual(fields, parsedFields) {
+		t.Errorf("ParseFieldsPrimitive was incorrect, \n\texpected: \"%#v\" \n\tgot:\"%#v\" %v", fields, parsedFields, err)
+	}
+
+	annotations := Annotations{Symbol("x-opt-partition-key"): "p1", uint64(0x1234): int64(-3)}
+	buf, err = SerializeAnnotationsPrimitive(annotations)
+	if err != nil {
+		t.Fatalf("SerializeAnnotationsPrimitive was incorrect, expected no errors got: %s", err.Error())
+	}
+	parsedAnnotations, _, err := ParseAnnotationsPrimitive(buf)
+	if err != nil || !reflect.DeepEqual(annotations, parsedAnnotations) {
+		t.Errorf("ParseAnnotationsPrimitive was incorrect, \n\texpected: \"%#v\" \n\tgot:\"%#v\" %v", annotations, parsedAnnotations, err)
+	}
+
+	// string keys are neither fields nor annotations
+	buf, _ = SerializeMapPrimitive(Map{"key": true})
+	if _, _, err = ParseFieldsPrimitive(buf); err == nil {
+		t.Errorf("ParseFieldsPrimitive was incorrect, expected an error for a string key")
+	}
+	if _, _, err = ParseAnnotationsPrimitive(buf); err == nil {
+		t.Errorf("ParseAnnotationsPrimitive was incorrect, expected an error for a string key")
+	}
+}
+
+func TestSymbolArrayPrimitive(t *testing.T) {
+	capabilities := []Symbol{CapabilityAnonymousRelay, "DELAYED_DELIVERY"}
+	buf := SerializeSymbolArrayPrimitive(capabilities)
+	expected := []byte{0xe0, 0x23, 0x02, 0xa3, 0x0f, 'A', 'N', 'O', 'N', 'Y', 'M', 'O', 'U', 'S', '-', 'R', 'E', 'L', 'A', 'Y',
+		0x10, 'D', 'E', 'L', 'A', 'Y', 'E', 'D', '_', 'D', 'E', 'L', 'I', 'V', 'E', 'R', 'Y'}
+	if !reflect.DeepEqual(buf, expected) {
+		t.Errorf("SerializeSymbolArrayPrimitive was incorrect, \n\texpected: \"% x\" \n\tgot:\"% x\"", expected, buf)
+	}
+
+	parsed, bytesUsed, err := ParseSymbolArrayPrimitive(buf)
+	if err != nil || bytesUsed != uint32(len(buf)) || !reflect.DeepEqual(capabilities, parsed) {
+		t.Errorf("ParseSymbolArrayPrimitive was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", capabilities, parsed, err)
+	}
+
+	// a single symbol is accepted for a multiple field
+	parsed, _, err = ParseSymbolArrayPrimitive(SerializeSymbolPrimitive(CapabilityAnonymousRelay))
+	if err != nil || !reflect.DeepEqual([]Symbol{CapabilityAnonymousRelay}, parsed) {
+		t.Errorf("ParseSymbolArrayPrimitive (single) was incorrect, got:\"%v\" %v", parsed, err)
+	}
+
+	long := []Symbol{Symbol(strings.Repeat("x", 300))}
+	parsed, _, err = ParseSymbolArrayPrimitive(SerializeSymbolArrayPrimitive(long))
+	if err != nil || !reflect.DeepEqual(long, parsed) {
+		t.Errorf("ParseSymbolArrayPrimitive (sym32) was incorrect, %v", err)
+	}
+
+	if _, _, err = ParseSymbolArrayPrimitive(SerializeArray(uintSmallCode, []byte{0x01})); err == nil {
+		t.Errorf("ParseSymbolArrayPrimitive was incorrect, expected an error for a uint array")
+	}
+}
+
+func TestFloatAndDoublePrimitive(t *testing.T) {
+	for _, value := range []float32{0, 1.5, -3.25e10, float32(math.Inf(1))} {
+		parsed, bytesUsed, err := ParseFloatPrimitive(SerializeFloatPrimitive(value))
+		if err != nil || bytesUsed != 5 || parsed != value {
+			t.Errorf("ParseFloatPrimitive was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", value, parsed, err)
+		}
+	}
+	for _, value := range []float64{0, 21.5, -1.7976931348623157e308, math.SmallestNonzeroFloat64} {
+		parsed, bytesUsed, err := ParseDoublePrimitive(SerializeDoublePrimitive(value))
+		if err != nil || bytesUsed != 9 || parsed != value {
+			t.Errorf("ParseDoublePrimitive was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", value, parsed, err)
+		}
+	}
+
+	parsed, _, err := ParseDoublePrimitive(SerializeDoublePrimitive(math.NaN()))
+	if err != nil || !math.IsNaN(parsed) {
+		t.Errorf("ParseDoublePrimitive (NaN) was incorrect, got:\"%v\" %v", parsed, err)
+	}
+
+	// telemetry sample: 0x82 with the double 21.5
+	value, _, err := ParseAny([]byte{0x82, 0x40, 0x35, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00})
+	if err != nil || value != float64(21.5) {
+		t.Errorf("ParseAny (double) was incorrect, got:\"%#v\" %v", value, err)
+	}
+	if _, _, err = ParseDoublePrimitive([]byte{0x82, 0x40}); err == nil {
+		t.Errorf("ParseDoublePrimitive was incorrect, expected an error for a short buffer")
+	}
+	if _, _, err = ParseFloatPrimitive(SerializeDoublePrimitive(1)); err == nil {
+		t.Errorf("ParseFloatPrimitive was incorrect, expected an error for doubleCode")
+	}
+}
+
+func TestDecimalPrimitive(t *testing.T) {
+	decimals := []Decimal{
+		{Coefficient: big.NewInt(12345), Exponent: -2},
+		{Negative: true, Coefficient: big.NewInt(9999999), Exponent: 90},
+		{Coefficient: big.NewInt(0), Exponent: 0},
+		{Infinite: true, Negative: true},
+	}
+	for _, d := range decimals {
+		d32, err := NewDecimal32(d)
+		if err != nil {
+			t.Errorf("NewDecimal32 (%s) was incorrect, expected no errors got: %s", d, err.Error())
+			continue
+		}
+		parsed, bytesUsed, err := ParseDecimal32Primitive(SerializeDecimal32Primitive(d32))
+		if err != nil || bytesUsed != 5 || parsed.Decimal().String() != d.String() {
+			t.Errorf("ParseDecimal32Primitive was incorrect, \n\texpected: \"%s\" \n\tgot:\"%s\" %v", d, parsed.Decimal(), err)
+		}
+	}
+
+	big34, _ := new(big.Int).SetString("9999999999999999999999999999999999", 10)
+	d128, err := NewDecimal128(Decimal{Coefficient: big34, Exponent: -6176})
+	if err != nil {
+		t.Fatalf("NewDecimal128 was incorrect, expected no errors got: %s", err.Error())
+	}
+	parsed128, bytesUsed, err := ParseDecimal128Primitive(SerializeDecimal128Primitive(d128))
+	if err != nil || bytesUsed != 17 || parsed128.Decimal().Coefficient.Cmp(big34) != 0 || parsed128.Decimal().Exponent != -6176 {
+		t.Errorf("ParseDecimal128Primitive was incorrect, got:\"%s\" %v", parsed128.Decimal(), err)
+	}
+
+	// 1.00 as decimal64: coefficient 100, exponent -2 (biased 396)
+	d64 := Decimal64{0x31, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x64}
+	parsed64, _, err := ParseDecimal64Primitive(append([]byte{0x84}, d64[:]...))
+	if err != nil || parsed64.Decimal().String() != "100E-2" {
+		t.Errorf("ParseDecimal64Primitive was incorrect, \n\texpected: \"100E-2\" \n\tgot:\"%s\" %v", parsed64.Decimal(), err)
+	}
+	if f, _ := parsed64.Decimal().Float64(); f != 1.0 {
+		t.Errorf("Decimal.Float64 was incorrect, \n\texpected: \"1\" \n\tgot:\"%v\"", f)
+	}
+
+	if _, err = NewDecimal32(Decimal{Coefficient: big.NewInt(10000000)}); err == nil {
+		t.Errorf("NewDecimal32 was incorrect, expected an error for 8 digits")
+	}
+	if _, err = NewDecimal64(Decimal{Coefficient: big.NewInt(1), Exponent: 500}); err == nil {
+		t.Errorf("NewDecimal64 was incorrect, expected an error for the exponent")
+	}
+}
+
+func TestCharPrimitive(t *testing.T) {
+	for _, value := range []rune{'A', 'é', '😀'} {
+		parsed, bytesUsed, err := ParseCharPrimitive(SerializeCharPrimitive(value))
+		if err != nil || bytesUsed != 5 || parsed != value {
+			t.Errorf("ParseCharPrimitive was incorrect, \n\texpected: \"%c\" \n\tgot:\"%c\" %v", value, parsed, err)
+		}
+	}
+	if _, _, err := ParseCharPrimitive([]byte{0x73, 0x00, 0x11, 0x00, 0x00}); err == nil {
+		t.Errorf("ParseCharPrimitive was incorrect, expected an error for an invalid code point")
+	}
+}
+
+func TestUuidPrimitive(t *testing.T) {
+	uuid, err := ParseUUID("f81d4fae-7dec-11d0-a765-00a0c91e6bf6")
+	if err != nil {
+		t.Fatalf("ParseUUID was incorrect, expected no errors got: %s", err.Error())
+	}
+	if uuid.String() != "f81d4fae-7dec-11d0-a765-00a0c91e6bf6" {
+		t.Errorf("UUID.String was incorrect, got:\"%s\"", uuid)
+	}
+
+	parsed, bytesUsed, err := ParseUuidPrimitive(SerializeUuidPrimitive(uuid))
+	if err != nil || bytesUsed != 17 || parsed != uuid {
+		t.Errorf("ParseUuidPrimitive was incorrect, \n\texpected: \"%s\" \n\tgot:\"%s\" %v", uuid, parsed, err)
+	}
+
+	random, err := NewUUID()
+	if err != nil || random[6]>>4 != 4 || random == uuid {
+		t.Errorf("NewUUID was incorrect, got:\"%s\" %v", random, err)
+	}
+	if _, err = ParseUUID("f81d4fae7dec11d0a76500a0c91e6bf6"); err == nil {
+		t.Errorf("ParseUUID was incorrect, expected an error without dashes")
+	}
+}
+
+func TestSmallIntAndLongAreSigned(t *testing.T) {
+	for _, value := range []int32{-129, -128, -1, 0, 127, 128, 200} {
+		parsed, _, err := ParseIntPrimitive(SerializeIntPrimitive(value))
+		if err != nil || parsed != value {
+			t.Errorf("ParseIntPrimitive was incorrect, \n\texpected: \"%d\" \n\tgot:\"%d\" %v", value, parsed, err)
+		}
+	}
+	for _, value := range []int64{-129, -128, -1, 0, 127, 128, 200} {
+		parsed, _, err := ParseLongPrimitive(SerializeLongPrimitive(value))
+		if err != nil || parsed != value {
+			t.Errorf("ParseLongPrimitive was incorrect, \n\texpected: \"%d\" \n\tgot:\"%d\" %v", value, parsed, err)
+		}
+	}
+}
+
+func TestDescribedPrimitive(t *testing.T) {
+	// amqp:accepted:list sent with a symbolic descriptor
+	symbolic := append(SerializeSymbolPrimitive("amqp:accepted:list"), 0x45)
+	parsed, bytesUsed, err := ParseDescribedPrimitive(append([]byte{0x00}, symbolic...))
+	if err != nil || parsed != (Accepted{}) || bytesUsed != uint32(len(symbolic)+1) {
+		t.Errorf("ParseDescribedPrimitive (symbolic) was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", Accepted{}, parsed, err)
+	}
+
+	blockType, _, err := ParseBlockType(append([]byte{0x00, 0x80, 0, 0, 0, 0, 0, 0, 0, 0x10}, 0x45))
+	if err != nil || blockType != PerfOpen {
+		t.Errorf("ParseBlockType (ulong) was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", PerfOpen, blockType, err)
+	}
+
+	RegisterDescribedType(0x0000468C00000001, "example:custom:string", func(buffer []byte) (interface{}, uint32, error) {
+		return ParseStringPrimitive(buffer)
+	})
+	t.Cleanup(func() { unregisterDescribedType(0x0000468C00000001, "example:custom:string") })
+	buf := SerializeDescribedPrimitive(0x0000468C00000001, SerializeStringPrimitive("hello"))
+	parsed, _, err = ParseAny(buf)
+	if err != nil || parsed != "hello" {
+		t.Errorf("ParseAny (registered) was incorrect, \n\texpected: \"hello\" \n\tgot:\"%v\" %v", parsed, err)
+	}
+	if name, ok := DescriptorName(uint64(0x0000468C00000001)); !ok || name != "example:custom:string" {
+		t.Errorf("DescriptorName was incorrect, got:\"%v\"", name)
+	}
+}
+
+// ptrTo returns a pointer To a copy of value
+func ptrTo[T any](value T) *T {
+	return &value
+}
+
+type testComposite struct {
+	_        struct{}          `amqp:"example:test:list,0x0000468C:0x00000002"`
+	Name     string            `amqp:"name,mandatory"`
+	Count    *uint32           `amqp:"count"`
+	Priority byte              `amqp:"priority,default=4"`
+	Size     uint32            `amqp:"size,type=ulong"`
+	Tags     []Symbol          `amqp:"tags"`
+	Inner    *testComposite    `amqp:"inner"`
+	Props    map[string]string `amqp:"props"`
+	Skipped  string            `amqp:"-"`
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	count := uint32(0)
+	value := testComposite{
+		Name:  "outer",
+		Count: &count,
+		Size:  0x1234,
+		Tags:  []Symbol{"a", "b"},
+		Inner: &testComposite{Name: "inner", Priority: 7},
+		Props: map[string]string{"k": "v"},
+	}
+	buf, err := Marshal(value)
+	if err != nil {
+		t.Fatalf("%s\nMarshal was incorrect, expected no errors", err.Error())
+	}
+
+	var parsed testComposite
+	bytesUsed, err := Unmarshal(buf, &parsed)
+	if err != nil {
+		t.Fatalf("%s\nUnmarshal was incorrect, expected no errors", err.Error())
+	}
+	if bytesUsed != uint32(len(buf)) || !reflect.DeepEqual(parsed, value) {
+		t.Errorf("Unmarshal was incorrect, \n\texpected: \"%+v\" \n\tgot:\"%+v\"", value, parsed)
+	}
+
+	// a zero differing from the default is sent, the default is not
+	for _, priority := range []byte{0, 4} {
+		buf, _ = Marshal(testComposite{Name: "x", Priority: priority})
+		if _, err = Unmarshal(buf, &parsed); err != nil || parsed.Priority != priority {
+			t.Errorf("Unmarshal (priority) was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", priority, parsed.Priority, err)
+		}
+	}
+	header := Message{Header: &MessageHeader{Priority: ptrTo(byte(0))}}
+	buf, err = header.Marshal()
+	var message Message
+	if err == nil {
+		_, err = message.Unmarshal(buf)
+	}
+	if err != nil || message.Header == nil || message.Header.Priority == nil || *message.Header.Priority != 0 {
+		t.Errorf("Message.Unmarshal (priority) was incorrect, \n\texpected: \"%v\" \n\tgot:\"%+v\" %v", 0, message.Header, err)
+	}
+
+	// trailing null fields are elided, a zero without a default is sent
+	buf, _ = Marshal(testComposite{Name: "x", Priority: 4})
+	expected := []byte{0x00, 0x80, 0x00, 0x00, 0x46, 0x8c, 0x00, 0x00, 0x00, 0x02, 0xc0, 0x07, 0x04, 0xa1, 0x01, 0x78, 0x40, 0x40, 0x44}
+	if !bytes.Equal(buf, expected) {
+		t.Errorf("Marshal was incorrect, \n\texpected: \"%x\" \n\tgot:\"%x\"", expected, buf)
+	}
+
+	if _, err = Unmarshal([]byte{0x45}, &parsed); err == nil {
+		t.Errorf("Unmarshal was incorrect, expected an error for a null mandatory field")
+	}
+	if _, err = Unmarshal([]byte{0x40}, &parsed); err == nil {
+		t.Errorf("Unmarshal was incorrect, expected an error for a null composite with mandatory fields")
+	}
+	if _, err = Marshal(SaslChallenge{}); err == nil {
+		t.Errorf("Marshal was incorrect, expected an error for a nil mandatory binary")
+	}
+	if _, err = Unmarshal([]byte{0x00, 0x53, 0x70, 0x45}, &parsed); err == nil {
+		t.Errorf("Unmarshal was incorrect, expected an error for a header descriptor")
+	}
+}
+
+func TestMessageHeaderDefaults(t *testing.T) {
+	header, bytesUsed, err := ParseMessageHeader([]byte{0xc0, 0x02, 0x01, 0x41})
+	if err != nil || bytesUsed != 4 {
+		t.Fatalf("ParseMessageHeader was incorrect, expected no errors got %v", err)
+	}
+	if header.Durable != true || *header.Priority != 4 || header.DeliveryCount != 0 {
+		t.Errorf("ParseMessageHeader was incorrect, got:\"%+v\"", header)
+	}
+
+	header.Ttl = ptrTo[Milliseconds](1000)
+	buf, err := Marshal(header)
+	if err != nil {
+		t.Fatalf("%s\nMarshal was incorrect, expected no errors", err.Error())
+	}
+	blockType, inx, err := ParseBlockType(buf)
+	if err != nil || blockType != PerfHeader {
+		t.Fatalf("ParseBlockType was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", PerfHeader, blockType, err)
+	}
+	parsed, _, err := ParseMessageHeader(buf[inx:])
+	if err != nil || !reflect.DeepEqual(parsed, header) {
+		t.Errorf("ParseMessageHeader was incorrect, \n\texpected: \"%+v\" \n\tgot:\"%+v\" %v", header, parsed, err)
+	}
+}
+
+func TestEncoderMatchesSerialize(t *testing.T) {
+	e := NewEncoder(nil)
+	defer e.Release()
+
+	e.BeginList()
+	e.WriteString("container")
+	e.WriteUint(512)
+	e.BeginList()
+	e.WriteSymbol("inner")
+	e.EndList()
+	e.WriteNull()
+	e.WriteNull()
+	if err := e.EndComposite(); err != nil {
+		t.Fatalf("%s\nEndComposite was incorrect, expected no errors", err.Error())
+	}
+
+	expected := SerializeList(
+		SerializeStringPrimitive("container"),
+		SerializeUintPrimitive(512),
+		SerializeList(SerializeSymbolPrimitive("inner")))
+	if !bytes.Equal(e.Bytes(), expected) {
+		t.Errorf("Encoder was incorrect, \n\texpected: \"%x\" \n\tgot:\"%x\"", expected, e.Bytes())
+	}
+
+	// a list too large for the 8bit form keeps the 32bit header
+	e.Reset()
+	e.BeginList()
+	e.WriteString(strings.Repeat("x", 300))
+	e.EndList()
+	expected = SerializeList(SerializeStringPrimitive(strings.Repeat("x", 300)))
+	if !bytes.Equal(e.Bytes(), expected) {
+		t.Errorf("Encoder was incorrect for list32, \n\texpected: \"%x\" \n\tgot:\"%x\"", expected[:12], e.Bytes()[:12])
+	}
+
+	// maps, fields, annotations and symbol arrays are written in place in the order of Serialize
+	nested := Map{"b": Map{uint32(2): "two", uint32(1): "one"}, "a": []Symbol{"x", Symbol(strings.Repeat("y", 300))}}
+	fields := Fields{"z": int32(1), "a": nil}
+	annotations := Annotations{uint64(9): true, Symbol("x-opt"): "v"}
+	for _, value := range []interface{}{nested, fields, annotations, []Symbol{"p", "q"}, Map(nil)} {
+		e.Reset()
+		if err := e.WriteAny(value); err != nil {
+			t.Fatalf("%s\nWriteAny was incorrect, expected no errors", err.Error())
+		}
+		expected, _ = SerializeAny(value)
+		if !bytes.Equal(e.Bytes(), expected) {
+			t.Errorf("Encoder was incorrect for %T, \n\texpected: \"%x\" \n\tgot:\"%x\"", value, expected, e.Bytes())
+		}
+	}
+	e.Reset()
+	if err := e.WriteAny(Annotations{"string key": 1}); err == nil {
+		t.Errorf("WriteAny was incorrect, expected an error for a string annotations key")
+	}
+
+	var out bytes.Buffer
+	writer := NewEncoder(&out)
+	defer writer.Release()
+	writer.WriteDescriptor(Accepted{}.Descriptor())
+	writer.BeginList()
+	writer.EndList()
+	if err := writer.Flush(); err != nil || !bytes.Equal(out.Bytes(), []byte{0x00, 0x53, 0x24, 0x45}) {
+		t.Errorf("Encoder.Flush was incorrect, got:\"%x\" %v", out.Bytes(), err)
+	}
+}
+
+func TestDecoderReadsStream(t *testing.T) {
+	e := NewEncoder(nil)
+	defer e.Release()
+	e.WriteDescriptor(0x70)
+	e.BeginList()
+	e.WriteBoolean(true)
+	e.WriteUbyte(9)
+	e.EndList()
+	e.WriteBinary(bytes.Repeat([]byte{0xab}, 100))
+	e.WriteUlong(1 << 40)
+	e.WriteSymbolArray([]Symbol{"a", "b"})
+
+	// a reader buffer smaller than the binary Value forces a copy
+	d := NewDecoder(bufio.NewReaderSize(bytes.NewReader(e.Bytes()), 16))
+	var header MessageHeader
+	if err := d.Decode(&header); err != nil || header.Durable != true || header.Priority == nil || *header.Priority != 9 {
+		t.Errorf("Decoder.Decode was incorrect, got:\"%+v\" %v", header, err)
+	}
+	data, err := d.ReadBinary()
+	if err != nil || len(data) != 100 || data[99] != 0xab {
+		t.Errorf("Decoder.ReadBinary was incorrect, got:\"%x\" %v", data, err)
+	}
+	ulong, err := d.ReadUlong()
+	if err != nil || ulong != 1<<40 {
+		t.Errorf("Decoder.ReadUlong was incorrect, got:\"%x\" %v", ulong, err)
+	}
+	symbols, err := d.ReadSymbolArray()
+	if err != nil || !reflect.DeepEqual(symbols, []Symbol{"a", "b"}) {
+		t.Errorf("Decoder.ReadSymbolArray was incorrect, got:\"%v\" %v", symbols, err)
+	}
+	if d.Offset() != int64(e.Len()) {
+		t.Errorf("Decoder.Offset was incorrect, \n\texpected: \"%d\" \n\tgot:\"%d\"", e.Len(), d.Offset())
+	}
+	if _, err = d.ReadAny(); err != io.EOF {
+		t.Errorf("Decoder.ReadAny was incorrect, expected io.EOF got %v", err)
+	}
+
+	// a size above MaxValueSize is refused before it is read
+	d = NewDecoder(bytes.NewReader([]byte{0xb0, 0x7f, 0xff, 0xff, 0xff}))
+	d.MaxValueSize = 512
+	if _, err = d.ReadBinary(); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Decoder.ReadBinary was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", ErrInvalidValue, err)
+	}
+
+	d = NewDecoder(bytes.NewReader(e.Bytes()[:8]))
+	if _, err = d.ReadDescriptor(); err != nil {
+		t.Errorf("Decoder.ReadDescriptor was incorrect, expected no errors got %v", err)
+	}
+	if _, err = d.ReadListHeader(); err != nil {
+		t.Errorf("Decoder.ReadListHeader was incorrect, expected no errors got %v", err)
+	}
+	if _, err = d.ReadBoolean(); err != nil {
+		t.Errorf("Decoder.ReadBoolean was incorrect, expected no errors got %v", err)
+	}
+	if _, err = d.ReadUbyte(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Decoder.ReadUbyte was incorrect, expected io.ErrUnexpectedEOF got %v", err)
+	}
+}
+
+func benchmarkListItems() [][]byte {
+	return [][]byte{
+		SerializeStringPrimitive("container-id"),
+		SerializeStringPrimitive("hostname"),
+		SerializeUintPrimitive(65536),
+		SerializeUshortPrimitive(255),
+		SerializeUintPrimitive(30000),
+	}
+}
+
+func BenchmarkSerializeList(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		SerializeList(benchmarkListItems()...)
+	}
+}
+
+func BenchmarkEncoderList(b *testing.B) {
+	e := NewEncoder(nil)
+	defer e.Release()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		e.Reset()
+		e.BeginList()
+		e.WriteString("container-id")
+		e.WriteString("hostname")
+		e.WriteUint(65536)
+		e.WriteUshort(255)
+		e.WriteUint(30000)
+		e.EndList()
+	}
+}
+
+func BenchmarkParseList(b *testing.B) {
+	buf := SerializeList(benchmarkListItems()...)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _, _, inx, _ := ParseListPrimitive(buf)
+		_, advanceInx, _ := ParseStringPrimitive(buf[inx:])
+		inx += advanceInx
+		_, advanceInx, _ = ParseStringPrimitive(buf[inx:])
+		inx += advanceInx
+		_, advanceInx, _ = ParseUintPrimitive(buf[inx:])
+		inx += advanceInx
+		_, advanceInx, _ = PraseUshortPrimitive(buf[inx:])
+		inx += advanceInx
+		ParseUintPrimitive(buf[inx:])
+	}
+}
+
+func BenchmarkDecoderList(b *testing.B) {
+	buf := SerializeList(benchmarkListItems()...)
+	r := bytes.NewReader(buf)
+	br := bufio.NewReader(r)
+	d := NewDecoder(br)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.Reset(buf)
+		br.Reset(r)
+		d.ReadListHeader()
+		d.ReadString()
+		d.ReadString()
+		d.ReadUint()
+		d.ReadUshort()
+		d.ReadUint()
+	}
+}
+
+func TestParsePrimitiveDecodeError(t *testing.T) {
+	_, _, err := ParseUintPrimitive([]byte{0xa1, 0x01, 0x78})
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || !errors.Is(err, ErrUnexpectedConstructor) {
+		t.Fatalf("ParseUintPrimitive was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", ErrUnexpectedConstructor, err)
+	}
+	if !bytes.Equal(decodeErr.Expected, []byte{uintCode, uintSmallCode, uint0Code}) || decodeErr.Actual != 0xa1 {
+		t.Errorf("ParseUintPrimitive was incorrect constructors, \n\texpected: \"% x, got a1\" \n\tgot:\"% x, got %x\"",
+			[]byte{uintCode, uintSmallCode, uint0Code}, decodeErr.Expected, decodeErr.Actual)
+	}
+
+	encoded := [][]byte{
+		SerializeUlongPrimitive(1 << 40),
+		SerializeStringPrimitive(strings.Repeat("x", 300)),
+		SerializeSymbolArrayPrimitive([]Symbol{"a", "b"}),
+		SerializeList(SerializeUintPrimitive(1), SerializeStringPrimitive("x")),
+		{0x00, 0x53, 0x24, 0x45},
+		{0xd1, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x02, 0x41, 0x42},
+		{0xf0, 0x00, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00, 0x02, 0x52, 0x01, 0x02},
+	}
+	for _, buf := range encoded {
+		if _, _, err := ParseAny(buf); err != nil {
+			t.Fatalf("%s\nParseAny was incorrect, expected no errors", err.Error())
+		}
+		for i := 0; i < len(buf); i++ {
+			if _, _, err := ParseAny(buf[:i]); !errors.Is(err, ErrTruncated) {
+				t.Errorf("ParseAny of % x was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", buf[:i], ErrTruncated, err)
+			}
+		}
+	}
+}
+
+func TestSerializeEncodingForms(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	tests := []struct {
+		name     string
+		buf      []byte
+		expected []byte
+	}{
+		{"uint0", SerializeUintPrimitive(0), []byte{0x43}},
+		{"ulong0", SerializeUlongPrimitive(0), []byte{0x44}},
+		{"binary8", SerializeBinaryPrimitive([]byte{0x01, 0x02}), []byte{0xa0, 0x02, 0x01, 0x02}},
+		{"binary32", SerializeBinaryPrimitive([]byte(long))[:5], []byte{0xb0, 0x00, 0x00, 0x01, 0x2c}},
+		{"string8 empty", SerializeStringPrimitive(""), []byte{0xa1, 0x00}},
+		{"string8", SerializeStringPrimitive("ab"), []byte{0xa1, 0x02, 0x61, 0x62}},
+		{"string8 255", SerializeStringPrimitive(long[:255])[:2], []byte{0xa1, 0xff}},
+		{"string32", SerializeStringPrimitive(long)[:5], []byte{0xb1, 0x00, 0x00, 0x01, 0x2c}},
+		{"symbol8 empty", SerializeSymbolPrimitive(""), []byte{0xa3, 0x00}},
+		{"symbol32", SerializeSymbolPrimitive(Symbol(long))[:6], []byte{0xb3, 0x00, 0x00, 0x01, 0x2c, 0x78}},
+		{"list0", SerializeList(), []byte{0x45}},
+		{"list8", SerializeList([]byte{0x43}, []byte{0x41}), []byte{0xc0, 0x03, 0x02, 0x43, 0x41}},
+		{"list32", SerializeList(SerializeStringPrimitive(long))[:9], []byte{0xd0, 0x00, 0x00, 0x01, 0x35, 0x00, 0x00, 0x00, 0x01}},
+	}
+	for _, test := range tests {
+		if !reflect.DeepEqual(test.buf, test.expected) {
+			t.Errorf("%s was incorrect, \n\texpected: \"% x\" \n\tgot:\"% x\"", test.name, test.expected, test.buf)
+		}
+	}
+
+	// the long forms read back whole
+	for _, buf := range [][]byte{SerializeBinaryPrimitive([]byte(long)), SerializeStringPrimitive(long),
+		SerializeSymbolPrimitive(Symbol(long)), SerializeList(SerializeStringPrimitive(long))} {
+		if _, bytesUsed, err := ParseAny(buf); err != nil || bytesUsed != uint32(len(buf)) {
+			t.Errorf("ParseAny (0x%02x) was incorrect, \n\texpected: \"%d\" \n\tgot:\"%d\" %v", buf[0], len(buf), bytesUsed, err)
+		}
+	}
+}
@@ -0,0 +1,670 @@
+package amqpx
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	log "github.com/mgutz/logxi/v1"
+)
+
+// Names of the built in SASL mechanisms
+const (
+	SaslMechanismPlain       Symbol = "PLAIN"
+	SaslMechanismAnonymous   Symbol = "ANONYMOUS"
+	SaslMechanismExternal    Symbol = "EXTERNAL"
+	SaslMechanismScramSha256 Symbol = "SCRAM-SHA-256"
+)
+
+// ErrSaslAuthentication is returned when the credentials of the client are refused,
+// the sasl-outcome code is SaslCodeAuth
+var ErrSaslAuthentication = errors.New("amqpx: SASL authentication failed")
+
+// SaslClientMechanism is the client side of a SASL mechanism, used for one connection
+type SaslClientMechanism interface {
+	// Name returns the mechanism name sent in the sasl-init
+	Name() Symbol
+	// Start returns the initial response of the sasl-init, nil for none
+	Start() (initialResponse []byte, err error)
+	// Challenge returns the response To a challenge of the server
+	Challenge(challenge []byte) (response []byte, err error)
+	// Outcome checks the additional data of a successful sasl-outcome
+	Outcome(additionalData []byte) error
+}
+
+// SaslServerMechanism is the server side of a SASL mechanism, used for one connection
+type SaslServerMechanism interface {
+	// Name returns the mechanism name offered in the sasl-mechanisms
+	Name() Symbol
+	// Response processes the initial response, nil when the client sent none, or a later
+	// response of the client. While done is false challenge is sent To the client, when done
+	// it is the additional data of the sasl-outcome. A refused client is an ErrSaslAuthentication
+	Response(response []byte) (challenge []byte, done bool, err error)
+	// Identity returns the authenticated identity once done
+	Identity() string
+}
+
+// SaslCredentials are what a client gave in the SASL exchange
+type SaslCredentials struct {
+	Mechanism Symbol
+	// AuthorizationId is the identity the client asks To act as, empty for its own
+	AuthorizationId string
+	Username        string
+	// Password is only set by PLAIN, SCRAM-SHA-256 checks the password itself
+	Password string
+	// Trace is the trace information of ANONYMOUS
+	Trace string
+	// PeerCertificates are the certificates of a TLS client, used by EXTERNAL
+	PeerCertificates []*x509.Certificate
+}
+
+// SaslAuthenticator maps the credentials of a client To the identity of the connection,
+// an error refuses the client
+type SaslAuthenticator func(credentials SaslCredentials) (identity string, err error)
+
+// authenticate runs authenticator, the error of a refused client is an ErrSaslAuthentication
+func authenticate(authenticator SaslAuthenticator, credentials SaslCredentials) (identity string, err error) {
+	if authenticator == nil {
+		return "", fmt.Errorf("amqpx: no authenticator for %s", credentials.Mechanism)
+	}
+	identity, err = authenticator(credentials)
+	if err != nil && !errors.Is(err, ErrSaslAuthentication) {
+		err = fmt.Errorf("%w: %s", ErrSaslAuthentication, err.Error())
+	}
+	return identity, err
+}
+
+// NegotiateSaslClient runs the client side of the SASL exchange, after the SASL protocol
+// headers were exchanged. Spec section 5.3.2 SASL Negotiation
+func NegotiateSaslClient(reader *FrameReader, writer *FrameWriter, hostname string, mechanism SaslClientMechanism) error {
+	performative, err := readSaslFrame(reader)
+	if err != nil {
+		return err
+	}
+	mechanisms, ok := performative.(*SaslMechanisms)
+	if !ok {
+		return fmt.Errorf("amqpx: expected sasl-mechanisms, got %v", performative)
+	}
+	offered := false
+	for _, name := range mechanisms.Mechanisms {
+		offered = offered || name == mechanism.Name()
+	}
+	if !offered {
+		return fmt.Errorf("amqpx: server does not offer %s, only %v", mechanism.Name(), mechanisms.Mechanisms)
+	}
+
+	initialResponse, err := mechanism.Start()
+	if err != nil {
+		return err
+	}
+	saslInit := &SaslInit{Mechanism: mechanism.Name(), InitialResponse: initialResponse}
+	if hostname != "" {
+		saslInit.Hostname = &hostname
+	}
+	if err = writer.WriteSasl(saslInit); err != nil {
+		return err
+	}
+
+	for {
+		performative, err = readSaslFrame(reader)
+		if err != nil {
+			return err
+		}
+		switch p := performative.(type) {
+		case *SaslChallenge:
+			response, err := mechanism.Challenge(p.Challenge)
+			if err != nil {
+				return err
+			}
+			if response == nil {
+				// the response is mandatory, an empty one is not null
+				response = []byte{}
+			}
+			if err = writer.WriteSasl(&SaslResponse{Response: response}); err != nil {
+				return err
+			}
+		case *SaslOutcome:
+			if p.Code == SaslCodeAuth {
+				return fmt.Errorf("%w, %s refused", ErrSaslAuthentication, mechanism.Name())
+			}
+			if p.Code != SaslCodeOk {
+				return fmt.Errorf("amqpx: SASL negotiation failed with code %d", p.Code)
+			}
+			return mechanism.Outcome(p.AdditionalData)
+		default:
+			return fmt.Errorf("amqpx: unexpected SASL frame %v", performative)
+		}
+	}
+}
+
+// NegotiateSaslServer runs the server side of the SASL exchange, after the SASL protocol
+// headers were exchanged. It offers mechanisms, runs the one the client selects and sends
+// the outcome. It returns the identity of the client
+func NegotiateSaslServer(reader *FrameReader, writer *FrameWriter, mechanisms ...SaslServerMechanism) (identity string, err error) {
+	names := make([]Symbol, len(mechanisms))
+	for i, mechanism := range mechanisms {
+		names[i] = mechanism.Name()
+	}
+	if err = writer.WriteSasl(&SaslMechanisms{Mechanisms: names}); err != nil {
+		return "", err
+	}
+
+	performative, err := readSaslFrame(reader)
+	if err != nil {
+		return "", err
+	}
+	saslInit, ok := performative.(*SaslInit)
+	if !ok {
+		return "", fmt.Errorf("amqpx: expected sasl-init, got %v", performative)
+	}
+	log.Debug("sasl-init:", saslInit)
+	var mechanism SaslServerMechanism
+	for _, m := range mechanisms {
+		if m.Name() == saslInit.Mechanism {
+			mechanism = m
+		}
+	}
+	if mechanism == nil {
+		err = fmt.Errorf("%w, mechanism %s is not offered", ErrSaslAuthentication, saslInit.Mechanism)
+		return "", sendSaslFailure(writer, err)
+	}
+
+	response := []byte(saslInit.InitialResponse)
+	for {
+		challenge, done, err := mechanism.Response(response)
+		if err != nil {
+			return "", sendSaslFailure(writer, err)
+		}
+		if done {
+			if err = writer.WriteSasl(&SaslOutcome{Code: SaslCodeOk, AdditionalData: challenge}); err != nil {
+				return "", err
+			}
+			return mechanism.Identity(), nil
+		}
+
+		if challenge == nil {
+			// the challenge is mandatory, an empty one is not null
+			challenge = []byte{}
+		}
+		if err = writer.WriteSasl(&SaslChallenge{Challenge: challenge}); err != nil {
+			return "", err
+		}
+		performative, err = readSaslFrame(reader)
+		if err != nil {
+			return "", err
+		}
+		p, ok := performative.(*SaslResponse)
+		if !ok {
+			return "", fmt.Errorf("amqpx: expected sasl-response, got %v", performative)
+		}
+		response = p.Response
+	}
+}
+
+// sendSaslFailure sends the outcome for err and returns err
+func sendSaslFailure(writer *FrameWriter, err error) error {
+	code := SaslCodeSys
+	if errors.Is(err, ErrSaslAuthentication) {
+		code = SaslCodeAuth
+	}
+	log.Debug("sasl-outcome:", code, err.Error())
+	if writeErr := writer.WriteSasl(&SaslOutcome{Code: code}); writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+// readSaslFrame reads the next frame, which must be a SASL frame
+func readSaslFrame(reader *FrameReader) (Performative, error) {
+	frame, err := reader.ReadFrame()
+	if err != nil {
+		return nil, err
+	}
+	if frame.TypeCode != FrameTypeSASL {
+		return nil, fmt.Errorf("amqpx: expected a SASL frame, got type 0x%02x", frame.TypeCode)
+	}
+	return ParseSaslFrameBody(frame.Body)
+}
+
+// saslPlainClient sends authzid, username and password in the initial response. RFC 4616
+type saslPlainClient struct {
+	authorizationId, username, password string
+}
+
+// NewSaslPlainClient returns the PLAIN mechanism, authorizationId may be empty
+func NewSaslPlainClient(authorizationId, username, password string) SaslClientMechanism {
+	return &saslPlainClient{authorizationId: authorizationId, username: username, password: password}
+}
+
+func (m *saslPlainClient) Name() Symbol {
+	return SaslMechanismPlain
+}
+
+func (m *saslPlainClient) Start() ([]byte, error) {
+	return []byte(m.authorizationId + "\x00" + m.username + "\x00" + m.password), nil
+}
+
+func (m *saslPlainClient) Challenge(challenge []byte) ([]byte, error) {
+	// a server that wants the credentials in a response sends an empty challenge
+	return m.Start()
+}
+
+func (m *saslPlainClient) Outcome(additionalData []byte) error {
+	return nil
+}
+
+// saslPlainServer checks the PLAIN credentials with the authenticator
+type saslPlainServer struct {
+	authenticator SaslAuthenticator
+	challenged    bool
+	identity      string
+}
+
+// NewSaslPlainServer returns the PLAIN mechanism, authenticator checks the password
+func NewSaslPlainServer(authenticator SaslAuthenticator) SaslServerMechanism {
+	return &saslPlainServer{authenticator: authenticator}
+}
+
+func (m *saslPlainServer) Name() Symbol {
+	return SaslMechanismPlain
+}
+
+func (m *saslPlainServer) Response(response []byte) (challenge []byte, done bool, err error) {
+	if response == nil && !m.challenged {
+		// no initial response, ask for the credentials
+		m.challenged = true
+		return []byte{}, false, nil
+	}
+	fields := bytes.Split(response, []byte{0})
+	if len(fields) != 3 || len(fields[1]) == 0 {
+		return nil, false, fmt.Errorf("%w, malformed PLAIN response", ErrSaslAuthentication)
+	}
+	m.identity, err = authenticate(m.authenticator, SaslCredentials{
+		Mechanism:       SaslMechanismPlain,
+		AuthorizationId: string(fields[0]),
+		Username:        string(fields[1]),
+		Password:        string(fields[2]),
+	})
+	return nil, err == nil, err
+}
+
+func (m *saslPlainServer) Identity() string {
+	return m.identity
+}
+
+// saslAnonymousClient sends optional trace information. RFC 4505
+type saslAnonymousClient struct {
+	trace string
+}
+
+// NewSaslAnonymousClient returns the ANONYMOUS mechanism, trace may be empty
+func NewSaslAnonymousClient(trace string) SaslClientMechanism {
+	return &saslAnonymousClient{trace: trace}
+}
+
+func (m *saslAnonymousClient) Name() Symbol {
+	return SaslMechanismAnonymous
+}
+
+func (m *saslAnonymousClient) Start() ([]byte, error) {
+	return []byte(m.trace), nil
+}
+
+func (m *saslAnonymousClient) Challenge(challenge []byte) ([]byte, error) {
+	return []byte(m.trace), nil
+}
+
+func (m *saslAnonymousClient) Outcome(additionalData []byte) error {
+	return nil
+}
+
+// saslAnonymousServer lets the authenticator decide whether anonymous clients are welcome
+type saslAnonymousServer struct {
+	authenticator SaslAuthenticator
+	identity      string
+}
+
+// NewSaslAnonymousServer returns the ANONYMOUS mechanism
+func NewSaslAnonymousServer(authenticator SaslAuthenticator) SaslServerMechanism {
+	return &saslAnonymousServer{authenticator: authenticator}
+}
+
+func (m *saslAnonymousServer) Name() Symbol {
+	return SaslMechanismAnonymous
+}
+
+func (m *saslAnonymousServer) Response(response []byte) (challenge []byte, done bool, err error) {
+	m.identity, err = authenticate(m.authenticator, SaslCredentials{Mechanism: SaslMechanismAnonymous, Trace: string(response)})
+	return nil, err == nil, err
+}
+
+func (m *saslAnonymousServer) Identity() string {
+	return m.identity
+}
+
+// saslExternalClient relies on credentials of the transport, e.g. a TLS client certificate. RFC 4422
+type saslExternalClient struct {
+	authorizationId string
+}
+
+// NewSaslExternalClient returns the EXTERNAL mechanism, authorizationId may be empty
+func NewSaslExternalClient(authorizationId string) SaslClientMechanism {
+	return &saslExternalClient{authorizationId: authorizationId}
+}
+
+func (m *saslExternalClient) Name() Symbol {
+	return SaslMechanismExternal
+}
+
+func (m *saslExternalClient) Start() ([]byte, error) {
+	return []byte(m.authorizationId), nil
+}
+
+func (m *saslExternalClient) Challenge(challenge []byte) ([]byte, error) {
+	return []byte(m.authorizationId), nil
+}
+
+func (m *saslExternalClient) Outcome(additionalData []byte) error {
+	return nil
+}
+
+// saslExternalServer hands the certificates of the transport To the authenticator
+type saslExternalServer struct {
+	authenticator    SaslAuthenticator
+	peerCertificates []*x509.Certificate
+	identity         string
+}
+
+// NewSaslExternalServer returns the EXTERNAL mechanism, peerCertificates are the
+// verified certificates of the TLS client, see PeerCertificates, none without one
+func NewSaslExternalServer(peerCertificates []*x509.Certificate, authenticator SaslAuthenticator) SaslServerMechanism {
+	return &saslExternalServer{authenticator: authenticator, peerCertificates: peerCertificates}
+}
+
+func (m *saslExternalServer) Name() Symbol {
+	return SaslMechanismExternal
+}
+
+func (m *saslExternalServer) Response(response []byte) (challenge []byte, done bool, err error) {
+	if len(m.peerCertificates) == 0 {
+		return nil, false, fmt.Errorf("%w, EXTERNAL without a client certificate", ErrSaslAuthentication)
+	}
+	m.identity, err = authenticate(m.authenticator, SaslCredentials{
+		Mechanism:        SaslMechanismExternal,
+		AuthorizationId:  string(response),
+		Username:         m.peerCertificates[0].Subject.CommonName,
+		PeerCertificates: m.peerCertificates,
+	})
+	return nil, err == nil, err
+}
+
+func (m *saslExternalServer) Identity() string {
+	return m.identity
+}
+
+// ScramCredentials are what a server stores of a password for SCRAM-SHA-256. RFC 5802 and 7677
+type ScramCredentials struct {
+	Salt       []byte
+	Iterations int
+	StoredKey  []byte
+	ServerKey  []byte
+}
+
+// ScramIterations is the iteration count of NewScramCredentials, the minimum of RFC 7677
+const ScramIterations = 4096
+
+// NewScramCredentials derives the stored credentials of password with a random salt
+func NewScramCredentials(password string) (ScramCredentials, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return ScramCredentials{}, err
+	}
+	saltedPassword := scramSaltedPassword(password, salt, ScramIterations)
+	clientKey := scramHmac(saltedPassword, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	return ScramCredentials{
+		Salt:       salt,
+		Iterations: ScramIterations,
+		StoredKey:  storedKey[:],
+		ServerKey:  scramHmac(saltedPassword, "Server Key"),
+	}, nil
+}
+
+// scramSaltedPassword is Hi(password, salt, iterations), PBKDF2 with HMAC-SHA-256
+func scramSaltedPassword(password string, salt []byte, iterations int) []byte {
+	mac := hmac.New(sha256.New, []byte(password))
+	mac.Write(salt)
+	mac.Write([]byte{0, 0, 0, 1})
+	u := mac.Sum(nil)
+	result := append([]byte(nil), u...)
+	for i := 1; i < iterations; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for j := range result {
+			result[j] ^= u[j]
+		}
+	}
+	return result
+}
+
+func scramHmac(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// scramNonce returns a random printable nonce
+func scramNonce() (string, error) {
+	nonce := make([]byte, 18)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawStdEncoding.EncodeToString(nonce), nil
+}
+
+// scramAttributes splits a SCRAM message into its attributes, e.g. r=nonce
+func scramAttributes(message string) map[byte]string {
+	attributes := map[byte]string{}
+	for _, attribute := range strings.Split(message, ",") {
+		if len(attribute) >= 2 && attribute[1] == '=' {
+			attributes[attribute[0]] = attribute[2:]
+		}
+	}
+	return attributes
+}
+
+var scramNameEscaper = strings.NewReplacer("=", "=3D", ",", "=2C")
+var scramNameUnescaper = strings.NewReplacer("=3D", "=", "=2C", ",")
+
+// saslScramClient proves the password without sending it
+type saslScramClient struct {
+	authorizationId, username, password string
+	clientNonce                         string
+	gs2Header, clientFirstBare          string
+	serverSignature                     []byte
+}
+
+// NewSaslScramSha256Client returns the SCRAM-SHA-256 mechanism, authorizationId may be empty
+func NewSaslScramSha256Client(authorizationId, username, password string) SaslClientMechanism {
+	return &saslScramClient{authorizationId: authorizationId, username: username, password: password}
+}
+
+func (m *saslScramClient) Name() Symbol {
+	return SaslMechanismScramSha256
+}
+
+func (m *saslScramClient) Start() (initialResponse []byte, err error) {
+	if m.clientNonce, err = scramNonce(); err != nil {
+		return nil, err
+	}
+	// no channel binding
+	m.gs2Header = "n,,"
+	if m.authorizationId != "" {
+		m.gs2Header = "n,a=" + scramNameEscaper.Replace(m.authorizationId) + ","
+	}
+	m.clientFirstBare = "n=" + scramNameEscaper.Replace(m.username) + ",r=" + m.clientNonce
+	return []byte(m.gs2Header + m.clientFirstBare), nil
+}
+
+func (m *saslScramClient) Challenge(challenge []byte) ([]byte, error) {
+	serverFirst := string(challenge)
+	attributes := scramAttributes(serverFirst)
+	nonce := attributes['r']
+	if !strings.HasPrefix(nonce, m.clientNonce) || len(nonce) == len(m.clientNonce) {
+		return nil, errors.New("amqpx: SCRAM server nonce does not extend the client nonce")
+	}
+	salt, err := base64.StdEncoding.DecodeString(attributes['s'])
+	if err != nil {
+		return nil, errors.New(err.Error() + "\nsaslScramClient.Challenge() failed decoding the salt")
+	}
+	iterations, err := strconv.Atoi(attributes['i'])
+	if err != nil || iterations < 1 {
+		return nil, fmt.Errorf("amqpx: SCRAM iteration count %q is not valid", attributes['i'])
+	}
+
+	saltedPassword := scramSaltedPassword(m.password, salt, iterations)
+	clientKey := scramHmac(saltedPassword, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	clientFinal := "c=" + base64.StdEncoding.EncodeToString([]byte(m.gs2Header)) + ",r=" + nonce
+	authMessage := m.clientFirstBare + "," + serverFirst + "," + clientFinal
+	clientSignature := scramHmac(storedKey[:], authMessage)
+	for i := range clientSignature {
+		clientSignature[i] ^= clientKey[i]
+	}
+	m.serverSignature = scramHmac(scramHmac(saltedPassword, "Server Key"), authMessage)
+	return []byte(clientFinal + ",p=" + base64.StdEncoding.EncodeToString(clientSignature)), nil
+}
+
+// Outcome checks the server signature, proving that the server knows the password too
+func (m *saslScramClient) Outcome(additionalData []byte) error {
+	attributes := scramAttributes(string(additionalData))
+	if e, ok := attributes['e']; ok {
+		return fmt.Errorf("%w: %s", ErrSaslAuthentication, e)
+	}
+	signature, err := base64.StdEncoding.DecodeString(attributes['v'])
+	if err != nil || m.serverSignature == nil || !hmac.Equal(signature, m.serverSignature) {
+		return errors.New("amqpx: SCRAM server signature is not valid")
+	}
+	return nil
+}
+
+// saslScramServer checks the proof of the client against the stored credentials
+type saslScramServer struct {
+	lookup        func(username string) (ScramCredentials, error)
+	authenticator SaslAuthenticator
+
+	credentials                  ScramCredentials
+	authorizationId, username    string
+	gs2Header                    string
+	clientFirstBare, serverFirst string
+	nonce                        string
+	identity                     string
+}
+
+// NewSaslScramSha256Server returns the SCRAM-SHA-256 mechanism. lookup returns the stored
+// credentials of a user, authenticator maps the user that proved its password To the identity
+func NewSaslScramSha256Server(lookup func(username string) (ScramCredentials, error), authenticator SaslAuthenticator) SaslServerMechanism {
+	return &saslScramServer{lookup: lookup, authenticator: authenticator}
+}
+
+func (m *saslScramServer) Name() Symbol {
+	return SaslMechanismScramSha256
+}
+
+func (m *saslScramServer) Response(response []byte) (challenge []byte, done bool, err error) {
+	if m.serverFirst == "" {
+		return m.clientFirst(string(response))
+	}
+	return m.clientFinal(string(response))
+}
+
+// clientFirst answers the client-first-message with the salt, iterations and nonce
+func (m *saslScramServer) clientFirst(message string) (challenge []byte, done bool, err error) {
+	if message == "" {
+		// no initial response, ask for it
+		return []byte{}, false, nil
+	}
+	// gs2-header: channel binding flag, authzid, then the bare message
+	parts := strings.SplitN(message, ",", 3)
+	if len(parts) != 3 || (parts[0] != "n" && parts[0] != "y") {
+		return nil, false, fmt.Errorf("%w, malformed SCRAM client-first-message", ErrSaslAuthentication)
+	}
+	if strings.HasPrefix(parts[1], "a=") {
+		m.authorizationId = scramNameUnescaper.Replace(parts[1][2:])
+	}
+	m.gs2Header = parts[0] + "," + parts[1] + ","
+	m.clientFirstBare = parts[2]
+	attributes := scramAttributes(m.clientFirstBare)
+	m.username = scramNameUnescaper.Replace(attributes['n'])
+	clientNonce := attributes['r']
+	if m.username == "" || clientNonce == "" {
+		return nil, false, fmt.Errorf("%w, SCRAM client-first-message without user or nonce", ErrSaslAuthentication)
+	}
+	if m.credentials, err = m.lookup(m.username); err != nil {
+		return nil, false, fmt.Errorf("%w, unknown user %s", ErrSaslAuthentication, m.username)
+	}
+
+	serverNonce, err := scramNonce()
+	if err != nil {
+		return nil, false, err
+	}
+	m.nonce = clientNonce + serverNonce
+	m.serverFirst = "r=" + m.nonce + ",s=" + base64.StdEncoding.EncodeToString(m.credentials.Salt) +
+		",i=" + strconv.Itoa(m.credentials.Iterations)
+	return []byte(m.serverFirst), false, nil
+}
+
+// clientFinal checks the proof of the client-final-message, the server signature goes in the outcome
+func (m *saslScramServer) clientFinal(message string) (additionalData []byte, done bool, err error) {
+	inx := strings.LastIndex(message, ",p=")
+	if inx < 0 {
+		return nil, false, fmt.Errorf("%w, SCRAM client-final-message without proof", ErrSaslAuthentication)
+	}
+	clientFinal := message[:inx]
+	attributes := scramAttributes(clientFinal)
+	if attributes['r'] != m.nonce {
+		return nil, false, fmt.Errorf("%w, SCRAM nonce mismatch", ErrSaslAuthentication)
+	}
+	// the channel binding repeats the gs2-header of the client-first-message, RFC 5802 section 5.1
+	if attributes['c'] != base64.StdEncoding.EncodeToString([]byte(m.gs2Header)) {
+		return nil, false, fmt.Errorf("%w, SCRAM channel binding mismatch", ErrSaslAuthentication)
+	}
+	proof, err := base64.StdEncoding.DecodeString(message[inx+3:])
+	if err != nil || len(proof) != sha256.Size || len(m.credentials.StoredKey) != sha256.Size {
+		return nil, false, fmt.Errorf("%w, malformed SCRAM proof", ErrSaslAuthentication)
+	}
+
+	authMessage := m.clientFirstBare + "," + m.serverFirst + "," + clientFinal
+	clientKey := scramHmac(m.credentials.StoredKey, authMessage)
+	for i := range clientKey {
+		clientKey[i] ^= proof[i]
+	}
+	storedKey := sha256.Sum256(clientKey)
+	if subtle.ConstantTimeCompare(storedKey[:], m.credentials.StoredKey) != 1 {
+		return nil, false, fmt.Errorf("%w, wrong password for %s", ErrSaslAuthentication, m.username)
+	}
+
+	m.identity, err = authenticate(m.authenticator, SaslCredentials{
+		Mechanism:       SaslMechanismScramSha256,
+		AuthorizationId: m.authorizationId,
+		Username:        m.username,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	serverSignature := scramHmac(m.credentials.ServerKey, authMessage)
+	return []byte("v=" + base64.StdEncoding.EncodeToString(serverSignature)), true, nil
+}
+
+func (m *saslScramServer) Identity() string {
+	return m.identity
+}
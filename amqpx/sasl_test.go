@@ -0,0 +1,89 @@
+package amqpx
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestSaslNegotiation(t *testing.T) {
+	scram, err := NewScramCredentials("s3cret")
+	if err != nil {
+		t.Fatalf("%s\nNewScramCredentials was incorrect, expected no errors", err.Error())
+	}
+	lookup := func(username string) (ScramCredentials, error) {
+		if username != "guest" {
+			return ScramCredentials{}, errors.New("no such user")
+		}
+		return scram, nil
+	}
+	authenticator := func(credentials SaslCredentials) (string, error) {
+		switch {
+		case credentials.Mechanism == SaslMechanismAnonymous:
+			return "anonymous", nil
+		case credentials.Username == "guest" && (credentials.Password == "s3cret" || credentials.Mechanism == SaslMechanismScramSha256):
+			return credentials.Username, nil
+		}
+		return "", errors.New("bad credentials")
+	}
+
+	tests := []struct {
+		client   SaslClientMechanism
+		identity string
+		err      error
+	}{
+		{NewSaslPlainClient("", "guest", "s3cret"), "guest", nil},
+		{NewSaslPlainClient("", "guest", "wrong"), "", ErrSaslAuthentication},
+		{NewSaslAnonymousClient("trace"), "anonymous", nil},
+		{NewSaslScramSha256Client("", "guest", "s3cret"), "guest", nil},
+		{NewSaslScramSha256Client("", "guest", "wrong"), "", ErrSaslAuthentication},
+		{NewSaslScramSha256Client("", "other", "s3cret"), "", ErrSaslAuthentication},
+		{NewSaslExternalClient(""), "", ErrSaslAuthentication},
+	}
+	for _, test := range tests {
+		clientConn, serverConn := net.Pipe()
+		type result struct {
+			identity string
+			err      error
+		}
+		done := make(chan result)
+		go func() {
+			identity, err := NegotiateSaslServer(NewFrameReader(serverConn, MinMaxFrameSize), NewFrameWriter(serverConn, MinMaxFrameSize),
+				NewSaslPlainServer(authenticator), NewSaslAnonymousServer(authenticator),
+				NewSaslExternalServer(nil, authenticator), NewSaslScramSha256Server(lookup, authenticator))
+			serverConn.Close()
+			done <- result{identity, err}
+		}()
+
+		err := NegotiateSaslClient(NewFrameReader(clientConn, MinMaxFrameSize), NewFrameWriter(clientConn, MinMaxFrameSize), "localhost", test.client)
+		clientConn.Close()
+		server := <-done
+		if !errors.Is(err, test.err) && !(test.err == nil && err == nil) {
+			t.Errorf("NegotiateSaslClient (%s) was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", test.client.Name(), test.err, err)
+		}
+		if server.identity != test.identity || !errors.Is(server.err, test.err) && !(test.err == nil && server.err == nil) {
+			t.Errorf("NegotiateSaslServer (%s) was incorrect, \n\texpected: \"%v %v\" \n\tgot:\"%v %v\"", test.client.Name(), test.identity, test.err, server.identity, server.err)
+		}
+	}
+}
+
+func TestSaslScramChannelBinding(t *testing.T) {
+	scram, _ := NewScramCredentials("s3cret")
+	lookup := func(username string) (ScramCredentials, error) { return scram, nil }
+	server := NewSaslScramSha256Server(lookup, func(credentials SaslCredentials) (string, error) { return credentials.Username, nil })
+	client := NewSaslScramSha256Client("", "guest", "s3cret")
+
+	// the gs2-header is altered on its way, the client-final-message still binds "n,,"
+	clientFirst, _ := client.Start()
+	serverFirst, _, err := server.Response(append([]byte("y,,"), clientFirst[len("n,,"):]...))
+	if err != nil {
+		t.Fatalf("%s\nResponse was incorrect, expected no errors", err.Error())
+	}
+	clientFinal, err := client.Challenge(serverFirst)
+	if err != nil {
+		t.Fatalf("%s\nChallenge was incorrect, expected no errors", err.Error())
+	}
+	if _, _, err = server.Response(clientFinal); !errors.Is(err, ErrSaslAuthentication) {
+		t.Errorf("Response was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", ErrSaslAuthentication, err)
+	}
+}
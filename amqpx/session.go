@@ -0,0 +1,263 @@
+package amqpx
+
+import (
+	"errors"
+	"fmt"
+
+	log "github.com/mgutz/logxi/v1"
+)
+
+// SessionState is the state of a session, spec section 2.5.5 Session States
+type SessionState int
+
+// Spec session states
+const (
+	// SessionUnmapped no begin sent or received
+	SessionUnmapped SessionState = iota
+	// SessionBeginSent our begin was sent, the one of the peer not yet received
+	SessionBeginSent
+	// SessionBeginRcvd the begin of the peer was received, ours not yet sent
+	SessionBeginRcvd
+	// SessionMapped both begins were exchanged
+	SessionMapped
+	// SessionEndSent our end was sent, the one of the peer not yet received
+	SessionEndSent
+	// SessionEndRcvd the end of the peer was received, ours not yet sent
+	SessionEndRcvd
+	// SessionDiscarding our end was sent with an error, frames of the peer are discarded until its end
+	SessionDiscarding
+)
+
+var sessionStateNames = [...]string{"UNMAPPED", "BEGIN_SENT", "BEGIN_RCVD", "MAPPED", "END_SENT", "END_RCVD", "DISCARDING"}
+
+func (state SessionState) String() string {
+	if state < 0 || int(state) >= len(sessionStateNames) {
+		return fmt.Sprintf("SessionState(%d)", int(state))
+	}
+	return sessionStateNames[state]
+}
+
+// ErrSessionState is returned when a frame is sent in a session state that does not allow it
+var ErrSessionState = errors.New("amqpx: illegal session state")
+
+// ErrSessionWindow is returned when a transfer is sent while the incoming window of the peer is closed
+var ErrSessionWindow = errors.New("amqpx: session window closed")
+
+// ErrChannelMax is returned when every channel up To channel-max carries a session
+var ErrChannelMax = errors.New("amqpx: no channel left below channel-max")
+
+// Session is one session multiplexed on a connection, it tracks the session flow control
+// of its transfers. Spec section 2.5 Sessions
+type Session struct {
+	// Local is the begin we send, Remote the begin received from the peer
+	Local  SessionParameters
+	Remote SessionParameters
+	// Channel is the channel we send on, RemoteChannel the one of the peer
+	Channel       uint16
+	RemoteChannel uint16
+
+	// the session endpoint state, spec section 2.5.6 Session Flow Control. The incoming
+	// window is opened again To Local.IncomingWindow once half of it is used
+	NextIncomingId       TransferNumber
+	IncomingWindow       uint32
+	NextOutgoingId       TransferNumber
+	OutgoingWindow       uint32
+	RemoteIncomingWindow uint32
+	RemoteOutgoingWindow uint32
+
+	state      SessionState
+	connection *Connection
+}
+
+// State returns the current session state
+func (s *Session) State() SessionState {
+	return s.state
+}
+
+// Begin sends Local, with the channel of the peer when answering its begin. The windows
+// of Local are the ones the session starts with
+func (s *Session) Begin() error {
+	var next SessionState
+	switch s.state {
+	case SessionUnmapped:
+		s.Local.RemoteChannel = nil
+		next = SessionBeginSent
+	case SessionBeginRcvd:
+		remoteChannel := s.RemoteChannel
+		s.Local.RemoteChannel = &remoteChannel
+		next = SessionMapped
+	default:
+		return fmt.Errorf("%w, can not send begin in %s", ErrSessionState, s.state)
+	}
+	s.NextOutgoingId = TransferNumber(s.Local.NextOutgoing)
+	s.IncomingWindow = s.Local.IncomingWindow
+	s.OutgoingWindow = s.Local.OutgoingWindow
+	if err := s.connection.Send(s.Channel, &s.Local, nil); err != nil {
+		return err
+	}
+	s.transition(next)
+	return nil
+}
+
+// End sends an end, with amqpError when the session fails. After an error the frames of the
+// peer are discarded until its end arrives. When our begin was not sent yet it is sent first
+func (s *Session) End(amqpError *Error) error {
+	if s.state == SessionBeginRcvd {
+		if err := s.Begin(); err != nil {
+			return err
+		}
+	}
+	var next SessionState
+	switch s.state {
+	case SessionBeginSent, SessionMapped:
+		next = SessionEndSent
+		if amqpError != nil {
+			next = SessionDiscarding
+		}
+	case SessionEndRcvd:
+		next = SessionUnmapped
+	default:
+		return fmt.Errorf("%w, can not send end in %s", ErrSessionState, s.state)
+	}
+	if err := s.connection.Send(s.Channel, &EndParameters{Error: amqpError}, nil); err != nil {
+		return err
+	}
+	s.transition(next)
+	return nil
+}
+
+// Flow returns a flow with the state of the session, the link fields are left unset
+func (s *Session) Flow() *FlowParameters {
+	flow := &FlowParameters{
+		IncomingWindow: s.IncomingWindow,
+		NextOutgoing:   s.NextOutgoingId,
+		OutgoingWindow: s.OutgoingWindow,
+	}
+	if s.state != SessionBeginSent {
+		// the peer did not tell its next-outgoing-id before its begin
+		nextIncoming := s.NextIncomingId
+		flow.NextIncoming = &nextIncoming
+	}
+	return flow
+}
+
+// Send writes performative on the channel of the session. A transfer is refused with
+// ErrSessionWindow while the incoming window of the peer is closed
+func (s *Session) Send(performative Performative, payload []byte) error {
+	switch p := performative.(type) {
+	case *SessionParameters:
+		return s.Begin()
+	case *EndParameters:
+		return s.End(p.Error)
+	}
+	if s.state != SessionBeginSent && s.state != SessionMapped {
+		return fmt.Errorf("%w, can not send %v in %s", ErrSessionState, performative, s.state)
+	}
+	if _, ok := performative.(*TransferParameters); ok {
+		if s.RemoteIncomingWindow == 0 {
+			return fmt.Errorf("%w, the peer accepts no transfer on channel %d", ErrSessionWindow, s.Channel)
+		}
+		if err := s.connection.Send(s.Channel, performative, payload); err != nil {
+			return err
+		}
+		s.NextOutgoingId++
+		s.RemoteIncomingWindow--
+		return nil
+	}
+	return s.connection.Send(s.Channel, performative, payload)
+}
+
+// transition moves the session To next, an unmapped session frees its channels
+func (s *Session) transition(next SessionState) {
+	log.Debug("session state:", fmt.Sprintf("channel %d %s -> %s", s.Channel, s.state, next))
+	s.state = next
+	if next == SessionUnmapped {
+		s.connection.releaseSession(s)
+	}
+}
+
+// beginReceived maps the session To the begin of the peer
+func (s *Session) beginReceived(remoteChannel uint16, begin *SessionParameters) {
+	s.Remote = *begin
+	s.RemoteChannel = remoteChannel
+	s.NextIncomingId = TransferNumber(begin.NextOutgoing)
+	s.RemoteIncomingWindow = begin.IncomingWindow
+	s.RemoteOutgoingWindow = begin.OutgoingWindow
+}
+
+// received moves the session on a performative of the peer
+func (s *Session) received(performative Performative) error {
+	switch p := performative.(type) {
+	case *EndParameters:
+		switch s.state {
+		case SessionMapped:
+			s.transition(SessionEndRcvd)
+		case SessionEndSent, SessionDiscarding:
+			s.transition(SessionUnmapped)
+		default:
+			return s.fail(NewError(ErrorConnectionFramingError, "end received in %s", s.state))
+		}
+		return nil
+	case *FlowParameters:
+		s.flowReceived(p)
+	case *TransferParameters:
+		return s.transferReceived()
+	}
+	if s.state != SessionMapped && s.state != SessionEndSent {
+		return s.fail(NewError(ErrorConnectionFramingError, "%v received in %s", performative, s.state))
+	}
+	return nil
+}
+
+// flowReceived updates the windows of the peer and echoes a session flow when asked To
+func (s *Session) flowReceived(flow *FlowParameters) {
+	// before our begin reached the peer it counts from our initial next-outgoing-id
+	nextIncoming := TransferNumber(s.Local.NextOutgoing)
+	if flow.NextIncoming != nil {
+		nextIncoming = *flow.NextIncoming
+	}
+	s.RemoteIncomingWindow = uint32(nextIncoming) + flow.IncomingWindow - uint32(s.NextOutgoingId)
+	s.RemoteOutgoingWindow = flow.OutgoingWindow
+	if bool(flow.Echo) && !flow.IsLinkFlow() && s.state == SessionMapped {
+		if err := s.connection.Send(s.Channel, s.Flow(), nil); err != nil {
+			log.Debug("session flow echo failed:", err.Error())
+		}
+	}
+}
+
+// transferReceived counts a transfer against the incoming window, a transfer beyond it
+// ends the session with amqp:session:window-violation
+func (s *Session) transferReceived() error {
+	if s.state != SessionMapped && s.state != SessionEndSent {
+		return s.fail(NewError(ErrorConnectionFramingError, "transfer received in %s", s.state))
+	}
+	if s.IncomingWindow == 0 {
+		return s.fail(NewError(ErrorSessionWindowViolation, "transfer %d received with the incoming window closed", s.NextIncomingId))
+	}
+	s.NextIncomingId++
+	s.IncomingWindow--
+	if s.RemoteOutgoingWindow > 0 {
+		s.RemoteOutgoingWindow--
+	}
+	if s.state == SessionMapped && s.IncomingWindow < s.Local.IncomingWindow/2 {
+		// open the window again before the peer has To wait for it
+		s.IncomingWindow = s.Local.IncomingWindow
+		if err := s.connection.Send(s.Channel, s.Flow(), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fail ends the session with amqpError, unless our end was sent already, and returns amqpError
+func (s *Session) fail(amqpError *Error) error {
+	log.Debug("session failed:", amqpError.Error())
+	switch s.state {
+	case SessionEndSent, SessionDiscarding, SessionUnmapped:
+		return amqpError
+	}
+	if err := s.End(amqpError); err != nil {
+		log.Debug("session end failed:", err.Error())
+	}
+	return amqpError
+}
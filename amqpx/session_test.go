@@ -0,0 +1,100 @@
+package amqpx
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSessionFlowControl(t *testing.T) {
+	var toServer, toClient bytes.Buffer
+	client := NewConnection(NewFrameReader(&toClient, 0), NewFrameWriter(&toServer, 0))
+	server := NewConnection(NewFrameReader(&toServer, 0), NewFrameWriter(&toClient, 0))
+	client.Local = ConnectionParameters{ContainerId: "client", ChannelMax: ptrTo(uint16(3))}
+	server.Local = ConnectionParameters{ContainerId: "server", ChannelMax: ptrTo(uint16(1))}
+	client.SendHeader()
+	client.Open()
+	server.ReceiveHeader()
+	server.SendHeader()
+	server.Open()
+	server.Receive()
+	client.Receive()
+	if client.ChannelMax() != 1 {
+		t.Errorf("ChannelMax was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", 1, client.ChannelMax())
+	}
+
+	// two sessions up To channel-max 1, with incoming windows of 2 and 1 at the server
+	var sessions []*Session
+	for inx := 0; inx < 2; inx++ {
+		session, err := client.Begin(SessionParameters{NextOutgoing: 1, IncomingWindow: 10, OutgoingWindow: 10})
+		if err != nil || session.Channel != uint16(inx) {
+			t.Fatalf("Begin was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", inx, session, err)
+		}
+		sessions = append(sessions, session)
+	}
+	if _, err := client.Begin(SessionParameters{}); !errors.Is(err, ErrChannelMax) {
+		t.Errorf("Begin above channel-max was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", ErrChannelMax, err)
+	}
+	for inx := uint32(0); inx < 2; inx++ {
+		channel, _, _, err := server.Receive()
+		session := server.Session(channel)
+		if err != nil || session == nil || session.State() != SessionBeginRcvd {
+			t.Fatalf("Receive (begin) was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", SessionBeginRcvd, session, err)
+		}
+		session.Local = SessionParameters{IncomingWindow: 2 - inx, OutgoingWindow: 10}
+		session.Begin()
+	}
+	for _, session := range sessions {
+		client.Receive()
+		if session.State() != SessionMapped {
+			t.Errorf("session state was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", SessionMapped, session.State())
+		}
+	}
+
+	transfer := func(id DeliveryNumber) *TransferParameters {
+		return &TransferParameters{DeliveryId: &id, DeliveryTag: DeliveryTag{byte(id)}}
+	}
+	for id := DeliveryNumber(0); id < 2; id++ {
+		if err := sessions[0].Send(transfer(id), []byte{0x00, 0x53, 0x77, 0x40}); err != nil {
+			t.Errorf("%s\nSend (transfer) was incorrect, expected no errors", err.Error())
+		}
+	}
+	if err := sessions[0].Send(transfer(2), nil); !errors.Is(err, ErrSessionWindow) {
+		t.Errorf("Send beyond the window was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", ErrSessionWindow, err)
+	}
+	// the server opens its window again once half of it is used
+	server.Receive()
+	server.Receive()
+	if _, performative, _, err := client.Receive(); err != nil || sessions[0].RemoteIncomingWindow != 2 {
+		t.Errorf("Receive (flow) was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v %v", 2, sessions[0].RemoteIncomingWindow, performative, err)
+	}
+	if sessions[0].NextOutgoingId != 3 || server.Session(0).NextIncomingId != 3 {
+		t.Errorf("transfer ids were incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", 3, sessions[0].NextOutgoingId, server.Session(0).NextIncomingId)
+	}
+
+	// a transfer beyond the window of the server ends the session, not the connection
+	client.Send(1, transfer(0), nil)
+	client.Send(1, transfer(1), nil)
+	server.Receive()
+	_, _, _, err := server.Receive()
+	var amqpError *Error
+	if !errors.As(err, &amqpError) || amqpError.Condition != ErrorSessionWindowViolation {
+		t.Errorf("Receive beyond the window was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", ErrorSessionWindowViolation, err)
+	}
+	if server.State() != ConnOpened || server.Session(1).State() != SessionDiscarding {
+		t.Errorf("state was incorrect, \n\texpected: \"%v %v\" \n\tgot:\"%v %v\"", ConnOpened, SessionDiscarding, server.State(), server.Session(1).State())
+	}
+	client.Send(1, transfer(2), nil)
+	client.Receive()
+	if sessions[1].State() != SessionEndRcvd {
+		t.Errorf("session state was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", SessionEndRcvd, sessions[1].State())
+	}
+	sessions[1].End(nil)
+	server.Receive()
+	if server.Session(1) != nil || sessions[1].State() != SessionUnmapped {
+		t.Errorf("End was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", "unmapped sessions", server.Session(1))
+	}
+	if session, err := client.Begin(SessionParameters{}); err != nil || session.Channel != 1 {
+		t.Errorf("Begin on the freed channel was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v", 1, session, err)
+	}
+}
@@ -0,0 +1,575 @@
+// Code generated by specgen from xml/messaging.xml. DO NOT EDIT.
+
+package amqpx
+
+// MessageHeader .. the header composite of the message format
+// <type name="header" class="composite" source="list" provides="section">
+//
+//	<descriptor name="amqp:header:list" code="0x00000000:0x00000070"/>
+//	<field name="durable" type="boolean" default="false"/>
+//	<field name="priority" type="ubyte" default="4"/>
+//	<field name="ttl" type="milliseconds"/>
+//	<field name="first-acquirer" type="boolean" default="false"/>
+//	<field name="delivery-count" type="uint" default="0"/>
+//
+// </type>
+type MessageHeader struct {
+	_             struct{}      `amqp:"amqp:header:list,0x00000000:0x00000070"`
+	Durable       BooleanChoice `json:"durable,omitempty" amqp:"durable,default=false"`
+	Priority      *byte         `json:"priority,omitempty" amqp:"priority,default=4"`
+	Ttl           *Milliseconds `json:"ttl,omitempty" amqp:"ttl"`
+	FirstAcquirer BooleanChoice `json:"firstAcquirer,omitempty" amqp:"first-acquirer,default=false"`
+	DeliveryCount uint32        `json:"deliveryCount,omitempty" amqp:"delivery-count,default=0"`
+}
+
+// Descriptor returns the descriptor code of header
+func (MessageHeader) Descriptor() uint64 {
+	return 0x70
+}
+
+// Marshal serializes header including its descriptor
+func (messageHeader MessageHeader) Marshal() ([]byte, error) {
+	return Marshal(messageHeader)
+}
+
+// Unmarshal reads header from buffer, with or without its descriptor
+func (messageHeader *MessageHeader) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, messageHeader)
+}
+
+// DeliveryAnnotations .. the delivery-annotations composite of the message format
+// <type name="delivery-annotations" class="restricted" source="annotations" provides="section">
+//
+//	<descriptor name="amqp:delivery-annotations:map" code="0x00000000:0x00000071"/>
+//
+// </type>
+type DeliveryAnnotations Annotations
+
+// Descriptor returns the descriptor code of delivery-annotations
+func (DeliveryAnnotations) Descriptor() uint64 {
+	return 0x71
+}
+
+// MarshalAMQP serializes delivery-annotations including its descriptor
+func (deliveryAnnotations DeliveryAnnotations) MarshalAMQP() ([]byte, error) {
+	return marshalDescribed(0x71, (Annotations)(deliveryAnnotations))
+}
+
+// UnmarshalAMQP reads delivery-annotations from buffer including its descriptor
+func (deliveryAnnotations *DeliveryAnnotations) UnmarshalAMQP(buffer []byte) (bytesUsed uint32, err error) {
+	return unmarshalDescribed(buffer, "amqp:delivery-annotations:map", 0x71, (*Annotations)(deliveryAnnotations))
+}
+
+// MessageAnnotations .. the message-annotations composite of the message format
+// <type name="message-annotations" class="restricted" source="annotations" provides="section">
+//
+//	<descriptor name="amqp:message-annotations:map" code="0x00000000:0x00000072"/>
+//
+// </type>
+type MessageAnnotations Annotations
+
+// Descriptor returns the descriptor code of message-annotations
+func (MessageAnnotations) Descriptor() uint64 {
+	return 0x72
+}
+
+// MarshalAMQP serializes message-annotations including its descriptor
+func (messageAnnotations MessageAnnotations) MarshalAMQP() ([]byte, error) {
+	return marshalDescribed(0x72, (Annotations)(messageAnnotations))
+}
+
+// UnmarshalAMQP reads message-annotations from buffer including its descriptor
+func (messageAnnotations *MessageAnnotations) UnmarshalAMQP(buffer []byte) (bytesUsed uint32, err error) {
+	return unmarshalDescribed(buffer, "amqp:message-annotations:map", 0x72, (*Annotations)(messageAnnotations))
+}
+
+// MessageProperties .. the properties composite of the message format
+// <type name="properties" class="composite" source="list" provides="section">
+//
+//	<descriptor name="amqp:properties:list" code="0x00000000:0x00000073"/>
+//	<field name="message-id" type="*" requires="message-id"/>
+//	<field name="user-id" type="binary"/>
+//	<field name="to" type="*" requires="address"/>
+//	<field name="subject" type="string"/>
+//	<field name="reply-to" type="*" requires="address"/>
+//	<field name="correlation-id" type="*" requires="message-id"/>
+//	<field name="content-type" type="symbol"/>
+//	<field name="content-encoding" type="symbol"/>
+//	<field name="absolute-expiry-time" type="timestamp"/>
+//	<field name="creation-time" type="timestamp"/>
+//	<field name="group-id" type="string"/>
+//	<field name="group-sequence" type="sequence-no"/>
+//	<field name="reply-to-group-id" type="string"/>
+//
+// </type>
+type MessageProperties struct {
+	_               struct{}    `amqp:"amqp:properties:list,0x00000000:0x00000073"`
+	MessageId       MessageID   `json:"messageId,omitempty" amqp:"message-id"`
+	UserId          Binary      `json:"userId,omitempty" amqp:"user-id"`
+	To              *string     `json:"to,omitempty" amqp:"to"`
+	Subject         *string     `json:"subject,omitempty" amqp:"subject"`
+	ReplyTo         *string     `json:"replyTo,omitempty" amqp:"reply-to"`
+	CorrelationId   MessageID   `json:"correlationId,omitempty" amqp:"correlation-id"`
+	ContentType     *Symbol     `json:"contentType,omitempty" amqp:"content-type"`
+	ContentEncoding *Symbol     `json:"contentEncoding,omitempty" amqp:"content-encoding"`
+	AbsExpiryTime   *Timestamp  `json:"absoluteExpiryTime,omitempty" amqp:"absolute-expiry-time"`
+	CreationTime    *Timestamp  `json:"creationTime,omitempty" amqp:"creation-time"`
+	GroupId         *string     `json:"groupId,omitempty" amqp:"group-id"`
+	GroupSequence   *SequenceNo `json:"groupSequence,omitempty" amqp:"group-sequence"`
+	ReplyToGroupID  *string     `json:"replyToGroupId,omitempty" amqp:"reply-to-group-id"`
+}
+
+// Descriptor returns the descriptor code of properties
+func (MessageProperties) Descriptor() uint64 {
+	return 0x73
+}
+
+// Marshal serializes properties including its descriptor
+func (messageProperties MessageProperties) Marshal() ([]byte, error) {
+	return Marshal(messageProperties)
+}
+
+// Unmarshal reads properties from buffer, with or without its descriptor
+func (messageProperties *MessageProperties) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, messageProperties)
+}
+
+// ApplicationProperties .. the application-properties composite of the message format
+// <type name="application-properties" class="restricted" source="map" provides="section">
+//
+//	<descriptor name="amqp:application-properties:map" code="0x00000000:0x00000074"/>
+//
+// </type>
+type ApplicationProperties Map
+
+// Descriptor returns the descriptor code of application-properties
+func (ApplicationProperties) Descriptor() uint64 {
+	return 0x74
+}
+
+// MarshalAMQP serializes application-properties including its descriptor
+func (applicationProperties ApplicationProperties) MarshalAMQP() ([]byte, error) {
+	return marshalDescribed(0x74, (Map)(applicationProperties))
+}
+
+// UnmarshalAMQP reads application-properties from buffer including its descriptor
+func (applicationProperties *ApplicationProperties) UnmarshalAMQP(buffer []byte) (bytesUsed uint32, err error) {
+	return unmarshalDescribed(buffer, "amqp:application-properties:map", 0x74, (*Map)(applicationProperties))
+}
+
+// Data .. the data composite of the message format
+// <type name="data" class="restricted" source="binary" provides="section">
+//
+//	<descriptor name="amqp:data:binary" code="0x00000000:0x00000075"/>
+//
+// </type>
+type Data Binary
+
+// Descriptor returns the descriptor code of data
+func (Data) Descriptor() uint64 {
+	return 0x75
+}
+
+// MarshalAMQP serializes data including its descriptor
+func (data Data) MarshalAMQP() ([]byte, error) {
+	return marshalDescribed(0x75, (Binary)(data))
+}
+
+// UnmarshalAMQP reads data from buffer including its descriptor
+func (data *Data) UnmarshalAMQP(buffer []byte) (bytesUsed uint32, err error) {
+	return unmarshalDescribed(buffer, "amqp:data:binary", 0x75, (*Binary)(data))
+}
+
+// AmqpSequence .. the amqp-sequence composite of the message format
+// <type name="amqp-sequence" class="restricted" source="list" provides="section">
+//
+//	<descriptor name="amqp:amqp-sequence:list" code="0x00000000:0x00000076"/>
+//
+// </type>
+type AmqpSequence []interface{}
+
+// Descriptor returns the descriptor code of amqp-sequence
+func (AmqpSequence) Descriptor() uint64 {
+	return 0x76
+}
+
+// MarshalAMQP serializes amqp-sequence including its descriptor
+func (amqpSequence AmqpSequence) MarshalAMQP() ([]byte, error) {
+	return marshalDescribed(0x76, ([]interface{})(amqpSequence))
+}
+
+// UnmarshalAMQP reads amqp-sequence from buffer including its descriptor
+func (amqpSequence *AmqpSequence) UnmarshalAMQP(buffer []byte) (bytesUsed uint32, err error) {
+	return unmarshalDescribed(buffer, "amqp:amqp-sequence:list", 0x76, (*[]interface{})(amqpSequence))
+}
+
+// MessageAmqpValue .. the amqp-value composite of the message format
+// <type name="amqp-value" class="restricted" source="*" provides="section">
+//
+//	<descriptor name="amqp:amqp-value:*" code="0x00000000:0x00000077"/>
+//
+// </type>
+type MessageAmqpValue struct {
+	Value interface{} `json:"value"`
+}
+
+// Descriptor returns the descriptor code of amqp-value
+func (MessageAmqpValue) Descriptor() uint64 {
+	return 0x77
+}
+
+// MarshalAMQP serializes amqp-value including its descriptor
+func (messageAmqpValue MessageAmqpValue) MarshalAMQP() ([]byte, error) {
+	return marshalDescribed(0x77, messageAmqpValue.Value)
+}
+
+// UnmarshalAMQP reads amqp-value from buffer including its descriptor
+func (messageAmqpValue *MessageAmqpValue) UnmarshalAMQP(buffer []byte) (bytesUsed uint32, err error) {
+	return unmarshalDescribed(buffer, "amqp:amqp-value:*", 0x77, &messageAmqpValue.Value)
+}
+
+// Footer .. the footer composite of the message format
+// <type name="footer" class="restricted" source="annotations" provides="section">
+//
+//	<descriptor name="amqp:footer:map" code="0x00000000:0x00000078"/>
+//
+// </type>
+type Footer Annotations
+
+// Descriptor returns the descriptor code of footer
+func (Footer) Descriptor() uint64 {
+	return 0x78
+}
+
+// MarshalAMQP serializes footer including its descriptor
+func (footer Footer) MarshalAMQP() ([]byte, error) {
+	return marshalDescribed(0x78, (Annotations)(footer))
+}
+
+// UnmarshalAMQP reads footer from buffer including its descriptor
+func (footer *Footer) UnmarshalAMQP(buffer []byte) (bytesUsed uint32, err error) {
+	return unmarshalDescribed(buffer, "amqp:footer:map", 0x78, (*Annotations)(footer))
+}
+
+// Received .. the received composite of the delivery state
+// <type name="received" class="composite" source="list" provides="delivery-state">
+//
+//	<descriptor name="amqp:received:list" code="0x00000000:0x00000023"/>
+//	<field name="section-number" type="uint" mandatory="true"/>
+//	<field name="section-offset" type="ulong" mandatory="true"/>
+//
+// </type>
+type Received struct {
+	_             struct{} `amqp:"amqp:received:list,0x00000000:0x00000023"`
+	SectionNumber uint32   `json:"sectionNumber" amqp:"section-number,mandatory"`
+	SectionOffset uint64   `json:"sectionOffset" amqp:"section-offset,mandatory"`
+}
+
+// Descriptor returns the descriptor code of received
+func (Received) Descriptor() uint64 {
+	return 0x23
+}
+
+// Marshal serializes received including its descriptor
+func (received Received) Marshal() ([]byte, error) {
+	return Marshal(received)
+}
+
+// Unmarshal reads received from buffer, with or without its descriptor
+func (received *Received) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, received)
+}
+
+// Accepted .. the accepted composite of the delivery state
+// <type name="accepted" class="composite" source="list" provides="delivery-state, outcome">
+//
+//	<descriptor name="amqp:accepted:list" code="0x00000000:0x00000024"/>
+//
+// </type>
+type Accepted struct {
+	_ struct{} `amqp:"amqp:accepted:list,0x00000000:0x00000024"`
+}
+
+// Descriptor returns the descriptor code of accepted
+func (Accepted) Descriptor() uint64 {
+	return 0x24
+}
+
+// Marshal serializes accepted including its descriptor
+func (accepted Accepted) Marshal() ([]byte, error) {
+	return Marshal(accepted)
+}
+
+// Unmarshal reads accepted from buffer, with or without its descriptor
+func (accepted *Accepted) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, accepted)
+}
+
+// Rejected .. the rejected composite of the delivery state
+// <type name="rejected" class="composite" source="list" provides="delivery-state, outcome">
+//
+//	<descriptor name="amqp:rejected:list" code="0x00000000:0x00000025"/>
+//	<field name="error" type="error"/>
+//
+// </type>
+type Rejected struct {
+	_     struct{} `amqp:"amqp:rejected:list,0x00000000:0x00000025"`
+	Error *Error   `json:"error,omitempty" amqp:"error"`
+}
+
+// Descriptor returns the descriptor code of rejected
+func (Rejected) Descriptor() uint64 {
+	return 0x25
+}
+
+// Marshal serializes rejected including its descriptor
+func (rejected Rejected) Marshal() ([]byte, error) {
+	return Marshal(rejected)
+}
+
+// Unmarshal reads rejected from buffer, with or without its descriptor
+func (rejected *Rejected) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, rejected)
+}
+
+// Released .. the released composite of the delivery state
+// <type name="released" class="composite" source="list" provides="delivery-state, outcome">
+//
+//	<descriptor name="amqp:released:list" code="0x00000000:0x00000026"/>
+//
+// </type>
+type Released struct {
+	_ struct{} `amqp:"amqp:released:list,0x00000000:0x00000026"`
+}
+
+// Descriptor returns the descriptor code of released
+func (Released) Descriptor() uint64 {
+	return 0x26
+}
+
+// Marshal serializes released including its descriptor
+func (released Released) Marshal() ([]byte, error) {
+	return Marshal(released)
+}
+
+// Unmarshal reads released from buffer, with or without its descriptor
+func (released *Released) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, released)
+}
+
+// Modified .. the modified composite of the delivery state
+// <type name="modified" class="composite" source="list" provides="delivery-state, outcome">
+//
+//	<descriptor name="amqp:modified:list" code="0x00000000:0x00000027"/>
+//	<field name="delivery-failed" type="boolean"/>
+//	<field name="undeliverable-here" type="boolean"/>
+//	<field name="message-annotations" type="fields"/>
+//
+// </type>
+type Modified struct {
+	_                  struct{}       `amqp:"amqp:modified:list,0x00000000:0x00000027"`
+	DeliveryFailed     *BooleanChoice `json:"deliveryFailed,omitempty" amqp:"delivery-failed"`
+	UndeliverableHere  *BooleanChoice `json:"undeliverableHere,omitempty" amqp:"undeliverable-here"`
+	MessageAnnotations Fields         `json:"messageAnnotations,omitempty" amqp:"message-annotations"`
+}
+
+// Descriptor returns the descriptor code of modified
+func (Modified) Descriptor() uint64 {
+	return 0x27
+}
+
+// Marshal serializes modified including its descriptor
+func (modified Modified) Marshal() ([]byte, error) {
+	return Marshal(modified)
+}
+
+// Unmarshal reads modified from buffer, with or without its descriptor
+func (modified *Modified) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, modified)
+}
+
+// Source .. the source composite of the addressing
+// <type name="source" class="composite" source="list" provides="source">
+//
+//	<descriptor name="amqp:source:list" code="0x00000000:0x00000028"/>
+//	<field name="address" type="*" requires="address"/>
+//	<field name="durable" type="terminus-durability" default="none"/>
+//	<field name="expiry-policy" type="terminus-expiry-policy" default="session-end"/>
+//	<field name="timeout" type="seconds" default="0"/>
+//	<field name="dynamic" type="boolean" default="false"/>
+//	<field name="dynamic-node-properties" type="node-properties"/>
+//	<field name="distribution-mode" type="symbol" requires="distribution-mode"/>
+//	<field name="filter" type="filter-set"/>
+//	<field name="default-outcome" type="*" requires="outcome"/>
+//	<field name="outcomes" type="symbol" multiple="true"/>
+//	<field name="capabilities" type="symbol" multiple="true"/>
+//
+// </type>
+type Source struct {
+	_                     struct{}                    `amqp:"amqp:source:list,0x00000000:0x00000028"`
+	Address               *string                     `json:"address,omitempty" amqp:"address"`
+	Durable               TerminusDurabilityChoice    `json:"durable,omitempty" amqp:"durable,default=0,type=uint"`
+	ExpiryPolicy          *TerminusExpiryPolicyChoice `json:"expiryPolicy,omitempty" amqp:"expiry-policy,default=session-end,type=symbol"`
+	Timeout               uint32                      `json:"timeout,omitempty" amqp:"timeout,default=0"`
+	Dynamic               BooleanChoice               `json:"dynamic,omitempty" amqp:"dynamic,default=false"`
+	DynamicNodeProperties Fields                      `json:"dynamicNodeProperties,omitempty" amqp:"dynamic-node-properties"`
+	DistributionMode      *Symbol                     `json:"distributionMode,omitempty" amqp:"distribution-mode"`
+	Filter                FilterSet                   `json:"filter,omitempty" amqp:"filter"`
+	DefaultOutcome        Outcome                     `json:"defaultOutcome,omitempty" amqp:"default-outcome"`
+	Outcomes              []Symbol                    `json:"outcomes,omitempty" amqp:"outcomes"`
+	Capabilities          []Symbol                    `json:"capabilities,omitempty" amqp:"capabilities"`
+}
+
+// Descriptor returns the descriptor code of source
+func (Source) Descriptor() uint64 {
+	return 0x28
+}
+
+// Marshal serializes source including its descriptor
+func (source Source) Marshal() ([]byte, error) {
+	return Marshal(source)
+}
+
+// Unmarshal reads source from buffer, with or without its descriptor
+func (source *Source) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, source)
+}
+
+// Target .. the target composite of the addressing
+// <type name="target" class="composite" source="list" provides="target">
+//
+//	<descriptor name="amqp:target:list" code="0x00000000:0x00000029"/>
+//	<field name="address" type="*" requires="address"/>
+//	<field name="durable" type="terminus-durability" default="none"/>
+//	<field name="expiry-policy" type="terminus-expiry-policy" default="session-end"/>
+//	<field name="timeout" type="seconds" default="0"/>
+//	<field name="dynamic" type="boolean" default="false"/>
+//	<field name="dynamic-node-properties" type="node-properties"/>
+//	<field name="capabilities" type="symbol" multiple="true"/>
+//
+// </type>
+type Target struct {
+	_                     struct{}                    `amqp:"amqp:target:list,0x00000000:0x00000029"`
+	Address               *string                     `json:"address,omitempty" amqp:"address"`
+	Durable               TerminusDurabilityChoice    `json:"durable,omitempty" amqp:"durable,default=0,type=uint"`
+	ExpiryPolicy          *TerminusExpiryPolicyChoice `json:"expiryPolicy,omitempty" amqp:"expiry-policy,default=session-end,type=symbol"`
+	Timeout               uint32                      `json:"timeout,omitempty" amqp:"timeout,default=0"`
+	Dynamic               BooleanChoice               `json:"dynamic,omitempty" amqp:"dynamic,default=false"`
+	DynamicNodeProperties Fields                      `json:"dynamicNodeProperties,omitempty" amqp:"dynamic-node-properties"`
+	Capabilities          []Symbol                    `json:"capabilities,omitempty" amqp:"capabilities"`
+}
+
+// Descriptor returns the descriptor code of target
+func (Target) Descriptor() uint64 {
+	return 0x29
+}
+
+// Marshal serializes target including its descriptor
+func (target Target) Marshal() ([]byte, error) {
+	return Marshal(target)
+}
+
+// Unmarshal reads target from buffer, with or without its descriptor
+func (target *Target) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, target)
+}
+
+// DeleteOnClose .. the delete-on-close composite of the addressing
+// <type name="delete-on-close" class="composite" source="list" provides="lifetime-policy">
+//
+//	<descriptor name="amqp:delete-on-close:list" code="0x00000000:0x0000002b"/>
+//
+// </type>
+type DeleteOnClose struct {
+	_ struct{} `amqp:"amqp:delete-on-close:list,0x00000000:0x0000002b"`
+}
+
+// Descriptor returns the descriptor code of delete-on-close
+func (DeleteOnClose) Descriptor() uint64 {
+	return 0x2b
+}
+
+// Marshal serializes delete-on-close including its descriptor
+func (deleteOnClose DeleteOnClose) Marshal() ([]byte, error) {
+	return Marshal(deleteOnClose)
+}
+
+// Unmarshal reads delete-on-close from buffer, with or without its descriptor
+func (deleteOnClose *DeleteOnClose) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, deleteOnClose)
+}
+
+// DeleteOnNoLinks .. the delete-on-no-links composite of the addressing
+// <type name="delete-on-no-links" class="composite" source="list" provides="lifetime-policy">
+//
+//	<descriptor name="amqp:delete-on-no-links:list" code="0x00000000:0x0000002c"/>
+//
+// </type>
+type DeleteOnNoLinks struct {
+	_ struct{} `amqp:"amqp:delete-on-no-links:list,0x00000000:0x0000002c"`
+}
+
+// Descriptor returns the descriptor code of delete-on-no-links
+func (DeleteOnNoLinks) Descriptor() uint64 {
+	return 0x2c
+}
+
+// Marshal serializes delete-on-no-links including its descriptor
+func (deleteOnNoLinks DeleteOnNoLinks) Marshal() ([]byte, error) {
+	return Marshal(deleteOnNoLinks)
+}
+
+// Unmarshal reads delete-on-no-links from buffer, with or without its descriptor
+func (deleteOnNoLinks *DeleteOnNoLinks) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, deleteOnNoLinks)
+}
+
+// DeleteOnNoMessages .. the delete-on-no-messages composite of the addressing
+// <type name="delete-on-no-messages" class="composite" source="list" provides="lifetime-policy">
+//
+//	<descriptor name="amqp:delete-on-no-messages:list" code="0x00000000:0x0000002d"/>
+//
+// </type>
+type DeleteOnNoMessages struct {
+	_ struct{} `amqp:"amqp:delete-on-no-messages:list,0x00000000:0x0000002d"`
+}
+
+// Descriptor returns the descriptor code of delete-on-no-messages
+func (DeleteOnNoMessages) Descriptor() uint64 {
+	return 0x2d
+}
+
+// Marshal serializes delete-on-no-messages including its descriptor
+func (deleteOnNoMessages DeleteOnNoMessages) Marshal() ([]byte, error) {
+	return Marshal(deleteOnNoMessages)
+}
+
+// Unmarshal reads delete-on-no-messages from buffer, with or without its descriptor
+func (deleteOnNoMessages *DeleteOnNoMessages) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, deleteOnNoMessages)
+}
+
+// DeleteOnNoLinksOrMessages .. the delete-on-no-links-or-messages composite of the addressing
+// <type name="delete-on-no-links-or-messages" class="composite" source="list" provides="lifetime-policy">
+//
+//	<descriptor name="amqp:delete-on-no-links-or-messages:list" code="0x00000000:0x0000002e"/>
+//
+// </type>
+type DeleteOnNoLinksOrMessages struct {
+	_ struct{} `amqp:"amqp:delete-on-no-links-or-messages:list,0x00000000:0x0000002e"`
+}
+
+// Descriptor returns the descriptor code of delete-on-no-links-or-messages
+func (DeleteOnNoLinksOrMessages) Descriptor() uint64 {
+	return 0x2e
+}
+
+// Marshal serializes delete-on-no-links-or-messages including its descriptor
+func (deleteOnNoLinksOrMessages DeleteOnNoLinksOrMessages) Marshal() ([]byte, error) {
+	return Marshal(deleteOnNoLinksOrMessages)
+}
+
+// Unmarshal reads delete-on-no-links-or-messages from buffer, with or without its descriptor
+func (deleteOnNoLinksOrMessages *DeleteOnNoLinksOrMessages) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, deleteOnNoLinksOrMessages)
+}
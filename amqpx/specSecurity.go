@@ -0,0 +1,144 @@
+// Code generated by specgen from xml/security.xml. DO NOT EDIT.
+
+package amqpx
+
+// SaslMechanisms .. the sasl-mechanisms composite of the SASL frames
+// <type name="sasl-mechanisms" class="composite" source="list" provides="sasl-frame">
+//
+//	<descriptor name="amqp:sasl-mechanisms:list" code="0x00000000:0x00000040"/>
+//	<field name="sasl-server-mechanisms" type="symbol" mandatory="true" multiple="true"/>
+//
+// </type>
+type SaslMechanisms struct {
+	_          struct{} `amqp:"amqp:sasl-mechanisms:list,0x00000000:0x00000040"`
+	Mechanisms []Symbol `json:"saslServerMechanisms" amqp:"sasl-server-mechanisms,mandatory"`
+}
+
+// Descriptor returns the descriptor code of sasl-mechanisms
+func (SaslMechanisms) Descriptor() uint64 {
+	return 0x40
+}
+
+// Marshal serializes sasl-mechanisms including its descriptor
+func (saslMechanisms SaslMechanisms) Marshal() ([]byte, error) {
+	return Marshal(saslMechanisms)
+}
+
+// Unmarshal reads sasl-mechanisms from buffer, with or without its descriptor
+func (saslMechanisms *SaslMechanisms) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, saslMechanisms)
+}
+
+// SaslInit .. the sasl-init composite of the SASL frames
+// <type name="sasl-init" class="composite" source="list" provides="sasl-frame">
+//
+//	<descriptor name="amqp:sasl-init:list" code="0x00000000:0x00000041"/>
+//	<field name="mechanism" type="symbol" mandatory="true"/>
+//	<field name="initial-response" type="binary"/>
+//	<field name="hostname" type="string"/>
+//
+// </type>
+type SaslInit struct {
+	_               struct{} `amqp:"amqp:sasl-init:list,0x00000000:0x00000041"`
+	Mechanism       Symbol   `json:"mechanism" amqp:"mechanism,mandatory"`
+	InitialResponse Binary   `json:"initialResponse,omitempty" amqp:"initial-response"`
+	Hostname        *string  `json:"hostname,omitempty" amqp:"hostname"`
+}
+
+// Descriptor returns the descriptor code of sasl-init
+func (SaslInit) Descriptor() uint64 {
+	return 0x41
+}
+
+// Marshal serializes sasl-init including its descriptor
+func (saslInit SaslInit) Marshal() ([]byte, error) {
+	return Marshal(saslInit)
+}
+
+// Unmarshal reads sasl-init from buffer, with or without its descriptor
+func (saslInit *SaslInit) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, saslInit)
+}
+
+// SaslChallenge .. the sasl-challenge composite of the SASL frames
+// <type name="sasl-challenge" class="composite" source="list" provides="sasl-frame">
+//
+//	<descriptor name="amqp:sasl-challenge:list" code="0x00000000:0x00000042"/>
+//	<field name="challenge" type="binary" mandatory="true"/>
+//
+// </type>
+type SaslChallenge struct {
+	_         struct{} `amqp:"amqp:sasl-challenge:list,0x00000000:0x00000042"`
+	Challenge Binary   `json:"challenge" amqp:"challenge,mandatory"`
+}
+
+// Descriptor returns the descriptor code of sasl-challenge
+func (SaslChallenge) Descriptor() uint64 {
+	return 0x42
+}
+
+// Marshal serializes sasl-challenge including its descriptor
+func (saslChallenge SaslChallenge) Marshal() ([]byte, error) {
+	return Marshal(saslChallenge)
+}
+
+// Unmarshal reads sasl-challenge from buffer, with or without its descriptor
+func (saslChallenge *SaslChallenge) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, saslChallenge)
+}
+
+// SaslResponse .. the sasl-response composite of the SASL frames
+// <type name="sasl-response" class="composite" source="list" provides="sasl-frame">
+//
+//	<descriptor name="amqp:sasl-response:list" code="0x00000000:0x00000043"/>
+//	<field name="response" type="binary" mandatory="true"/>
+//
+// </type>
+type SaslResponse struct {
+	_        struct{} `amqp:"amqp:sasl-response:list,0x00000000:0x00000043"`
+	Response Binary   `json:"response" amqp:"response,mandatory"`
+}
+
+// Descriptor returns the descriptor code of sasl-response
+func (SaslResponse) Descriptor() uint64 {
+	return 0x43
+}
+
+// Marshal serializes sasl-response including its descriptor
+func (saslResponse SaslResponse) Marshal() ([]byte, error) {
+	return Marshal(saslResponse)
+}
+
+// Unmarshal reads sasl-response from buffer, with or without its descriptor
+func (saslResponse *SaslResponse) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, saslResponse)
+}
+
+// SaslOutcome .. the sasl-outcome composite of the SASL frames
+// <type name="sasl-outcome" class="composite" source="list" provides="sasl-frame">
+//
+//	<descriptor name="amqp:sasl-outcome:list" code="0x00000000:0x00000044"/>
+//	<field name="code" type="sasl-code" mandatory="true"/>
+//	<field name="additional-data" type="binary"/>
+//
+// </type>
+type SaslOutcome struct {
+	_              struct{} `amqp:"amqp:sasl-outcome:list,0x00000000:0x00000044"`
+	Code           SaslCode `json:"code" amqp:"code,mandatory"`
+	AdditionalData Binary   `json:"additionalData,omitempty" amqp:"additional-data"`
+}
+
+// Descriptor returns the descriptor code of sasl-outcome
+func (SaslOutcome) Descriptor() uint64 {
+	return 0x44
+}
+
+// Marshal serializes sasl-outcome including its descriptor
+func (saslOutcome SaslOutcome) Marshal() ([]byte, error) {
+	return Marshal(saslOutcome)
+}
+
+// Unmarshal reads sasl-outcome from buffer, with or without its descriptor
+func (saslOutcome *SaslOutcome) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, saslOutcome)
+}
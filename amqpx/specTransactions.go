@@ -0,0 +1,142 @@
+// Code generated by specgen from xml/transactions.xml. DO NOT EDIT.
+
+package amqpx
+
+// Coordinator .. the coordinator composite of the transaction coordination
+// <type name="coordinator" class="composite" source="list" provides="target">
+//
+//	<descriptor name="amqp:coordinator:list" code="0x00000000:0x00000030"/>
+//	<field name="capabilities" type="symbol" requires="txn-capability" multiple="true"/>
+//
+// </type>
+type Coordinator struct {
+	_            struct{} `amqp:"amqp:coordinator:list,0x00000000:0x00000030"`
+	Capabilities []Symbol `json:"capabilities,omitempty" amqp:"capabilities"`
+}
+
+// Descriptor returns the descriptor code of coordinator
+func (Coordinator) Descriptor() uint64 {
+	return 0x30
+}
+
+// Marshal serializes coordinator including its descriptor
+func (coordinator Coordinator) Marshal() ([]byte, error) {
+	return Marshal(coordinator)
+}
+
+// Unmarshal reads coordinator from buffer, with or without its descriptor
+func (coordinator *Coordinator) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, coordinator)
+}
+
+// Declare .. the declare composite of the transaction coordination
+// <type name="declare" class="composite" source="list">
+//
+//	<descriptor name="amqp:declare:list" code="0x00000000:0x00000031"/>
+//	<field name="global-id" type="*" requires="global-tx-id"/>
+//
+// </type>
+type Declare struct {
+	_        struct{}    `amqp:"amqp:declare:list,0x00000000:0x00000031"`
+	GlobalId interface{} `json:"globalId,omitempty" amqp:"global-id"`
+}
+
+// Descriptor returns the descriptor code of declare
+func (Declare) Descriptor() uint64 {
+	return 0x31
+}
+
+// Marshal serializes declare including its descriptor
+func (declare Declare) Marshal() ([]byte, error) {
+	return Marshal(declare)
+}
+
+// Unmarshal reads declare from buffer, with or without its descriptor
+func (declare *Declare) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, declare)
+}
+
+// Discharge .. the discharge composite of the transaction coordination
+// <type name="discharge" class="composite" source="list">
+//
+//	<descriptor name="amqp:discharge:list" code="0x00000000:0x00000032"/>
+//	<field name="txn-id" type="*" requires="txn-id" mandatory="true"/>
+//	<field name="fail" type="boolean"/>
+//
+// </type>
+type Discharge struct {
+	_     struct{}       `amqp:"amqp:discharge:list,0x00000000:0x00000032"`
+	TxnId Binary         `json:"txnId" amqp:"txn-id,mandatory"`
+	Fail  *BooleanChoice `json:"fail,omitempty" amqp:"fail"`
+}
+
+// Descriptor returns the descriptor code of discharge
+func (Discharge) Descriptor() uint64 {
+	return 0x32
+}
+
+// Marshal serializes discharge including its descriptor
+func (discharge Discharge) Marshal() ([]byte, error) {
+	return Marshal(discharge)
+}
+
+// Unmarshal reads discharge from buffer, with or without its descriptor
+func (discharge *Discharge) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, discharge)
+}
+
+// Declared .. the declared composite of the transaction coordination
+// <type name="declared" class="composite" source="list" provides="delivery-state, outcome">
+//
+//	<descriptor name="amqp:declared:list" code="0x00000000:0x00000033"/>
+//	<field name="txn-id" type="*" requires="txn-id" mandatory="true"/>
+//
+// </type>
+type Declared struct {
+	_     struct{} `amqp:"amqp:declared:list,0x00000000:0x00000033"`
+	TxnId Binary   `json:"txnId" amqp:"txn-id,mandatory"`
+}
+
+// Descriptor returns the descriptor code of declared
+func (Declared) Descriptor() uint64 {
+	return 0x33
+}
+
+// Marshal serializes declared including its descriptor
+func (declared Declared) Marshal() ([]byte, error) {
+	return Marshal(declared)
+}
+
+// Unmarshal reads declared from buffer, with or without its descriptor
+func (declared *Declared) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, declared)
+}
+
+// TransactionalState .. the transactional-state composite of the transaction coordination
+// <type name="transactional-state" class="composite" source="list" provides="delivery-state">
+//
+//	<descriptor name="amqp:transactional-state:list" code="0x00000000:0x00000034"/>
+//	<field name="txn-id" type="*" requires="txn-id" mandatory="true"/>
+//	<field name="outcome" type="*" requires="outcome"/>
+//
+// </type>
+type TransactionalState struct {
+	_       struct{} `amqp:"amqp:transactional-state:list,0x00000000:0x00000034"`
+	TxnId   Binary   `json:"txnId" amqp:"txn-id,mandatory"`
+	Outcome Outcome  `json:"outcome,omitempty" amqp:"outcome"`
+}
+
+// Descriptor returns the descriptor code of transactional-state
+func (TransactionalState) Descriptor() uint64 {
+	return 0x34
+}
+
+// Marshal serializes transactional-state including its descriptor
+func (transactionalState TransactionalState) Marshal() ([]byte, error) {
+	return Marshal(transactionalState)
+}
+
+// Unmarshal reads transactional-state from buffer, with or without its descriptor
+func (transactionalState *TransactionalState) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, transactionalState)
+}
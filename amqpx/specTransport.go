@@ -0,0 +1,389 @@
+// Code generated by specgen from xml/transport.xml. DO NOT EDIT.
+
+package amqpx
+
+// ConnectionParameters .. the open composite of the transport performatives
+// <type name="open" class="composite" source="list" provides="frame">
+//
+//	<descriptor name="amqp:open:list" code="0x00000000:0x00000010"/>
+//	<field name="container-id" type="string" mandatory="true"/>
+//	<field name="hostname" type="string"/>
+//	<field name="max-frame-size" type="uint" default="4294967295"/>
+//	<field name="channel-max" type="ushort" default="65535"/>
+//	<field name="idle-time-out" type="milliseconds"/>
+//	<field name="outgoing-locales" type="ietf-language-tag" multiple="true"/>
+//	<field name="incoming-locales" type="ietf-language-tag" multiple="true"/>
+//	<field name="offered-capabilities" type="symbol" multiple="true"/>
+//	<field name="desired-capabilities" type="symbol" multiple="true"/>
+//	<field name="properties" type="fields"/>
+//
+// </type>
+type ConnectionParameters struct {
+	_                   struct{}      `amqp:"amqp:open:list,0x00000000:0x00000010"`
+	ContainerId         string        `json:"containerId" amqp:"container-id,mandatory"`
+	Hostname            *string       `json:"hostname,omitempty" amqp:"hostname"`
+	MaxFrameSize        *uint32       `json:"maxFrameSize,omitempty" amqp:"max-frame-size,default=4294967295"`
+	ChannelMax          *uint16       `json:"channelMax,omitempty" amqp:"channel-max,default=65535"`
+	IdleTimeoutMs       *Milliseconds `json:"idleTimeoutMs,omitempty" amqp:"idle-time-out"`
+	OutgoingLocales     []Symbol      `json:"outgoingLocales,omitempty" amqp:"outgoing-locales"`
+	IncomingLocales     []Symbol      `json:"incomingLocales,omitempty" amqp:"incoming-locales"`
+	OfferedCapabilities []Symbol      `json:"offeredCapabilities,omitempty" amqp:"offered-capabilities"`
+	DesiredCapabilities []Symbol      `json:"desiredCapabilities,omitempty" amqp:"desired-capabilities"`
+	Properties          Fields        `json:"properties,omitempty" amqp:"properties"`
+}
+
+// Descriptor returns the descriptor code of open
+func (ConnectionParameters) Descriptor() uint64 {
+	return 0x10
+}
+
+// Marshal serializes open including its descriptor
+func (connectionParameters ConnectionParameters) Marshal() ([]byte, error) {
+	return Marshal(connectionParameters)
+}
+
+// Unmarshal reads open from buffer, with or without its descriptor
+func (connectionParameters *ConnectionParameters) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, connectionParameters)
+}
+
+// SessionParameters .. the begin composite of the transport performatives
+// <type name="begin" class="composite" source="list" provides="frame">
+//
+//	<descriptor name="amqp:begin:list" code="0x00000000:0x00000011"/>
+//	<field name="remote-channel" type="ushort"/>
+//	<field name="next-outgoing-id" type="transfer-number" mandatory="true"/>
+//	<field name="incoming-window" type="uint" mandatory="true"/>
+//	<field name="outgoing-window" type="uint" mandatory="true"/>
+//	<field name="handle-max" type="handle" default="4294967295"/>
+//	<field name="offered-capabilities" type="symbol" multiple="true"/>
+//	<field name="desired-capabilities" type="symbol" multiple="true"/>
+//	<field name="properties" type="fields"/>
+//
+// </type>
+type SessionParameters struct {
+	_                   struct{}       `amqp:"amqp:begin:list,0x00000000:0x00000011"`
+	RemoteChannel       *uint16        `json:"remoteChannel,omitempty" amqp:"remote-channel"`
+	NextOutgoing        TransferNumber `json:"nextOutgoing" amqp:"next-outgoing-id,mandatory"`
+	IncomingWindow      uint32         `json:"incomingWindow" amqp:"incoming-window,mandatory"`
+	OutgoingWindow      uint32         `json:"outgoingWindow" amqp:"outgoing-window,mandatory"`
+	HandleMax           *Handle        `json:"handleMax,omitempty" amqp:"handle-max,default=4294967295"`
+	OfferedCapabilities []Symbol       `json:"offeredCapabilities,omitempty" amqp:"offered-capabilities"`
+	DesiredCapabilities []Symbol       `json:"desiredCapabilities,omitempty" amqp:"desired-capabilities"`
+	Properties          Fields         `json:"properties,omitempty" amqp:"properties"`
+}
+
+// Descriptor returns the descriptor code of begin
+func (SessionParameters) Descriptor() uint64 {
+	return 0x11
+}
+
+// Marshal serializes begin including its descriptor
+func (sessionParameters SessionParameters) Marshal() ([]byte, error) {
+	return Marshal(sessionParameters)
+}
+
+// Unmarshal reads begin from buffer, with or without its descriptor
+func (sessionParameters *SessionParameters) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, sessionParameters)
+}
+
+// AttachParameters .. the attach composite of the transport performatives
+// <type name="attach" class="composite" source="list" provides="frame">
+//
+//	<descriptor name="amqp:attach:list" code="0x00000000:0x00000012"/>
+//	<field name="name" type="string" mandatory="true"/>
+//	<field name="handle" type="handle" mandatory="true"/>
+//	<field name="role" type="role" mandatory="true"/>
+//	<field name="snd-settle-mode" type="sender-settle-mode" default="mixed"/>
+//	<field name="rcv-settle-mode" type="receiver-settle-mode" default="first"/>
+//	<field name="source" type="*" requires="source"/>
+//	<field name="target" type="*" requires="target"/>
+//	<field name="unsettled" type="map"/>
+//	<field name="incomplete-unsettled" type="boolean" default="false"/>
+//	<field name="initial-delivery-count" type="sequence-no"/>
+//	<field name="max-message-size" type="ulong"/>
+//	<field name="offered-capabilities" type="symbol" multiple="true"/>
+//	<field name="desired-capabilities" type="symbol" multiple="true"/>
+//	<field name="properties" type="fields"/>
+//
+// </type>
+type AttachParameters struct {
+	_                    struct{}                 `amqp:"amqp:attach:list,0x00000000:0x00000012"`
+	Name                 string                   `json:"name" amqp:"name,mandatory"`
+	Handle               Handle                   `json:"handle" amqp:"handle,mandatory"`
+	Role                 RoleChoice               `json:"role" amqp:"role,mandatory"`
+	SndSettleMode        *SenderSettleModeChoice  `json:"sndSettleMode,omitempty" amqp:"snd-settle-mode,default=2"`
+	RcvSettleMode        ReceiverSettleModeChoice `json:"rcvSettleMode,omitempty" amqp:"rcv-settle-mode,default=0"`
+	Source               *Source                  `json:"source,omitempty" amqp:"source"`
+	Target               LinkTarget               `json:"target,omitempty" amqp:"target"`
+	Unsettled            Map                      `json:"unsettled,omitempty" amqp:"unsettled"`
+	IncompleteUnsettled  BooleanChoice            `json:"incompleteUnsettled,omitempty" amqp:"incomplete-unsettled,default=false"`
+	InitialDeliveryCount *SequenceNo              `json:"initialDeliveryCount,omitempty" amqp:"initial-delivery-count"`
+	MaxMessageSize       *uint64                  `json:"maxMessageSize,omitempty" amqp:"max-message-size"`
+	OfferedCapabilities  []Symbol                 `json:"offeredCapabilities,omitempty" amqp:"offered-capabilities"`
+	DesiredCapabilities  []Symbol                 `json:"desiredCapabilities,omitempty" amqp:"desired-capabilities"`
+	Properties           Fields                   `json:"properties,omitempty" amqp:"properties"`
+}
+
+// Descriptor returns the descriptor code of attach
+func (AttachParameters) Descriptor() uint64 {
+	return 0x12
+}
+
+// Marshal serializes attach including its descriptor
+func (attachParameters AttachParameters) Marshal() ([]byte, error) {
+	return Marshal(attachParameters)
+}
+
+// Unmarshal reads attach from buffer, with or without its descriptor
+func (attachParameters *AttachParameters) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, attachParameters)
+}
+
+// FlowParameters .. the flow composite of the transport performatives
+// <type name="flow" class="composite" source="list" provides="frame">
+//
+//	<descriptor name="amqp:flow:list" code="0x00000000:0x00000013"/>
+//	<field name="next-incoming-id" type="transfer-number"/>
+//	<field name="incoming-window" type="uint" mandatory="true"/>
+//	<field name="next-outgoing-id" type="transfer-number" mandatory="true"/>
+//	<field name="outgoing-window" type="uint" mandatory="true"/>
+//	<field name="handle" type="handle"/>
+//	<field name="delivery-count" type="sequence-no"/>
+//	<field name="link-credit" type="uint"/>
+//	<field name="available" type="uint"/>
+//	<field name="drain" type="boolean" default="false"/>
+//	<field name="echo" type="boolean" default="false"/>
+//	<field name="properties" type="fields"/>
+//
+// </type>
+type FlowParameters struct {
+	_              struct{}        `amqp:"amqp:flow:list,0x00000000:0x00000013"`
+	NextIncoming   *TransferNumber `json:"nextIncoming,omitempty" amqp:"next-incoming-id"`
+	IncomingWindow uint32          `json:"incomingWindow" amqp:"incoming-window,mandatory"`
+	NextOutgoing   TransferNumber  `json:"nextOutgoing" amqp:"next-outgoing-id,mandatory"`
+	OutgoingWindow uint32          `json:"outgoingWindow" amqp:"outgoing-window,mandatory"`
+	Handle         *Handle         `json:"handle,omitempty" amqp:"handle"`
+	DeliveryCount  *SequenceNo     `json:"deliveryCount,omitempty" amqp:"delivery-count"`
+	LinkCredit     *uint32         `json:"linkCredit,omitempty" amqp:"link-credit"`
+	Available      *uint32         `json:"available,omitempty" amqp:"available"`
+	Drain          BooleanChoice   `json:"drain,omitempty" amqp:"drain,default=false"`
+	Echo           BooleanChoice   `json:"echo,omitempty" amqp:"echo,default=false"`
+	Properties     Fields          `json:"properties,omitempty" amqp:"properties"`
+}
+
+// Descriptor returns the descriptor code of flow
+func (FlowParameters) Descriptor() uint64 {
+	return 0x13
+}
+
+// Marshal serializes flow including its descriptor
+func (flowParameters FlowParameters) Marshal() ([]byte, error) {
+	return Marshal(flowParameters)
+}
+
+// Unmarshal reads flow from buffer, with or without its descriptor
+func (flowParameters *FlowParameters) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, flowParameters)
+}
+
+// TransferParameters .. the transfer composite of the transport performatives
+// <type name="transfer" class="composite" source="list" provides="frame">
+//
+//	<descriptor name="amqp:transfer:list" code="0x00000000:0x00000014"/>
+//	<field name="handle" type="handle" mandatory="true"/>
+//	<field name="delivery-id" type="delivery-number"/>
+//	<field name="delivery-tag" type="delivery-tag"/>
+//	<field name="message-format" type="message-format"/>
+//	<field name="settled" type="boolean"/>
+//	<field name="more" type="boolean" default="false"/>
+//	<field name="rcv-settle-mode" type="receiver-settle-mode"/>
+//	<field name="state" type="*" requires="delivery-state"/>
+//	<field name="resume" type="boolean" default="false"/>
+//	<field name="aborted" type="boolean" default="false"/>
+//	<field name="batchable" type="boolean" default="false"/>
+//
+// </type>
+type TransferParameters struct {
+	_             struct{}                  `amqp:"amqp:transfer:list,0x00000000:0x00000014"`
+	Handle        Handle                    `json:"handle" amqp:"handle,mandatory"`
+	DeliveryId    *DeliveryNumber           `json:"deliveryId,omitempty" amqp:"delivery-id"`
+	DeliveryTag   DeliveryTag               `json:"deliveryTag,omitempty" amqp:"delivery-tag"`
+	MessageFormat *MessageFormat            `json:"messageFormat,omitempty" amqp:"message-format"`
+	Settled       *BooleanChoice            `json:"settled,omitempty" amqp:"settled"`
+	More          BooleanChoice             `json:"more,omitempty" amqp:"more,default=false"`
+	RcvSettleMode *ReceiverSettleModeChoice `json:"rcvSettleMode,omitempty" amqp:"rcv-settle-mode"`
+	State         DeliveryState             `json:"state,omitempty" amqp:"state"`
+	Resume        BooleanChoice             `json:"resume,omitempty" amqp:"resume,default=false"`
+	Aborted       BooleanChoice             `json:"aborted,omitempty" amqp:"aborted,default=false"`
+	Batchable     BooleanChoice             `json:"batchable,omitempty" amqp:"batchable,default=false"`
+}
+
+// Descriptor returns the descriptor code of transfer
+func (TransferParameters) Descriptor() uint64 {
+	return 0x14
+}
+
+// Marshal serializes transfer including its descriptor
+func (transferParameters TransferParameters) Marshal() ([]byte, error) {
+	return Marshal(transferParameters)
+}
+
+// Unmarshal reads transfer from buffer, with or without its descriptor
+func (transferParameters *TransferParameters) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, transferParameters)
+}
+
+// DispositionParameters .. the disposition composite of the transport performatives
+// <type name="disposition" class="composite" source="list" provides="frame">
+//
+//	<descriptor name="amqp:disposition:list" code="0x00000000:0x00000015"/>
+//	<field name="role" type="role" mandatory="true"/>
+//	<field name="first" type="delivery-number" mandatory="true"/>
+//	<field name="last" type="delivery-number"/>
+//	<field name="settled" type="boolean" default="false"/>
+//	<field name="state" type="*" requires="delivery-state"/>
+//	<field name="batchable" type="boolean" default="false"/>
+//
+// </type>
+type DispositionParameters struct {
+	_         struct{}        `amqp:"amqp:disposition:list,0x00000000:0x00000015"`
+	Role      RoleChoice      `json:"role" amqp:"role,mandatory"`
+	First     DeliveryNumber  `json:"first" amqp:"first,mandatory"`
+	Last      *DeliveryNumber `json:"last,omitempty" amqp:"last"`
+	Settled   BooleanChoice   `json:"settled,omitempty" amqp:"settled,default=false"`
+	State     DeliveryState   `json:"state,omitempty" amqp:"state"`
+	Batchable BooleanChoice   `json:"batchable,omitempty" amqp:"batchable,default=false"`
+}
+
+// Descriptor returns the descriptor code of disposition
+func (DispositionParameters) Descriptor() uint64 {
+	return 0x15
+}
+
+// Marshal serializes disposition including its descriptor
+func (dispositionParameters DispositionParameters) Marshal() ([]byte, error) {
+	return Marshal(dispositionParameters)
+}
+
+// Unmarshal reads disposition from buffer, with or without its descriptor
+func (dispositionParameters *DispositionParameters) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, dispositionParameters)
+}
+
+// DetachParameters .. the detach composite of the transport performatives
+// <type name="detach" class="composite" source="list" provides="frame">
+//
+//	<descriptor name="amqp:detach:list" code="0x00000000:0x00000016"/>
+//	<field name="handle" type="handle" mandatory="true"/>
+//	<field name="closed" type="boolean" default="false"/>
+//	<field name="error" type="error"/>
+//
+// </type>
+type DetachParameters struct {
+	_      struct{}      `amqp:"amqp:detach:list,0x00000000:0x00000016"`
+	Handle Handle        `json:"handle" amqp:"handle,mandatory"`
+	Closed BooleanChoice `json:"closed,omitempty" amqp:"closed,default=false"`
+	Error  *Error        `json:"error,omitempty" amqp:"error"`
+}
+
+// Descriptor returns the descriptor code of detach
+func (DetachParameters) Descriptor() uint64 {
+	return 0x16
+}
+
+// Marshal serializes detach including its descriptor
+func (detachParameters DetachParameters) Marshal() ([]byte, error) {
+	return Marshal(detachParameters)
+}
+
+// Unmarshal reads detach from buffer, with or without its descriptor
+func (detachParameters *DetachParameters) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, detachParameters)
+}
+
+// EndParameters .. the end composite of the transport performatives
+// <type name="end" class="composite" source="list" provides="frame">
+//
+//	<descriptor name="amqp:end:list" code="0x00000000:0x00000017"/>
+//	<field name="error" type="error"/>
+//
+// </type>
+type EndParameters struct {
+	_     struct{} `amqp:"amqp:end:list,0x00000000:0x00000017"`
+	Error *Error   `json:"error,omitempty" amqp:"error"`
+}
+
+// Descriptor returns the descriptor code of end
+func (EndParameters) Descriptor() uint64 {
+	return 0x17
+}
+
+// Marshal serializes end including its descriptor
+func (endParameters EndParameters) Marshal() ([]byte, error) {
+	return Marshal(endParameters)
+}
+
+// Unmarshal reads end from buffer, with or without its descriptor
+func (endParameters *EndParameters) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, endParameters)
+}
+
+// CloseParameters .. the close composite of the transport performatives
+// <type name="close" class="composite" source="list" provides="frame">
+//
+//	<descriptor name="amqp:close:list" code="0x00000000:0x00000018"/>
+//	<field name="error" type="error"/>
+//
+// </type>
+type CloseParameters struct {
+	_     struct{} `amqp:"amqp:close:list,0x00000000:0x00000018"`
+	Error *Error   `json:"error,omitempty" amqp:"error"`
+}
+
+// Descriptor returns the descriptor code of close
+func (CloseParameters) Descriptor() uint64 {
+	return 0x18
+}
+
+// Marshal serializes close including its descriptor
+func (closeParameters CloseParameters) Marshal() ([]byte, error) {
+	return Marshal(closeParameters)
+}
+
+// Unmarshal reads close from buffer, with or without its descriptor
+func (closeParameters *CloseParameters) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, closeParameters)
+}
+
+// Error .. the error composite of the transport definitions
+// <type name="error" class="composite" source="list">
+//
+//	<descriptor name="amqp:error:list" code="0x00000000:0x0000001d"/>
+//	<field name="condition" type="symbol" requires="error-condition" mandatory="true"/>
+//	<field name="description" type="string"/>
+//	<field name="info" type="fields"/>
+//
+// </type>
+type Error struct {
+	_           struct{} `amqp:"amqp:error:list,0x00000000:0x0000001d"`
+	Condition   Symbol   `json:"condition" amqp:"condition,mandatory"`
+	Description *string  `json:"description,omitempty" amqp:"description"`
+	Info        Fields   `json:"info,omitempty" amqp:"info"`
+}
+
+// Descriptor returns the descriptor code of error
+func (Error) Descriptor() uint64 {
+	return 0x1d
+}
+
+// Marshal serializes error including its descriptor
+func (e Error) Marshal() ([]byte, error) {
+	return Marshal(e)
+}
+
+// Unmarshal reads error from buffer, with or without its descriptor
+func (e *Error) Unmarshal(buffer []byte) (bytesUsed uint32, err error) {
+	return Unmarshal(buffer, e)
+}
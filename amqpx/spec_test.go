@@ -0,0 +1,96 @@
+package amqpx
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestSpecCompositeRoundTrip(t *testing.T) {
+	composites := []struct {
+		name      string
+		value     interface{}
+		described byte
+	}{
+		{"detach", &DetachParameters{Handle: 2, Closed: true, Error: &Error{Condition: ErrorLinkDetachForced, Description: ptrTo("bye")}}, 0x16},
+		{"header", &MessageHeader{Durable: true, Priority: ptrTo(byte(7)), Ttl: ptrTo[Milliseconds](1000), DeliveryCount: 2}, 0x70},
+		{"source", &Source{Address: ptrTo("queue"), Durable: TerminusDurabilityUnsettled, ExpiryPolicy: ptrTo(TerminusExpiryNever)}, 0x28},
+		{"delete-on-close", &DeleteOnClose{}, 0x2b},
+		{"sasl-init", &SaslInit{Mechanism: "PLAIN", InitialResponse: Binary("\x00user\x00pass")}, 0x41},
+		{"sasl-outcome", &SaslOutcome{Code: SaslCodeAuth}, 0x44},
+		{"coordinator", &Coordinator{Capabilities: []Symbol{"amqp:local-transactions"}}, 0x30},
+		{"declare", &Declare{}, 0x31},
+		{"discharge", &Discharge{TxnId: Binary{0x01, 0x02}, Fail: ptrTo[BooleanChoice](true)}, 0x32},
+		{"declared", &Declared{TxnId: Binary{0x01, 0x02}}, 0x33},
+	}
+
+	for _, c := range composites {
+		buf, err := Marshal(c.value)
+		if err != nil {
+			t.Fatalf("%s\nMarshal %s was incorrect, expected no errors", err.Error(), c.name)
+		}
+		if !bytes.Equal(buf[:3], []byte{0x00, 0x53, c.described}) {
+			t.Errorf("Marshal %s was incorrect descriptor, \n\texpected: \"00 53 %02x\" \n\tgot:\"% x\"", c.name, c.described, buf[:3])
+		}
+
+		parsed := reflect.New(reflect.TypeOf(c.value).Elem())
+		bytesUsed, err := Unmarshal(buf, parsed.Interface())
+		if err != nil {
+			t.Fatalf("%s\nUnmarshal %s was incorrect, expected no errors", err.Error(), c.name)
+		}
+		if bytesUsed != uint32(len(buf)) {
+			t.Errorf("Unmarshal %s was incorrect bytesUsed, \n\texpected: \"%d\" \n\tgot:\"%d\"", c.name, len(buf), bytesUsed)
+		}
+		if !reflect.DeepEqual(parsed.Interface(), c.value) {
+			t.Errorf("%s round trip was incorrect, \n\texpected: \"%#v\" \n\tgot:\"%#v\"", c.name, c.value, parsed.Interface())
+		}
+	}
+}
+
+func TestSpecCompositeZeroValues(t *testing.T) {
+	// a zero is a Value of its own, only nil is sent as null
+	composites := []interface{}{
+		&DispositionParameters{Role: true, First: 0, Last: ptrTo[DeliveryNumber](0)},
+		&MessageHeader{Priority: ptrTo(byte(0)), Ttl: ptrTo[Milliseconds](0)},
+		&MessageProperties{GroupSequence: ptrTo[SequenceNo](0), Subject: ptrTo("")},
+		&Modified{DeliveryFailed: ptrTo[BooleanChoice](false)},
+	}
+
+	for _, value := range composites {
+		buf, err := Marshal(value)
+		if err != nil {
+			t.Fatalf("%s\nMarshal %T was incorrect, expected no errors", err.Error(), value)
+		}
+		parsed := reflect.New(reflect.TypeOf(value).Elem())
+		if _, err = Unmarshal(buf, parsed.Interface()); err != nil {
+			t.Fatalf("%s\nUnmarshal %T was incorrect, expected no errors", err.Error(), value)
+		}
+		if !reflect.DeepEqual(parsed.Interface(), value) {
+			t.Errorf("%T round trip was incorrect, \n\texpected: \"%+v\" \n\tgot:\"%+v\"", value, value, parsed.Interface())
+		}
+	}
+}
+
+func TestSpecCompositeDefaults(t *testing.T) {
+	// an empty header and an empty source take the defaults of the spec
+	var header MessageHeader
+	if _, err := Unmarshal([]byte{0x00, 0x53, 0x70, 0x45}, &header); err != nil {
+		t.Fatalf("%s\nUnmarshal header was incorrect, expected no errors", err.Error())
+	}
+	if header.Priority == nil || *header.Priority != 4 || header.Ttl != nil {
+		t.Errorf("Unmarshal header was incorrect defaults, \n\texpected: \"4 <nil>\" \n\tgot:\"%v %v\"", header.Priority, header.Ttl)
+	}
+
+	var source Source
+	if _, err := Unmarshal([]byte{0x00, 0x53, 0x28, 0x45}, &source); err != nil {
+		t.Fatalf("%s\nUnmarshal source was incorrect, expected no errors", err.Error())
+	}
+	if source.ExpiryPolicy == nil || *source.ExpiryPolicy != TerminusExpirySessionEnd {
+		t.Errorf("Unmarshal source was incorrect ExpiryPolicy, \n\texpected: \"session-end\" \n\tgot:\"%v\"", source.ExpiryPolicy)
+	}
+
+	// a transaction id is mandatory
+	if _, err := Marshal(&Declared{}); err == nil {
+		t.Errorf("Marshal declared was incorrect, expected an error for a missing txn-id")
+	}
+}
@@ -0,0 +1,115 @@
+package amqpx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+)
+
+// IANA ports of AMQP, amqps is TLS from the first byte on
+const (
+	PortAMQP  = 5672
+	PortAMQPS = 5671
+)
+
+// NewTLSServerConfig loads the certificate and key of the server. With caFile the
+// certificates of clients are verified against it when they send one, set ClientAuth
+// To tls.RequireAndVerifyClientCert To insist on them
+func NewTLSServerConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, errors.New(err.Error() + "\nNewTLSServerConfig() failed loading the certificate")
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		if config.ClientCAs, err = loadCertPool(caFile); err != nil {
+			return nil, err
+		}
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
+
+// NewTLSClientConfig verifies the server against caFile, the system roots when empty.
+// certFile and keyFile are the client certificate, e.g. for SASL EXTERNAL, and may be empty
+func NewTLSClientConfig(serverName, certFile, keyFile, caFile string) (*tls.Config, error) {
+	config := &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS12}
+	if certFile != "" || keyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.New(err.Error() + "\nNewTLSClientConfig() failed loading the client certificate")
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	if caFile != "" {
+		var err error
+		if config.RootCAs, err = loadCertPool(caFile); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, errors.New(err.Error() + "\nloadCertPool() failed reading the CA certificates")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("amqpx: no certificate found in %s", caFile)
+	}
+	return pool, nil
+}
+
+// UpgradeTLSClient runs the in-band TLS upgrade of a client: it sends the TLS protocol
+// header, reads the one of the server and runs the handshake. The AMQP or SASL protocol
+// header is then sent over the returned connection. Spec section 5.2 TLS
+func UpgradeTLSClient(conn net.Conn, config *tls.Config) (*tls.Conn, error) {
+	if err := NewFrameWriter(conn, 0).WriteProtocolHeader(ProtocolIdTLS); err != nil {
+		return nil, err
+	}
+	protocolVersion, err := NewFrameReader(conn, 0).ReadProtocolHeader()
+	if err != nil {
+		return nil, err
+	}
+	if protocolVersion.ProtocolId != ProtocolIdTLS {
+		return nil, fmt.Errorf("amqpx: server answered the TLS header with protocol id %d", protocolVersion.ProtocolId)
+	}
+	tlsConn := tls.Client(conn, config)
+	if err = tlsConn.Handshake(); err != nil {
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// UpgradeTLSServer answers the TLS protocol header the server has read with its own
+// and runs the handshake. The client then starts over with a protocol header on the
+// returned connection
+func UpgradeTLSServer(conn net.Conn, config *tls.Config) (*tls.Conn, error) {
+	if err := NewFrameWriter(conn, 0).WriteProtocolHeader(ProtocolIdTLS); err != nil {
+		return nil, err
+	}
+	tlsConn := tls.Server(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// PeerCertificates returns the verified certificate chain of the peer of a TLS connection,
+// the peer certificate first. It is nil for other connections and unverified certificates
+func PeerCertificates(conn net.Conn) []*x509.Certificate {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	state := tlsConn.ConnectionState()
+	if len(state.VerifiedChains) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0]
+}
@@ -0,0 +1,106 @@
+package amqpx
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate writes a certificate for name signed by parent, self signed when
+// parent is nil, and its key To dir. It returns the certificate and key
+func writeTestCertificate(t *testing.T, dir string, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, name+".pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	certificate, _ := x509.ParseCertificate(der)
+	return certificate, key
+}
+
+func TestTLSUpgradeAndExternal(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := writeTestCertificate(t, dir, "ca", nil, nil)
+	writeTestCertificate(t, dir, "server", ca, caKey)
+	writeTestCertificate(t, dir, "client", ca, caKey)
+	file := func(name string) string { return filepath.Join(dir, name) }
+
+	serverConfig, err := NewTLSServerConfig(file("server.pem"), file("server.key"), file("ca.pem"))
+	if err != nil {
+		t.Fatalf("%s\nNewTLSServerConfig was incorrect, expected no errors", err.Error())
+	}
+	clientConfig, err := NewTLSClientConfig("server", file("client.pem"), file("client.key"), file("ca.pem"))
+	if err != nil {
+		t.Fatalf("%s\nNewTLSClientConfig was incorrect, expected no errors", err.Error())
+	}
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+	type result struct {
+		identity string
+		err      error
+	}
+	done := make(chan result)
+	go func() {
+		protocolVersion, err := NewFrameReader(serverConn, 0).ReadProtocolHeader()
+		if err != nil || protocolVersion.ProtocolId != ProtocolIdTLS {
+			done <- result{"", fmt.Errorf("protocol id %d %v", protocolVersion.ProtocolId, err)}
+			return
+		}
+		tlsConn, err := UpgradeTLSServer(serverConn, serverConfig)
+		if err != nil {
+			done <- result{"", err}
+			return
+		}
+		authenticator := func(credentials SaslCredentials) (string, error) { return credentials.Username, nil }
+		identity, err := NegotiateSaslServer(NewFrameReader(tlsConn, MinMaxFrameSize), NewFrameWriter(tlsConn, MinMaxFrameSize),
+			NewSaslExternalServer(PeerCertificates(tlsConn), authenticator))
+		done <- result{identity, err}
+	}()
+
+	tlsConn, err := UpgradeTLSClient(clientConn, clientConfig)
+	if err != nil {
+		t.Fatalf("%s\nUpgradeTLSClient was incorrect, expected no errors", err.Error())
+	}
+	if certificates := PeerCertificates(tlsConn); len(certificates) == 0 || certificates[0].Subject.CommonName != "server" {
+		t.Errorf("PeerCertificates was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\"", "server", certificates)
+	}
+	err = NegotiateSaslClient(NewFrameReader(tlsConn, MinMaxFrameSize), NewFrameWriter(tlsConn, MinMaxFrameSize), "server", NewSaslExternalClient(""))
+	server := <-done
+	if err != nil || server.err != nil || server.identity != "client" {
+		t.Errorf("NegotiateSasl (EXTERNAL) was incorrect, \n\texpected: \"%v\" \n\tgot:\"%v\" %v %v", "client", server.identity, server.err, err)
+	}
+}
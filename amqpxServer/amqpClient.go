@@ -1,20 +1,28 @@
 package main
 
 import (
+	"crypto/x509"
 	"net"
 	"time"
 
 	"github.com/ewk-elwa/go-amqpx/amqpx"
 )
 
+// linkKey names a link of the client, handles are chosen per session
+type linkKey struct {
+	channel uint16
+	handle  amqpx.Handle
+}
+
 type amqpConnInfo struct {
 	openParams  amqpx.ConnectionParameters
-	session     amqpx.SessionParameters
 	attach      amqpx.AttachParameters
 	disposition amqpx.DispositionParameters
 	unsettled   map[uint32]uint32
 	flow        amqpx.FlowParameters
 	transfer    amqpx.TransferParameters
+	// deliveries joins the transfers received on each link
+	deliveries map[linkKey]*amqpx.DeliveryAssembler
 	// Stats
 	messageCount uint64
 }
@@ -22,10 +30,30 @@ type amqpConnInfo struct {
 type amqpClient struct {
 	conn        net.Conn
 	containerID string
+	// secured is set once the connection runs over TLS, peerCertificates holds the
+	// verified certificate chain of the client when it sent one
+	secured          bool
+	peerCertificates []*x509.Certificate
+	// identity is who the client authenticated as with SASL, empty without SASL
+	identity    string
 	hostname    string
 	channelMax  uint16
 	idleTimeout uint32
-	readTimeout time.Duration
-	rx          amqpConnInfo
-	tx          amqpConnInfo
+	// maxFrameSize is the largest frame we accept, sent in our open
+	maxFrameSize uint32
+	// linkCredit is granted To each link the client attaches as a sender
+	linkCredit uint32
+	// sessionWindow is the incoming and outgoing window of each session the client begins
+	sessionWindow uint32
+	// maxMessageSize limits the messages we receive, 0 for no limit
+	maxMessageSize uint64
+	readTimeout    time.Duration
+	// frames reads the frames of the client, writer writes ours
+	frames *amqpx.FrameReader
+	writer *amqpx.FrameWriter
+	// connection tracks the connection state of the frames on frames and writer and
+	// the sessions of the client
+	connection *amqpx.Connection
+	rx         amqpConnInfo
+	tx         amqpConnInfo
 }
@@ -1,12 +1,11 @@
 package main
 
 import (
-	"bytes"
-	"encoding/binary"
+	"crypto/tls"
 	"errors"
-	"fmt"
 	"math/rand"
 	"net"
+	"runtime"
 	"strconv"
 	"time"
 
@@ -23,372 +22,312 @@ const (
 	lengthSize = 8
 )
 
-func serializeStruct(myStruct interface{}) []byte {
-	var binBuf bytes.Buffer
-	binary.Write(&binBuf, binary.BigEndian, myStruct)
-	return binBuf.Bytes()
-}
-
 // Read reads up 'til buffer length
 func (client *amqpClient) Read(rxBuf []byte) (n int, err error) {
 	client.conn.SetReadDeadline(time.Now().Add(client.readTimeout))
 	return client.conn.Read(rxBuf)
 }
 
-// ReadUntilFull reads until the rx buffer is full
-func (client *amqpClient) ReadUntilFull(rxBuf []byte) (n int, err error) {
-	byteCount := 0
-	size := len(rxBuf)
-	for byteCount < size {
-		client.conn.SetReadDeadline(time.Now().Add(client.readTimeout))
-		bytesRead, err := client.conn.Read(rxBuf[byteCount:])
-		byteCount += bytesRead
-		if err != nil || byteCount >= size {
-			return byteCount, err
-		}
-	}
-	return byteCount, err
-}
-
 func (client *amqpClient) Write(rxBuf []byte) (n int, err error) {
 	client.conn.SetWriteDeadline(time.Now().Add(client.readTimeout))
 	return client.conn.Write(rxBuf)
 }
 
-func handleAmqpVersion(client amqpClient) (err error) {
+func handleAmqpVersion(client *amqpClient) (err error) {
 	// Make a buffer to hold the Version message
 	log.Debug("handleAmqpVersion():Entered")
-	rxBuf := make([]byte, sizeAmqpVersionStruct)
-
-	_, err = client.ReadUntilFull(rxBuf)
+	protocolVersion, err := client.frames.ReadProtocolHeader()
 	if err != nil {
 		log.Debug("handleAmqpVersion():Error reading AMQP Version:", err.Error())
 		return err
 	}
 
-	protocolVersion, _, err := amqpx.ParseProtocolHeader(rxBuf)
-	if err != nil {
-		return err
+	if protocolVersion.ProtocolId == amqpx.ProtocolIdTLS && serverTLSConfig != nil && !client.secured {
+		if err = handleTLS(client); err != nil {
+			return err
+		}
+		// the client starts over with a protocol header over TLS
+		return handleAmqpVersion(client)
 	}
 
-	myAmqpVersion := serializeStruct(amqpVersion0100)
-	recvAmqpVersion := serializeStruct(protocolVersion)
-	if !bytes.Equal(myAmqpVersion, recvAmqpVersion) {
-		fmt.Printf("handleAmqpVersion():Error bad AMQP Version received: % x", recvAmqpVersion)
-		return errors.New("Bad AMQP Protocol Version")
+	if mechanisms := saslMechanisms(client); len(mechanisms) > 0 && client.identity == "" {
+		if protocolVersion.ProtocolId != amqpx.ProtocolIdSASL {
+			// our SASL header tells the client that it has To authenticate first
+			client.writer.WriteProtocolHeader(amqpx.ProtocolIdSASL)
+			return errors.New("handleAmqpVersion():Error client did not authenticate")
+		}
+		if err = handleSasl(client, mechanisms); err != nil {
+			return err
+		}
+		// the AMQP protocol header follows the SASL layer
+		return handleAmqpVersion(client)
+	}
+
+	// the connection answers an unsupported header with the one we support
+	if err = client.connection.HeaderReceived(protocolVersion); err != nil {
+		log.Debug("handleAmqpVersion():Error bad AMQP Version received:", err.Error())
+		return err
 	}
 	return nil
 }
 
-func handleAmqpOpen(client amqpClient) (err error) {
-	log.Debug("handleAmqpOpen():Entered")
-	// First read 4 bytes of length
-	amqpLengthBuf := make([]byte, 4)
-	_, err = client.ReadUntilFull(amqpLengthBuf)
-	if err != nil {
-		log.Debug("handleAmqpOpen():Error reading AMQP Length: ", err.Error())
+// sendAmqpVersionAndOpen answers the header of the client with ours and opens the
+// connection, without waiting for the open of the client
+func sendAmqpVersionAndOpen(client *amqpClient) error {
+	log.Debug("sendAmqpVersionAndOpen():Entered")
+	if err := client.connection.SendHeader(); err != nil {
 		return err
 	}
-	amqpLength := binary.BigEndian.Uint32(amqpLengthBuf[:])
 
-	frameBuf := make([]byte, amqpLength)
-	copy(frameBuf[:], amqpLengthBuf)
-	_, err = client.Read(frameBuf[4:])
-	if err != nil {
-		log.Debug("handleAmqpOpen():Error reading AMQP Open Frame: ", err.Error())
-		return err
+	client.tx.openParams = amqpx.ConnectionParameters{
+		ContainerId:   client.containerID,
+		Hostname:      &client.hostname,
+		MaxFrameSize:  &client.maxFrameSize,
+		ChannelMax:    &client.channelMax,
+		IdleTimeoutMs: (*amqpx.Milliseconds)(&client.idleTimeout),
+		Properties: amqpx.Fields{
+			amqpx.PropertyProduct:  serverProduct,
+			amqpx.PropertyVersion:  serverVersion,
+			amqpx.PropertyPlatform: runtime.Version(),
+		},
 	}
+	return sendPerformative(client, 0, &client.tx.openParams)
+}
 
-	_, bytesUsed, performative, err := amqpx.ParseFraming(frameBuf)
-	if err != nil {
-		log.Debug("handleAmqpOpen():Error OPEN performative:", err.Error())
-		return err
+// grantCredit gives link credit To a client that attached as a sender, so that it
+// can start sending. Receivers of the client get no messages from us
+func grantCredit(client *amqpClient, session *amqpx.Session, attach amqpx.AttachParameters) error {
+	if attach.Role != amqpx.RoleSender {
+		return nil
 	}
+	// a sender always sends its initial-delivery-count
+	var deliveryCount amqpx.SequenceNo
+	if attach.InitialDeliveryCount != nil {
+		deliveryCount = *attach.InitialDeliveryCount
+	}
+	return sendSessionPerformative(session, linkFlow(client, session, attach.Handle, deliveryCount))
+}
 
-	if performative == amqpx.PerfOpen {
-		connParameters, _, err := amqpx.ParsePerformativeOpen(frameBuf[bytesUsed:])
+// linkFlow returns a flow of session granting the link credit of the client on handle,
+// counting from the delivery-count of the sender
+func linkFlow(client *amqpClient, session *amqpx.Session, handle amqpx.Handle, deliveryCount amqpx.SequenceNo) *amqpx.FlowParameters {
+	flow := session.Flow()
+	linkCredit := client.linkCredit
+	flow.Handle, flow.DeliveryCount, flow.LinkCredit = &handle, &deliveryCount, &linkCredit
+	return flow
+}
 
-		if err != nil {
-			log.Debug("handleAmqpOpen():ReadOpenPerformative was incorrect, expected no errors", err.Error())
-		} else {
-			log.Debug("connection parameters")
-			log.Debug("\tcontainer-id:", connParameters.ContainerId)
-			log.Debug("\thostname:", connParameters.Hostname)
-			log.Debug("\tmaxFrameSize:", connParameters.MaxFrameSize)
-			log.Debug("\tchannelMax:", connParameters.ChannelMax)
-			log.Debug("\tidleTimeoutMs", connParameters.IdleTimeoutMs)
-		}
-	} else {
-		return errors.New("handleAmqpOpen():Error did not read expected OPEN performative")
+// attachReply completes the link the client attached, with the opposite role and
+// the same termini so that the client sees the terminus it asked for
+func attachReply(client *amqpClient, channel uint16, attach amqpx.AttachParameters) *amqpx.AttachParameters {
+	if attach.Role == amqpx.RoleSender {
+		// we receive on this link, our max-message-size limits the deliveries of the client
+		client.deliveryAssembler(channel, attach.Handle).MaxMessageSize = client.maxMessageSize
+	}
+	return &amqpx.AttachParameters{
+		Name:           attach.Name,
+		Handle:         attach.Handle,
+		Role:           !attach.Role,
+		SndSettleMode:  attach.SndSettleMode,
+		RcvSettleMode:  attach.RcvSettleMode,
+		Source:         attach.Source,
+		Target:         attach.Target,
+		MaxMessageSize: &client.maxMessageSize,
 	}
-	sendAmqpVersionAndOpen(client)
-	return nil
 }
 
-func sendAmqpVersionAndOpen(client amqpClient) {
-	log.Debug("sendAmqpVersionAndOpen():Entered")
-	versionBuf := serializeStruct(amqpVersion0100)
-
-	// Build arguments block
-	arguments := amqpArgumentsStruct{
-		unknownField:     unknownArgumentsField,
-		a1TagContainerID: a1TagContainerID,
-		containerLength:  byte(len(client.containerID)),
-		containerID:      client.containerID,
-		a1TagHostname:    a1TagHostname,
-		hostnameLength:   byte(len(client.hostname)),
-		hostname:         client.hostname,
-		tagChannelMax:    tagChannelMax,
-		channelMax:       client.channelMax,
-		tagIdleTimeout:   tagIdleTimeout,
-		idleTimeout:      client.idleTimeout,
+// deliveryAssembler returns the assembler of the deliveries received on handle of the
+// session the client sends on channel
+func (client *amqpClient) deliveryAssembler(channel uint16, handle amqpx.Handle) *amqpx.DeliveryAssembler {
+	if client.rx.deliveries == nil {
+		client.rx.deliveries = map[linkKey]*amqpx.DeliveryAssembler{}
 	}
-	argumentsBuf := serializeArgumentsStruct(arguments)
-	fmt.Printf("sendAmqpVersionAndOpen():serializeArgumentsStruct(%v): % x\n", len(argumentsBuf), argumentsBuf)
-
-	// Build header block last since it needs the length
-	var frameLength = uint32(sizeAmqpHeaderStruct + len(argumentsBuf) + len(amqpEndFrame))
-
-	writeBuf := make([]byte, frameLength+uint32(len(versionBuf)))
-
-	hdrBuf := serializeStruct(amqpHeaderStruct{frameLength, doff, theType, 0, perfTag, amqpOpen})
-
-	inx := 0
-	copy(writeBuf[inx:], versionBuf)
-	inx += len(versionBuf)
-	copy(writeBuf[inx:], hdrBuf)
-	inx += len(hdrBuf)
-	copy(writeBuf[inx:], argumentsBuf)
-	inx += len(argumentsBuf)
-	copy(writeBuf[inx:], amqpEndFrame)
-
-	client.Write(writeBuf)
+	assembler, ok := client.rx.deliveries[linkKey{channel, handle}]
+	if !ok {
+		assembler = &amqpx.DeliveryAssembler{}
+		client.rx.deliveries[linkKey{channel, handle}] = assembler
+	}
+	return assembler
 }
 
-func handleAmqpBeginAttachFlow(client amqpClient) (err error) {
-	log.Debug("handleAmqpBeginAttachFlow():Entered")
-
-	for i := 0; i < 4; i++ {
-		// First read 4 bytes of length
-		amqpLengthBuf := make([]byte, 4)
-		_, err := client.Read(amqpLengthBuf)
-		if err != nil {
-			log.Debug("handleAmqpBeginAttachFlow():Error reading AMQP Length: ", err.Error())
-			return err
-		}
-		amqpLength := binary.BigEndian.Uint32(amqpLengthBuf[:])
-
-		frameBuf := make([]byte, amqpLength)
-		copy(frameBuf[:], amqpLengthBuf)
-		_, err = client.Read(frameBuf[4:])
-		if err != nil {
-			log.Debug("handleAmqpBeginAttachFlow():Error reading AMQP Frame: ", err.Error())
-			return err
-		}
-
-		frameInx := uint32(0)
-		_, bytesUsed, performative, err := amqpx.ParseFraming(frameBuf[frameInx:])
-		if err != nil {
-			log.Debug("handleAmqpBeginAttachFlow():Error parsing performative: ", err.Error())
-			return err
-		}
-		frameInx += bytesUsed
-		switch performative {
-		case amqpx.PerfBegin:
-			sessionParameters, bytesUsed, err := amqpx.ParsePerformativeBegin(frameBuf[frameInx:])
-			frameInx += bytesUsed
-			if err != nil {
-				log.Debug("handleAmqpBeginAttachFlow():Error ParsePerformativeBegin: ", err.Error())
-				return err
-			}
-			log.Debug("session parameters")
-			log.Debug("\t remoteChannel:", sessionParameters.RemoteChannel)
-			log.Debug("\t nextOutgoing:", sessionParameters.NextOutgoing)
-			log.Debug("\t incomingWindow:", sessionParameters.IncomingWindow)
-			log.Debug("\t outgoingWindow:", sessionParameters.OutgoingWindow)
-
-		case amqpx.PerfAttach:
-			attach, bytesUsed, err := amqpx.ParsePerformativeAttach(frameBuf[frameInx:])
-			frameInx += bytesUsed
-			if err != nil {
-				log.Debug("handleAmqpBeginAttachFlow():Error ParsePerformativeAttach: ", err.Error())
-				return err
-			}
-			log.Debug("Attach parameters")
-			log.Debug("\t name:", attach.Name)
-			log.Debug("\t handle:", attach.Handle)
-			log.Debug("\t role:", attach.Role)
-			log.Debug("\t sndSettleMode:", attach.SndSettleMode)
-
-		case amqpx.PerfFlow:
-			flow, bytesUsed, err := amqpx.ParsePerformativeFlow(frameBuf[frameInx:])
-			frameInx += bytesUsed
-			if err != nil {
-				log.Debug("handleAmqpBeginAttachFlow():Error ParsePerformativeFlow: ", err.Error())
-				return err
-			}
-			log.Debug("Flow parameters")
-			log.Debug("\t NextIncoming:", flow.NextIncoming)
-			log.Debug("\t IncomingWindow:", flow.IncomingWindow)
+// forgetLinks drops the deliveries in progress on the session the client sent on channel
+func (client *amqpClient) forgetLinks(channel uint16) {
+	for link := range client.rx.deliveries {
+		if link.channel == channel {
+			delete(client.rx.deliveries, link)
 		}
 	}
-
-	sendAmqpBeginAttachFlow(client)
-	return nil
 }
 
-func sendAmqpBeginAttachFlow(client amqpClient) {
-	log.Debug("sendAmqpBeginAttachFlow():Entered")
-	client.tx.session.NextOutgoing = 1
-	client.tx.session.IncomingWindow = 0x12345678
-	client.tx.session.OutgoingWindow = 0x87654321
-	beginBuf := client.tx.session.Serialize()
-
-	var frameLength = uint32(sizeAmqpHeaderStruct + len(beginBuf))
-
-	writeBuf := make([]byte, frameLength)
-	hdrBuf := serializeStruct(amqpHeaderStruct{frameLength, doff, theType, 0, perfTag, amqpx.PerfBegin})
+// beginReply answers the session the client began with our windows
+func beginReply(client *amqpClient, session *amqpx.Session) error {
+	session.Local = amqpx.SessionParameters{
+		NextOutgoing:   1,
+		IncomingWindow: client.sessionWindow,
+		OutgoingWindow: client.sessionWindow,
+	}
+	return sendSessionPerformative(session, &session.Local)
+}
 
-	inx := 0
-	copy(writeBuf[inx:], hdrBuf)
-	inx += len(hdrBuf)
-	copy(writeBuf[inx:], beginBuf)
+// sendPerformative writes performative in an AMQP frame on channel
+func sendPerformative(client *amqpClient, channel uint16, performative amqpx.Performative) error {
+	err := client.connection.Send(channel, performative, nil)
+	if err != nil {
+		log.Debug("sendPerformative():Error sending", performative, err.Error())
+	}
+	return err
+}
 
-	client.Write(writeBuf)
+// sendSessionPerformative writes performative on the channel of session
+func sendSessionPerformative(session *amqpx.Session, performative amqpx.Performative) error {
+	err := session.Send(performative, nil)
+	if err != nil {
+		log.Debug("sendSessionPerformative():Error sending", performative, err.Error())
+	}
+	return err
 }
 
-func handleAmqpLifecycle(client amqpClient) (err error) {
+func handleAmqpLifecycle(client *amqpClient) (err error) {
 	log.Debug("handleAmqpLifecycle():Entered")
 
-	for {
-		// First read length from net client to know buffer size
-		amqpLengthBuf := make([]byte, 4)
-		_, err := client.ReadUntilFull(amqpLengthBuf)
-		if err != nil {
-			log.Debug("handleAmqpLifecycle():Error reading AMQP Length: ", err.Error())
-			return err
+	for client.connection.State() != amqpx.ConnEnd {
+		// frames the connection state does not allow close the connection with a framing-error
+		channel, performative, payload, err := client.connection.Receive()
+		var amqpError *amqpx.Error
+		if errors.As(err, &amqpError) && client.connection.State() == amqpx.ConnOpened {
+			// a session error, e.g. a window violation, ended the session but not the connection
+			log.Debug("handleAmqpLifecycle():Error session ended:", err.Error())
+			client.forgetLinks(channel)
+			continue
 		}
-		amqpLength := binary.BigEndian.Uint32(amqpLengthBuf[:])
-
-		// Create properly sized buffer, and read that number of bytes from net client
-		frameBuf := make([]byte, amqpLength)
-		copy(frameBuf[:], amqpLengthBuf)
-		_, err = client.ReadUntilFull(frameBuf[4:])
 		if err != nil {
-			log.Debug("handleAmqpLifecycle():Error reading AMQP Frame: ", err.Error())
+			log.Debug("handleAmqpLifecycle():Error receiving AMQP Frame: ", err.Error())
 			return err
 		}
-
-		// From here on, we will just parse from the frame buffer we just read from the net client
-		frameInx := uint32(0)
-		_, bytesUsed, performative, err := amqpx.ParseFraming(frameBuf)
-		frameInx += bytesUsed
-		if err != nil {
-			log.Debug("handleAmqpLifecycle():Error parsing frame:", err.Error())
-			return err
+		if performative == nil {
+			log.Debug("handleAmqpLifecycle():heartbeat")
+			continue
 		}
+		// the session the client sends on channel, nil for open and close
+		session := client.connection.Session(channel)
 
-		switch performative {
-		case amqpx.PerfOpen:
-			connParameters, bytesUsed, err := amqpx.ParsePerformativeOpen(frameBuf[frameInx:])
-			frameInx += bytesUsed
-
-			if err != nil {
-				log.Debug("handleAmqpLifecycle():Error ReadOpenPerformative", err.Error())
-				return err
-			}
-			client.rx.openParams = connParameters
+		switch p := performative.(type) {
+		case *amqpx.ConnectionParameters:
+			client.rx.openParams = *p
 			// TODO(eking) the "idleTimeout field should be used to set a keepAliveInterval"
-			log.Debug("connection parameters:", client.rx.openParams.ContainerId)
+			log.Debug("connection parameters")
+			log.Debug("\tcontainer-id:", p.ContainerId)
+			if p.Hostname != nil {
+				log.Debug("\thostname:", *p.Hostname)
+			}
+			log.Debug("\tmaxFrameSize:", p.FrameSizeLimit())
+			log.Debug("\tchannelMax:", p.ChannelLimit())
+			if p.IdleTimeoutMs != nil {
+				log.Debug("\tidleTimeoutMs", *p.IdleTimeoutMs)
+			}
+			log.Debug("\tproperties", p.Properties)
 
-		case amqpx.PerfBegin:
-			sessionParameters, bytesUsed, err := amqpx.ParsePerformativeBegin(frameBuf[frameInx:])
-			frameInx += bytesUsed
-			if err != nil {
-				log.Debug("handleAmqpLifecycle():Error ParsePerformativeBegin", err.Error())
+		case *amqpx.SessionParameters:
+			log.Debug("session parameters:", p.IncomingWindow)
+			if err = beginReply(client, session); err != nil {
 				return err
 			}
-			client.rx.session = sessionParameters
-			log.Debug("session parameters:", client.rx.session.IncomingWindow)
 
-		case amqpx.PerfAttach:
-			attach, bytesUsed, err := amqpx.ParsePerformativeAttach(frameBuf[frameInx:])
-			frameInx += bytesUsed
-			if err != nil {
-				log.Debug("handleAmqpLifecycle():Error ParsePerformativeAttach", err.Error())
+		case *amqpx.AttachParameters:
+			client.rx.attach = *p
+			log.Debug("Attach parameters:", client.rx.attach.Name)
+			if err = sendSessionPerformative(session, attachReply(client, channel, *p)); err != nil {
 				return err
 			}
-			client.rx.attach = attach
-			log.Debug("Attach parameters:", client.rx.attach.Name)
-
-		case amqpx.PerfFlow:
-			flow, bytesUsed, err := amqpx.ParsePerformativeFlow(frameBuf[frameInx:])
-			frameInx += bytesUsed
-			if err != nil {
-				log.Debug("handleAmqpLifecycle():Error ParsePerformativeFlow", err.Error())
+			if err = grantCredit(client, session, *p); err != nil {
 				return err
 			}
-			client.rx.flow = flow
+
+		case *amqpx.FlowParameters:
+			client.rx.flow = *p
 			log.Debug("Flow parameters:", client.rx.flow.IncomingWindow)
+			// the session echoes session flows, we echo the ones of links with our link state
+			if bool(p.Echo) && p.IsLinkFlow() {
+				var deliveryCount amqpx.SequenceNo
+				if p.DeliveryCount != nil {
+					deliveryCount = *p.DeliveryCount
+				}
+				if err = sendSessionPerformative(session, linkFlow(client, session, *p.Handle, deliveryCount)); err != nil {
+					return err
+				}
+			}
 
-		case amqpx.PerfTransfer:
-			_, bytesUsed, err := amqpx.ParsePerformativeTransfer(frameBuf[frameInx:])
-			frameInx += bytesUsed
+		case *amqpx.TransferParameters:
+			// the session counted the transfer against its incoming window
+			log.Debug("Transfer parameters", "next-incoming-id", session.NextIncomingId, "incoming-window", session.IncomingWindow)
+
+			// a message spans transfers until one has more unset
+			delivery, err := client.deliveryAssembler(channel, p.Handle).Add(*p, payload)
+			var amqpError *amqpx.Error
+			if errors.As(err, &amqpError) {
+				log.Debug("handleAmqpLifecycle():Error refusing delivery", err.Error())
+				if err = sendSessionPerformative(session, &amqpx.DetachParameters{Handle: p.Handle, Closed: true, Error: amqpError}); err != nil {
+					return err
+				}
+				continue
+			}
+			if delivery == nil {
+				continue
+			}
+			message, err := delivery.Message()
 			if err != nil {
-				log.Debug("handleAmqpLifecycle():Error ParsePerformativeTransfer", err.Error())
+				log.Debug("handleAmqpLifecycle():Error reading message", err.Error())
 				return err
 			}
-			// TODO(eking) Apply AMQP section 2.5.6 session flow control here.
-			log.Debug("Transfer parameters:")
+			client.rx.messageCount++
+			log.Debug("Message header:", message.Header)
+			log.Debug("Message properties:", message.Properties)
+			if message.AmqpValue != nil {
+				log.Debug("MessageAmqpValue parameters:", message.AmqpValue.Value)
+			}
 
-			_, bytesUsed, err = amqpx.ParseBlockType(frameBuf[frameInx:])
-			frameInx += bytesUsed
-			_, bytesUsed, err = amqpx.ParseMessageHeader(frameBuf[frameInx:])
-			frameInx += bytesUsed
-			if err != nil {
-				log.Debug("handleAmqpLifecycle():Error ParseMessageHeader", err.Error())
-				return err
+		case *amqpx.DispositionParameters:
+			client.rx.disposition = *p
+			// TODO(eking) remove messages that have been dispositioned from the client.tx.unsettled list
+			switch state := p.State.(type) {
+			case amqpx.Accepted:
+				log.Debug("Disposition accepted:", p.First, p.Last)
+			case amqpx.Rejected:
+				log.Debug("Disposition rejected:", p.First, p.Last, state.Error)
+			case amqpx.Released, amqpx.Modified:
+				log.Debug("Disposition not processed, to be redelivered:", p.First, p.Last, state)
+			default:
+				log.Debug("Disposition parameters:", p)
 			}
-			log.Debug("MessageHeader parameters:")
 
-			_, bytesUsed, err = amqpx.ParseBlockType(frameBuf[frameInx:])
-			frameInx += bytesUsed
-			_, bytesUsed, err = amqpx.ParseMessageProperties(frameBuf[frameInx:])
-			frameInx += bytesUsed
-			if err != nil {
-				log.Debug("handleAmqpLifecycle():Error ParseMessageProperties", err.Error())
+		case *amqpx.DetachParameters:
+			log.Debug("Detach parameters:", p)
+			if err = sendSessionPerformative(session, &amqpx.DetachParameters{Handle: p.Handle, Closed: p.Closed}); err != nil {
 				return err
 			}
-			log.Debug("MessageProperties parameters:")
 
-			_, bytesUsed, err = amqpx.ParseBlockType(frameBuf[frameInx:])
-			frameInx += bytesUsed
-			amqpValue, bytesUsed, err := amqpx.ParseMessageAmqpValue(frameBuf[frameInx:])
-			frameInx += bytesUsed
-			if err != nil {
-				log.Debug("handleAmqpLifecycle():Error ParseMessageAmqpValue", err.Error())
-				return err
+		case *amqpx.EndParameters:
+			log.Debug("End parameters:", p)
+			client.forgetLinks(channel)
+			// the end is answered with an end, unless it answers ours
+			if session != nil && session.State() == amqpx.SessionEndRcvd {
+				if err = session.End(nil); err != nil {
+					return err
+				}
 			}
-			fmt.Printf("MessageAmqpValue parameters: %v", amqpValue)
 
-		case amqpx.PerfDisposition:
-			disposition, bytesUsed, err := amqpx.ParsePerformativeDisposition(frameBuf[frameInx:])
-			frameInx += bytesUsed
-			if err != nil {
-				log.Debug("handleAmqpLifecycle():Error ParsePerformativeDisposition", err.Error())
-				return err
+		case *amqpx.CloseParameters:
+			log.Debug("Close parameters:", p)
+			// the close is answered with a close, then the connection is done
+			if client.connection.State() == amqpx.ConnCloseRcvd {
+				return client.connection.Close(nil)
 			}
-			client.rx.disposition = disposition
-			// TODO(eking) remove messages that have been dispositioned from the client.tx.unsettled list
-			log.Debug("Disposition parameters")
 
 		default:
-			log.Debug("handleAmqpLifecycle():Error Not ready for this performative yet ;)")
+			log.Debug("handleAmqpLifecycle():Error Not ready for this performative yet ;)", performative)
 		}
 	}
-
-	//sendAmqpBeginAttachFlow(client)
-	//return nil
+	return nil
 }
 
 const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
@@ -409,39 +348,50 @@ func RandString(n int) string {
 func handleClientConnection(conn net.Conn) {
 	var client amqpClient
 	client.conn = conn
+	client.frames = amqpx.NewFrameReader(&client, amqpx.MinMaxFrameSize)
+	client.writer = amqpx.NewFrameWriter(&client, amqpx.MinMaxFrameSize)
+	client.connection = amqpx.NewConnection(client.frames, client.writer)
 	client.containerID = "amqpxServer-" + RandString(12)
 	tmp, _ := strconv.ParseUint(utils.GetEnv("AMQPX_SERVER_CHANNELMAX", "1"), 10, 16)
 	client.channelMax = uint16(tmp)
 	tmp, _ = strconv.ParseUint(utils.GetEnv("AMQPX_SERVER_IDLETIMEOUT", "1111"), 10, 32)
 	client.idleTimeout = uint32(tmp)
+	tmp, _ = strconv.ParseUint(utils.GetEnv("AMQPX_SERVER_MAXFRAMESIZE", "65536"), 10, 32)
+	client.maxFrameSize = uint32(tmp)
+	tmp, _ = strconv.ParseUint(utils.GetEnv("AMQPX_SERVER_LINKCREDIT", "100"), 10, 32)
+	client.linkCredit = uint32(tmp)
+	tmp, _ = strconv.ParseUint(utils.GetEnv("AMQPX_SERVER_SESSIONWINDOW", "2048"), 10, 32)
+	client.sessionWindow = uint32(tmp)
+	client.maxMessageSize, _ = strconv.ParseUint(utils.GetEnv("AMQPX_SERVER_MAXMESSAGESIZE", "0"), 10, 64)
 	client.hostname = utils.GetEnv("AMQPX_SERVER_HOSTNAME", "amqpxServer")
 	tmpInt, _ := strconv.ParseInt(utils.GetEnv("AMQPX_SERVER_READTIMEOUT", "5"), 10, 32)
 	client.readTimeout = time.Duration(tmpInt) * time.Second
 
 	defer func() {
 		log.Debug("Closing client connection TBD details about connection")
-		conn.Close()
+		client.conn.Close()
 	}()
 
-	err := handleAmqpVersion(client)
-	if err != nil {
-		log.Debug("Closing client connection after AmqpVersion")
-		return
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		// amqps, the connection is TLS from the first byte on
+		if err := handshakeTLS(&client, tlsConn); err != nil {
+			return
+		}
 	}
 
-	err = handleAmqpOpen(client)
+	err := handleAmqpVersion(&client)
 	if err != nil {
-		log.Debug("Closing client connection after AmqpOpen")
+		log.Debug("Closing client connection after AmqpVersion")
 		return
 	}
 
-	err = handleAmqpBeginAttachFlow(client)
+	err = sendAmqpVersionAndOpen(&client)
 	if err != nil {
-		log.Debug("Closing client connection after handleAmqpBeginAttachFlow")
+		log.Debug("Closing client connection after AmqpOpen")
 		return
 	}
 
-	err = handleAmqpLifecycle(client)
+	err = handleAmqpLifecycle(&client)
 	if err != nil {
 		log.Debug("Closing client connection after handleAmqpLifecycle")
 		return
@@ -463,6 +413,20 @@ func server(serverListenPort string) {
 		log.Debug("Server error :", err)
 		return
 	}
+	if serverTLSConfig != nil {
+		tlsListenPort := utils.GetEnv("AMQPX_SERVER_TLS_PORT", strconv.Itoa(amqpx.PortAMQPS))
+		tlsLn, err := tls.Listen(connType, serverHost+":"+tlsListenPort, serverTLSConfig)
+		if err != nil {
+			log.Debug("Server error :", err)
+			return
+		}
+		log.Debug("Server listening for amqps on port ", tlsListenPort)
+		go acceptConnections(tlsLn)
+	}
+	acceptConnections(ln)
+}
+
+func acceptConnections(ln net.Listener) {
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
@@ -475,6 +439,11 @@ func server(serverListenPort string) {
 
 func main() {
 	initRand()
+	var err error
+	if serverTLSConfig, err = loadTLSConfig(); err != nil {
+		log.Error("Server TLS configuration error :", err)
+		return
+	}
 	serverListenPort := utils.GetEnv("AMQPX_SERVER_PORT", "10010")
 	log.Debug("Server listening on port ", serverListenPort)
 	server(serverListenPort)
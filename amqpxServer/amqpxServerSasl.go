@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	amqpx "github.com/ewk-elwa/go-amqpx/amqpx"
+	"github.com/ewk-elwa/go-amqpx/utils"
+
+	log "github.com/mgutz/logxi/v1"
+)
+
+// authenticator is the hook that maps the credentials of a client To the identity of its
+// connection. The default checks the users of AMQPX_SERVER_SASL_USERS, replace it To use
+// another user store
+var authenticator amqpx.SaslAuthenticator = authenticateUser
+
+// scramLookup returns the SCRAM-SHA-256 credentials of a user, it goes with authenticator
+var scramLookup = lookupScramUser
+
+// saslUsers holds the passwords of AMQPX_SERVER_SASL_USERS, "user:password,..."
+var saslUsers = parseSaslUsers(utils.GetEnv("AMQPX_SERVER_SASL_USERS", ""))
+
+// scramUsers holds the SCRAM-SHA-256 credentials derived from saslUsers
+var scramUsers = map[string]amqpx.ScramCredentials{}
+
+func parseSaslUsers(users string) map[string]string {
+	passwords := map[string]string{}
+	for _, user := range strings.Split(users, ",") {
+		if name, password, ok := strings.Cut(user, ":"); ok && name != "" {
+			passwords[name] = password
+		}
+	}
+	return passwords
+}
+
+func init() {
+	for name, password := range saslUsers {
+		credentials, err := amqpx.NewScramCredentials(password)
+		if err != nil {
+			log.Debug("init():Error deriving SCRAM credentials of", name, err.Error())
+			continue
+		}
+		scramUsers[name] = credentials
+	}
+}
+
+// authenticateUser accepts anonymous clients, users with their password and the common name
+// of a client certificate. A client may not act as another identity
+func authenticateUser(credentials amqpx.SaslCredentials) (identity string, err error) {
+	if credentials.AuthorizationId != "" && credentials.AuthorizationId != credentials.Username {
+		return "", errors.New("authorization as another identity is not supported")
+	}
+	switch credentials.Mechanism {
+	case amqpx.SaslMechanismAnonymous:
+		return "anonymous", nil
+	case amqpx.SaslMechanismPlain:
+		password, ok := saslUsers[credentials.Username]
+		// compared in constant time so the time taken tells nothing about the password
+		if subtle.ConstantTimeCompare([]byte(password), []byte(credentials.Password)) != 1 || !ok {
+			return "", errors.New("unknown user or wrong password")
+		}
+	case amqpx.SaslMechanismExternal, amqpx.SaslMechanismScramSha256:
+		// the certificate or the proof of the password was checked by the mechanism
+	default:
+		return "", errors.New("unsupported mechanism")
+	}
+	return credentials.Username, nil
+}
+
+func lookupScramUser(username string) (amqpx.ScramCredentials, error) {
+	credentials, ok := scramUsers[username]
+	if !ok {
+		return credentials, errors.New("unknown user")
+	}
+	return credentials, nil
+}
+
+// saslMechanisms returns the mechanisms of AMQPX_SERVER_SASL_MECHANISMS for a connection,
+// none when the server does not ask for authentication
+func saslMechanisms(client *amqpClient) (mechanisms []amqpx.SaslServerMechanism) {
+	for _, name := range strings.Split(utils.GetEnv("AMQPX_SERVER_SASL_MECHANISMS", ""), ",") {
+		switch amqpx.Symbol(strings.TrimSpace(name)) {
+		case amqpx.SaslMechanismPlain:
+			mechanisms = append(mechanisms, amqpx.NewSaslPlainServer(authenticator))
+		case amqpx.SaslMechanismAnonymous:
+			mechanisms = append(mechanisms, amqpx.NewSaslAnonymousServer(authenticator))
+		case amqpx.SaslMechanismExternal:
+			mechanisms = append(mechanisms, amqpx.NewSaslExternalServer(client.peerCertificates, authenticator))
+		case amqpx.SaslMechanismScramSha256:
+			mechanisms = append(mechanisms, amqpx.NewSaslScramSha256Server(scramLookup, authenticator))
+		}
+	}
+	return mechanisms
+}
+
+// handleSasl authenticates the client once it sent the SASL protocol header
+func handleSasl(client *amqpClient, mechanisms []amqpx.SaslServerMechanism) (err error) {
+	log.Debug("handleSasl():Entered")
+	if err = client.writer.WriteProtocolHeader(amqpx.ProtocolIdSASL); err != nil {
+		return err
+	}
+	client.identity, err = amqpx.NegotiateSaslServer(client.frames, client.writer, mechanisms...)
+	if err != nil {
+		log.Debug("handleSasl():Error authenticating client:", err.Error())
+		return err
+	}
+	log.Debug("handleSasl():client authenticated as", client.identity)
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"time"
+
+	amqpx "github.com/ewk-elwa/go-amqpx/amqpx"
+	"github.com/ewk-elwa/go-amqpx/utils"
+
+	log "github.com/mgutz/logxi/v1"
+)
+
+// serverTLSConfig is set when the server has a certificate, then it listens for amqps
+// and accepts the TLS protocol header on the plain port
+var serverTLSConfig *tls.Config
+
+// loadTLSConfig reads the certificate, key and CA of the server from AMQPX_SERVER_TLS_CERT,
+// AMQPX_SERVER_TLS_KEY and AMQPX_SERVER_TLS_CA. Client certificates are verified against
+// the CA, AMQPX_SERVER_TLS_CLIENTAUTH=require refuses clients without one
+func loadTLSConfig() (*tls.Config, error) {
+	certFile := utils.GetEnv("AMQPX_SERVER_TLS_CERT", "")
+	keyFile := utils.GetEnv("AMQPX_SERVER_TLS_KEY", "")
+	caFile := utils.GetEnv("AMQPX_SERVER_TLS_CA", "")
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	config, err := amqpx.NewTLSServerConfig(certFile, keyFile, caFile)
+	if err != nil {
+		return nil, err
+	}
+	if utils.GetEnv("AMQPX_SERVER_TLS_CLIENTAUTH", "") == "require" {
+		if caFile == "" {
+			return nil, errors.New("AMQPX_SERVER_TLS_CLIENTAUTH=require needs AMQPX_SERVER_TLS_CA")
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// handleTLS upgrades the connection after the client sent the TLS protocol header
+func handleTLS(client *amqpClient) error {
+	log.Debug("handleTLS():Entered")
+	client.conn.SetDeadline(time.Now().Add(client.readTimeout))
+	tlsConn, err := amqpx.UpgradeTLSServer(client.conn, serverTLSConfig)
+	if err != nil {
+		log.Debug("handleTLS():Error TLS handshake:", err.Error())
+		return err
+	}
+	client.conn = tlsConn
+	return handshakeTLS(client, tlsConn)
+}
+
+// handshakeTLS completes the handshake of a TLS connection and keeps the certificate of the client
+func handshakeTLS(client *amqpClient, tlsConn *tls.Conn) error {
+	tlsConn.SetDeadline(time.Now().Add(client.readTimeout))
+	if err := tlsConn.Handshake(); err != nil {
+		log.Debug("handshakeTLS():Error TLS handshake:", err.Error())
+		return err
+	}
+	client.secured = true
+	client.peerCertificates = amqpx.PeerCertificates(tlsConn)
+	if len(client.peerCertificates) > 0 {
+		log.Debug("handshakeTLS():client certificate", client.peerCertificates[0].Subject.String())
+	}
+	return nil
+}